@@ -0,0 +1,184 @@
+package agentclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/client/internal/fakeagent"
+)
+
+func startAgent(t *testing.T, scripts ...[]string) (*fakeagent.Agent, *Client) {
+	t.Helper()
+	agent := fakeagent.New(t, scripts...)
+	return agent, New(agent.URL, WithReconnect(2, time.Millisecond))
+}
+
+func message() types.MessageSendParam {
+	return types.MessageSendParam{Message: &types.Message{
+		Kind: "message", MessageID: "message-1", Role: types.User,
+		Parts: []types.Part{&types.TextPart{Kind: "text", Text: "hi"}},
+	}}
+}
+
+func TestStreamReassemblesArtifacts(t *testing.T) {
+	tool := &types.TaskArtifactUpdateEvent{
+		TaskId: "task-1", Kind: "artifact-update",
+		Artifact: &types.Artifact{ArtifactId: "tool-1", Name: "list_issues", Parts: []types.Part{&types.DataPart{Kind: "data", Data: map[string]any{"count": 2.0}}}},
+	}
+	agent, client := startAgent(t, []string{
+		fakeagent.Result(t, fakeagent.Status(types.SUBMITTED, false)),
+		fakeagent.Result(t, fakeagent.Status(types.WORKING, false)),
+		fakeagent.Result(t, tool),
+		fakeagent.Result(t, fakeagent.Chunk("answer-1", "Two ", false, false)),
+		fakeagent.Result(t, fakeagent.Chunk("answer-1", "open ", true, false)),
+		fakeagent.Result(t, fakeagent.Chunk("answer-1", "issues.", true, true)),
+		fakeagent.Result(t, fakeagent.Status(types.COMPLETED, true)),
+	})
+
+	stream, err := client.SendStream(context.Background(), message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for {
+		event, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		kinds = append(kinds, event.Type())
+	}
+	if len(kinds) != 7 || kinds[2] != "artifact_update" || kinds[6] != "status_update" {
+		t.Errorf("events %v", kinds)
+	}
+
+	res := stream.Result()
+	if res.TaskID != "task-1" || res.ContextID != "context-1" || res.State() != types.COMPLETED || !res.Done() {
+		t.Errorf("result %+v", res)
+	}
+	if len(res.Artifacts) != 2 || res.Artifacts[0].ArtifactId != "tool-1" {
+		t.Fatalf("artifacts %+v", res.Artifacts)
+	}
+	if parts := res.Artifact("answer-1").Parts; len(parts) != 1 {
+		t.Errorf("answer has %d parts, want the chunks joined into 1", len(parts))
+	}
+	if got := res.Text(); got != "Two open issues." {
+		t.Errorf("text %q", got)
+	}
+	if requests := agent.Requests(); requests[0].JSONRPC != "2.0" || requests[0].Method != types.MethodMessageStream {
+		t.Errorf("request %+v", requests[0])
+	}
+}
+
+func TestStreamResubscribesAfterBreak(t *testing.T) {
+	agent, client := startAgent(t,
+		[]string{
+			fakeagent.Result(t, fakeagent.Status(types.SUBMITTED, false)),
+			fakeagent.Result(t, fakeagent.Status(types.WORKING, false)),
+			fakeagent.Result(t, fakeagent.Chunk("answer-1", "Part ", false, false)),
+		},
+		// The first resubscription fails before any event, the second replays the rest
+		[]string{},
+		[]string{
+			fakeagent.Result(t, fakeagent.Chunk("answer-1", "two.", true, true)),
+			fakeagent.Result(t, fakeagent.Status(types.COMPLETED, true)),
+		},
+	)
+
+	stream, err := client.SendStream(context.Background(), message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := stream.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.State() != types.COMPLETED || res.Text() != "Part two." || stream.Received() != 5 {
+		t.Errorf("state %s, text %q after %d events", res.State(), res.Text(), stream.Received())
+	}
+
+	requests := agent.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("%d requests, want 3", len(requests))
+	}
+	for i, want := range []float64{3, 3} {
+		req := requests[i+1]
+		if req.Method != types.MethodTasksResubscribe || req.Params["id"] != "task-1" {
+			t.Errorf("request %d %+v", i+1, req)
+		}
+		if from := req.Params["metadata"].(map[string]any)["from"]; from != want {
+			t.Errorf("request %d resubscribed from %v, want %v", i+1, from, want)
+		}
+	}
+}
+
+func TestStreamGivesUp(t *testing.T) {
+	_, client := startAgent(t, []string{fakeagent.Result(t, fakeagent.Status(types.SUBMITTED, false))})
+
+	stream, err := client.Resubscribe(context.Background(), "task-1", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = stream.Wait()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("error %v, want unexpected EOF", err)
+	}
+	if stream.Received() != 5 {
+		t.Errorf("received %d, want 5", stream.Received())
+	}
+	if _, err := stream.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("next after the error %v, want EOF", err)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	_, client := startAgent(t, []string{`{"jsonrpc":"2.0","id":"1","error":{"code":-32001,"message":"task not found"}}`})
+
+	stream, err := client.Resubscribe(context.Background(), "missing", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = stream.Next()
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32001 {
+		t.Errorf("error %v, want code -32001", err)
+	}
+}
+
+func TestCalls(t *testing.T) {
+	task := &types.Task{Id: "task-1", ContextId: "context-1", Kind: "task", Status: types.TaskStatus{State: types.CANCELED}}
+	agent, client := startAgent(t,
+		[]string{fakeagent.Result(t, task)},
+		[]string{fakeagent.Result(t, task)},
+		[]string{fakeagent.Result(t, &types.Message{Kind: "message", MessageID: "m", Role: types.Agent, Parts: []types.Part{&types.TextPart{Kind: "text", Text: "Hello"}}})},
+	)
+	ctx := context.Background()
+
+	got, err := client.GetTask(ctx, types.TaskQueryParams{Id: "task-1"})
+	if err != nil || got.Id != "task-1" {
+		t.Fatalf("get task %+v, %v", got, err)
+	}
+	if got, err = client.CancelTask(ctx, types.TaskIdParams{Id: "task-1"}); err != nil || got.Status.State != types.CANCELED {
+		t.Fatalf("cancel task %+v, %v", got, err)
+	}
+	event, err := client.SendMessage(ctx, message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer, ok := event.(*types.Message); !ok || PartsText(answer.Parts) != "Hello" {
+		t.Errorf("answer %#v", event)
+	}
+
+	requests := agent.Requests()
+	for i, method := range []string{types.MethodTasksGet, types.MethodTasksCancel, types.MethodMessageSend} {
+		if requests[i].Method != method || requests[i].JSONRPC != "2.0" {
+			t.Errorf("request %d %+v, want %s", i, requests[i], method)
+		}
+	}
+}
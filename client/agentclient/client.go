@@ -0,0 +1,189 @@
+// Package agentclient is a client of the agent's JSON-RPC API. Streams are decoded into the typed
+// events of a2a-go, chunked artifacts are reassembled into the result of the task, and a stream that
+// breaks before the task finished is resumed with tasks/resubscribe from the first event not received.
+package agentclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// Client calls the JSON-RPC endpoint of an agent, it is safe for concurrent use
+type Client struct {
+	url        string
+	httpClient *http.Client
+	// reconnects bounds the resubscriptions of a single stream, backoff is the wait before the first one
+	reconnects int
+	backoff    time.Duration
+}
+
+// Option configures optional Client settings
+type Option func(c *Client)
+
+// WithHTTPClient sends the requests with client, it must not time out streams
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithReconnect sets how often a broken stream is resumed and the wait before the first attempt,
+// which doubles with every further attempt. Zero attempts disable reconnection.
+func WithReconnect(attempts int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.reconnects = attempts
+		c.backoff = backoff
+	}
+}
+
+// New creates a client of the JSON-RPC endpoint at url
+func New(url string, opts ...Option) *Client {
+	c := &Client{
+		url:        url,
+		httpClient: &http.Client{},
+		reconnects: 3,
+		backoff:    500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error is a JSON-RPC error answered by the agent
+type Error struct {
+	Code    types.ErrorCode
+	Message string
+	Data    any
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// rpcResponse is a JSON-RPC response, or an event of a stream
+type rpcResponse struct {
+	Result json.RawMessage     `json:"result"`
+	Error  *types.JSONRPCError `json:"error"`
+}
+
+func (r rpcResponse) err() error {
+	if r.Error == nil {
+		return nil
+	}
+	return &Error{Code: r.Error.Code, Message: r.Error.Message, Data: r.Error.Data}
+}
+
+// SendMessage sends a message and waits for the task it started, or the message the agent answered with
+func (c *Client) SendMessage(ctx context.Context, params types.MessageSendParam) (types.Event, error) {
+	var result json.RawMessage
+	if err := c.call(ctx, types.MethodMessageSend, params, &result); err != nil {
+		return nil, err
+	}
+	return decodeEvent(result)
+}
+
+// GetTask returns a task
+func (c *Client) GetTask(ctx context.Context, params types.TaskQueryParams) (*types.Task, error) {
+	var task types.Task
+	if err := c.call(ctx, types.MethodTasksGet, params, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// CancelTask cancels a task and returns it in its final state
+func (c *Client) CancelTask(ctx context.Context, params types.TaskIdParams) (*types.Task, error) {
+	var task types.Task
+	if err := c.call(ctx, types.MethodTasksCancel, params, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// call sends a JSON-RPC request and decodes the result
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	response, err := c.post(ctx, method, params, "application/json")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	var decoded rpcResponse
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
+	}
+	if err := decoded.err(); err != nil {
+		return err
+	}
+	if len(decoded.Result) == 0 || string(decoded.Result) == "null" {
+		return fmt.Errorf("%s response without a result", method)
+	}
+	if err := json.Unmarshal(decoded.Result, result); err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// post sends a JSON-RPC request with a new request id
+func (c *Client) post(ctx context.Context, method string, params any, accept string) (*http.Response, error) {
+	payload, err := json.Marshal(map[string]any{
+		"jsonrpc": types.Version,
+		"id":      uuid.NewString(),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode %s request: %w", method, err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", accept)
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		response.Body.Close()
+		return nil, fmt.Errorf("%s: unexpected status %s: %s", method, response.Status, bytes.TrimSpace(body))
+	}
+	return response, nil
+}
+
+// decodeEvent decodes a task, a status update, an artifact update or a message
+func decodeEvent(raw json.RawMessage) (types.Event, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, fmt.Errorf("decode event: %w", err)
+	}
+	var event types.Event
+	switch {
+	case members["artifact"] != nil:
+		event = &types.TaskArtifactUpdateEvent{}
+	case members["status"] != nil:
+		event = &types.TaskStatusUpdateEvent{}
+	case members["task_status"] != nil:
+		event = &types.Task{}
+	case members["parts"] != nil:
+		event = &types.Message{}
+	default:
+		return nil, fmt.Errorf("unknown event %s", raw)
+	}
+	if err := json.Unmarshal(raw, event); err != nil {
+		return nil, fmt.Errorf("decode %s event: %w", event.Type(), err)
+	}
+	return event, nil
+}
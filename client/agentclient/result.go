@@ -0,0 +1,156 @@
+package agentclient
+
+import (
+	"strings"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// Result is a task as reassembled from its events. Artifacts streamed in chunks are joined, so every
+// artifact appears once with all its parts, in the order the artifacts started.
+type Result struct {
+	TaskID    string
+	ContextID string
+	Status    types.TaskStatus
+	Artifacts []types.Artifact
+	// Message is the answer of an agent that replied with a message instead of starting a task
+	Message *types.Message
+}
+
+// State returns the last known state of the task
+func (r *Result) State() types.TaskState {
+	return r.Status.State
+}
+
+// Done reports whether the task reached a final state or the agent answered with a message
+func (r *Result) Done() bool {
+	if r.Message != nil {
+		return true
+	}
+	task := types.Task{Status: r.Status}
+	return task.Done()
+}
+
+// Artifact returns the artifact with the given id, nil when there is none
+func (r *Result) Artifact(id string) *types.Artifact {
+	for i := range r.Artifacts {
+		if r.Artifacts[i].ArtifactId == id {
+			return &r.Artifacts[i]
+		}
+	}
+	return nil
+}
+
+// Text returns the answer: the text of the artifacts, otherwise the text of the message the agent
+// answered with or of the last status message
+func (r *Result) Text() string {
+	var texts []string
+	for _, artifact := range r.Artifacts {
+		if text := PartsText(artifact.Parts); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	if len(texts) > 0 {
+		return strings.Join(texts, "\n\n")
+	}
+	if r.Message != nil {
+		return PartsText(r.Message.Parts)
+	}
+	if r.Status.Message != nil {
+		return PartsText(r.Status.Message.Parts)
+	}
+	return ""
+}
+
+// apply updates the result with an event
+func (r *Result) apply(event types.Event) {
+	switch typed := event.(type) {
+	case *types.Task:
+		r.track(typed.Id, typed.ContextId)
+		r.Status = typed.Status
+		// A task is a snapshot, e.g. of a finished task on resubscription, its artifacts are complete
+		if len(typed.Artifacts) > 0 {
+			r.Artifacts = r.Artifacts[:0]
+			for _, artifact := range typed.Artifacts {
+				r.Artifacts = append(r.Artifacts, copyArtifact(&artifact))
+			}
+		}
+	case *types.Message:
+		r.track(typed.TaskID, typed.ContextID)
+		r.Message = typed
+	case *types.TaskStatusUpdateEvent:
+		r.track(typed.TaskId, typed.ContextId)
+		r.Status = typed.Status
+	case *types.TaskArtifactUpdateEvent:
+		r.track(typed.TaskId, typed.ContextId)
+		r.addArtifact(typed)
+	}
+}
+
+func (r *Result) track(taskID string, contextID string) {
+	if taskID != "" {
+		r.TaskID = taskID
+	}
+	if contextID != "" {
+		r.ContextID = contextID
+	}
+}
+
+// addArtifact adds a chunk to the artifact it belongs to. A chunk with append set extends the
+// artifact, other chunks start it over.
+func (r *Result) addArtifact(event *types.TaskArtifactUpdateEvent) {
+	if event.Artifact == nil {
+		return
+	}
+	existing := r.Artifact(event.Artifact.ArtifactId)
+	switch {
+	case existing == nil:
+		r.Artifacts = append(r.Artifacts, copyArtifact(event.Artifact))
+	case !event.Append:
+		*existing = copyArtifact(event.Artifact)
+	default:
+		for _, part := range event.Artifact.Parts {
+			existing.Parts = appendPart(existing.Parts, part)
+		}
+		if event.Artifact.Name != "" {
+			existing.Name = event.Artifact.Name
+		}
+		if event.Artifact.Description != "" {
+			existing.Description = event.Artifact.Description
+		}
+	}
+}
+
+// appendPart appends a part, joining consecutive text parts into one
+func appendPart(parts []types.Part, part types.Part) []types.Part {
+	text, ok := part.(*types.TextPart)
+	if !ok || len(parts) == 0 {
+		return append(parts, part)
+	}
+	last, ok := parts[len(parts)-1].(*types.TextPart)
+	if !ok {
+		return append(parts, part)
+	}
+	joined := *last
+	joined.Text += text.Text
+	parts[len(parts)-1] = &joined
+	return parts
+}
+
+// copyArtifact copies an artifact with its own parts slice, so joining chunks leaves the events unchanged
+func copyArtifact(artifact *types.Artifact) types.Artifact {
+	copied := *artifact
+	copied.Parts = append([]types.Part(nil), artifact.Parts...)
+	return copied
+}
+
+// PartsText joins the text parts
+func PartsText(parts []types.Part) string {
+	var texts []string
+	for _, part := range parts {
+		if text, ok := part.(*types.TextPart); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,206 @@
+package agentclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// resubscribeFromKey is the metadata key of tasks/resubscribe holding the index of the first event to replay
+const resubscribeFromKey = "from"
+
+// Stream is the event stream of a task. Next returns the events in order and io.EOF after the
+// final one, Result holds the task as reassembled from the events so far.
+type Stream struct {
+	client *Client
+	ctx    context.Context
+
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	// received counts the events of the task's execution, the index to resume from
+	received int
+	// reconnects counts the resubscriptions since the last event
+	reconnects int
+	// openErr is why the last resubscription failed
+	openErr error
+	done    bool
+	result  *Result
+}
+
+// SendStream sends a message and streams the events of the task it starts or continues
+func (c *Client) SendStream(ctx context.Context, params types.MessageSendParam) (*Stream, error) {
+	s := &Stream{client: c, ctx: ctx, result: &Result{}}
+	if params.Message != nil {
+		s.result.TaskID = params.Message.TaskID
+		s.result.ContextID = params.Message.ContextID
+	}
+	if err := s.open(types.MethodMessageStream, params); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Resubscribe streams the events of a running task, replaying them from the given index, e.g. the
+// number of events already received. A finished task is answered with the task itself.
+func (c *Client) Resubscribe(ctx context.Context, taskID string, from int) (*Stream, error) {
+	s := &Stream{client: c, ctx: ctx, received: from, result: &Result{TaskID: taskID}}
+	if err := s.open(types.MethodTasksResubscribe, s.resubscribeParams()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Next returns the next event, a *types.Task, *types.Message, *types.TaskStatusUpdateEvent or
+// *types.TaskArtifactUpdateEvent. It returns io.EOF after the final event, and an *Error when the
+// agent answers the stream with one.
+func (s *Stream) Next() (types.Event, error) {
+	for {
+		if s.done {
+			return nil, io.EOF
+		}
+		line, err := s.readLine()
+		if err != nil {
+			if retryErr := s.reconnect(err); retryErr != nil {
+				return nil, retryErr
+			}
+			continue
+		}
+
+		var response rpcResponse
+		if err := json.Unmarshal(line, &response); err != nil {
+			return nil, fmt.Errorf("decode stream response: %w", err)
+		}
+		if err := response.err(); err != nil {
+			s.finish()
+			return nil, err
+		}
+		event, err := decodeEvent(response.Result)
+		if err != nil {
+			return nil, err
+		}
+		s.received++
+		s.reconnects = 0
+		s.result.apply(event)
+		if event.Done() {
+			s.finish()
+		}
+		return event, nil
+	}
+}
+
+// Wait reads the remaining events and returns the result of the task
+func (s *Stream) Wait() (*Result, error) {
+	for {
+		if _, err := s.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return s.result, nil
+			}
+			return s.result, err
+		}
+	}
+}
+
+// Result returns the task as reassembled from the events received so far
+func (s *Stream) Result() *Result {
+	return s.result
+}
+
+// Received returns the number of events of the task's execution received so far, the index to
+// resubscribe from to continue the stream
+func (s *Stream) Received() int {
+	return s.received
+}
+
+// Close stops reading the stream
+func (s *Stream) Close() error {
+	s.done = true
+	if s.body == nil {
+		return nil
+	}
+	err := s.body.Close()
+	s.body = nil
+	return err
+}
+
+func (s *Stream) finish() {
+	s.done = true
+	if s.body != nil {
+		s.body.Close()
+		s.body = nil
+	}
+}
+
+// open starts a streaming request
+func (s *Stream) open(method string, params any) error {
+	response, err := s.client.post(s.ctx, method, params, "text/event-stream")
+	if err != nil {
+		return err
+	}
+	s.body = response.Body
+	s.scanner = bufio.NewScanner(response.Body)
+	s.scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	return nil
+}
+
+// readLine returns the next non-empty line of the stream, io.ErrUnexpectedEOF when it ends
+func (s *Stream) readLine() ([]byte, error) {
+	if s.body == nil {
+		if s.openErr != nil {
+			return nil, s.openErr
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	for s.scanner.Scan() {
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) > 0 {
+			return line, nil
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// reconnect resubscribes to the task after the stream broke with cause, returning the error to
+// report when the stream cannot be resumed
+func (s *Stream) reconnect(cause error) error {
+	if s.body != nil {
+		s.body.Close()
+		s.body = nil
+	}
+	if err := s.ctx.Err(); err != nil {
+		s.done = true
+		return err
+	}
+	if s.result.TaskID == "" || s.reconnects >= s.client.reconnects {
+		s.done = true
+		return fmt.Errorf("stream broke after %d events: %w", s.received, cause)
+	}
+
+	wait := s.client.backoff << s.reconnects
+	s.reconnects++
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-s.ctx.Done():
+		s.done = true
+		return s.ctx.Err()
+	case <-timer.C:
+	}
+
+	// A failed request leaves the stream without a body, the next read tries again until the attempts are used up
+	s.openErr = s.open(types.MethodTasksResubscribe, s.resubscribeParams())
+	return nil
+}
+
+func (s *Stream) resubscribeParams() types.TaskIdParams {
+	return types.TaskIdParams{Id: s.result.TaskID, Metadata: map[string]any{resubscribeFromKey: s.received}}
+}
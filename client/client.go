@@ -1,44 +1,60 @@
 package main
 
 import (
-	"encoding/json"
-	"log"
-	"net/http"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
 
-	"github.com/yeeaiclub/a2a-go/sdk/client"
 	"github.com/yeeaiclub/a2a-go/sdk/types"
 )
 
 func main() {
-	httpClient := http.Client{}
-	newClient := client.NewClient(&httpClient, "http://localhost:8080/api")
-
-	eventChan := make(chan any)
-	errChan := make(chan error, 1)
-
-	go func() {
-		errChan <- newClient.SendMessageStream(types.MessageSendParam{
-			Message: &types.Message{
-				ContextID: "context-1",
-				Role:      types.User,
-				Parts: []types.Part{
-					&types.TextPart{Text: "Show recent commits for repository 'facebook/react", Kind: "text"},
-				},
-			},
-		}, eventChan)
-		close(eventChan)
-	}()
-
-	for event := range eventChan {
-		rawMsg, ok := event.(json.RawMessage)
-		if !ok {
-			log.Println("Unexpected event type")
-			continue
-		}
-		log.Println("Raw event:", string(rawMsg))
+	url := flag.String("url", "http://localhost:8080/api", "agent JSON-RPC endpoint")
+	prompt := flag.String("p", "", "send a single message, print the answer and exit instead of starting the REPL")
+	contextID := flag.String("context", "", "continue the conversation with this context ID")
+	taskID := flag.String("task", "", "reattach to the event stream of a running task instead of sending a message")
+	from := flag.Int("from", 0, "with -task, index of the first event to replay, e.g. the number of events already received")
+	noColor := flag.Bool("no-color", false, "disable colors and markdown formatting, the default when stdout is not a terminal")
+	flag.Parse()
+
+	render := newRenderer(os.Stdout, os.Stderr, !*noColor && useColor(os.Stdout))
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	s := newSession(*url, render, signals)
+	if *contextID != "" {
+		s.contextID = *contextID
+	}
+
+	switch {
+	case *taskID != "":
+		os.Exit(exitCode(s, s.follow(*taskID, *from)))
+	case *prompt != "":
+		os.Exit(exitCode(s, s.send(*prompt)))
+	case flag.NArg() > 0:
+		fmt.Fprintf(os.Stderr, "unexpected arguments %v, use -p to send a single message\n", flag.Args())
+		os.Exit(2)
 	}
 
-	if err := <-errChan; err != nil {
-		log.Printf("Stream error: %v", err)
+	if err := runREPL(s, os.Stdin, os.Stderr); err != nil {
+		render.Errorf("%v", err)
+		os.Exit(1)
+	}
+}
+
+// exitCode reports the outcome of a single message to scripts: 0 when the task completed,
+// 1 when it ended otherwise or the stream failed and 130 when it was interrupted
+func exitCode(s *session, err error) int {
+	switch {
+	case errors.Is(err, errInterrupted):
+		return 130
+	case err != nil:
+		s.render.Errorf("%v", err)
+		return 1
+	case s.state != types.COMPLETED:
+		return 1
 	}
+	return 0
 }
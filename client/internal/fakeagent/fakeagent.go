@@ -0,0 +1,110 @@
+// Package fakeagent is a scripted A2A agent for client tests. Each request is answered with the next
+// script, a list of JSON-RPC response lines written as an event stream.
+package fakeagent
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// Task and context of the events built by Status and Chunk
+const (
+	TaskID    = "task-1"
+	ContextID = "context-1"
+)
+
+// Request is a JSON-RPC request as received by the agent
+type Request struct {
+	JSONRPC string         `json:"jsonrpc"`
+	Method  string         `json:"method"`
+	Params  map[string]any `json:"params"`
+}
+
+// Message returns the message of a message/send or message/stream request
+func (r Request) Message() map[string]any {
+	message, _ := r.Params["message"].(map[string]any)
+	return message
+}
+
+// Agent answers the n-th request with the n-th script, requests beyond the scripts get an empty response
+type Agent struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	scripts  [][]string
+	requests []Request
+}
+
+// New starts an agent that is closed with the test
+func New(t *testing.T, scripts ...[]string) *Agent {
+	t.Helper()
+	agent := &Agent{scripts: scripts}
+	agent.Server = httptest.NewServer(http.HandlerFunc(agent.serve))
+	t.Cleanup(agent.Close)
+	return agent
+}
+
+func (a *Agent) serve(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	a.requests = append(a.requests, req)
+	var script []string
+	if len(a.scripts) > 0 {
+		script, a.scripts = a.scripts[0], a.scripts[1:]
+	}
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, line := range script {
+		io.WriteString(w, line+"\n")
+	}
+}
+
+// Requests returns the requests received so far
+func (a *Agent) Requests() []Request {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Request(nil), a.requests...)
+}
+
+// Result is a response line carrying an event, a task or a message
+func Result(t *testing.T, event any) string {
+	t.Helper()
+	line, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": "1", "result": event})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(line)
+}
+
+// Status is a status update of the task
+func Status(state types.TaskState, final bool) *types.TaskStatusUpdateEvent {
+	return &types.TaskStatusUpdateEvent{TaskId: TaskID, ContextId: ContextID, Kind: "status-update", Final: final, Status: types.TaskStatus{State: state}}
+}
+
+// Chunk is a chunk of a text artifact of the task
+func Chunk(id string, text string, append bool, last bool) *types.TaskArtifactUpdateEvent {
+	return &types.TaskArtifactUpdateEvent{
+		TaskId: TaskID, ContextId: ContextID, Kind: "artifact-update", Append: append, LastChunk: last,
+		Artifact: &types.Artifact{ArtifactId: id, Name: "answer", Parts: []types.Part{&types.TextPart{Kind: "text", Text: text}}},
+	}
+}
+
+// Task is a snapshot of the task in the given state
+func Task(state types.TaskState, answer string) *types.Task {
+	task := &types.Task{Id: TaskID, ContextId: ContextID, Kind: "task", Status: types.TaskStatus{State: state}}
+	if answer != "" {
+		task.Artifacts = []types.Artifact{{ArtifactId: "answer-1", Name: "answer", Parts: []types.Part{&types.TextPart{Kind: "text", Text: answer}}}}
+	}
+	return task
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// style holds the ANSI escape codes of the terminal output, all empty when colors are off
+type style struct {
+	bold, dim, italic, code, heading, red, green, yellow, reset string
+}
+
+var ansi = style{
+	bold:    "\x1b[1m",
+	dim:     "\x1b[2m",
+	italic:  "\x1b[3m",
+	code:    "\x1b[36m",
+	heading: "\x1b[1;34m",
+	red:     "\x1b[31m",
+	green:   "\x1b[32m",
+	yellow:  "\x1b[33m",
+	reset:   "\x1b[0m",
+}
+
+// useColor reports whether the file is a terminal and NO_COLOR is not set
+func useColor(file *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+var (
+	boldPattern   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicPattern = regexp.MustCompile(`(^|[^*\w])[*_]([^*_\s][^*_]*)[*_]`)
+	linkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	listPattern   = regexp.MustCompile(`^(\s*)[-*+] `)
+)
+
+// renderer writes answers to out and state transitions and tool progress to status. Streamed text is
+// rendered line by line as it arrives, with markdown formatted for the terminal when colors are on.
+type renderer struct {
+	out    io.Writer
+	status io.Writer
+	style  style
+	// markdown formats answers, otherwise they are written as they are
+	markdown bool
+
+	pending string
+	inCode  bool
+}
+
+func newRenderer(out io.Writer, status io.Writer, color bool) *renderer {
+	r := &renderer{out: out, status: status, markdown: color}
+	if color {
+		r.style = ansi
+	}
+	return r
+}
+
+// Text renders streamed answer text, a trailing partial line waits for the rest of the line
+func (r *renderer) Text(text string) {
+	r.pending += text
+	for {
+		end := strings.IndexByte(r.pending, '\n')
+		if end < 0 {
+			return
+		}
+		line := r.pending[:end]
+		r.pending = r.pending[end+1:]
+		fmt.Fprintln(r.out, r.line(line))
+	}
+}
+
+// Flush ends the answer, rendering a pending partial line
+func (r *renderer) Flush() {
+	if r.pending != "" {
+		fmt.Fprintln(r.out, r.line(r.pending))
+		r.pending = ""
+	}
+	r.inCode = false
+}
+
+// Statusf writes a dimmed progress line
+func (r *renderer) Statusf(format string, args ...any) {
+	fmt.Fprintf(r.status, "%s%s%s\n", r.style.dim, fmt.Sprintf(format, args...), r.style.reset)
+}
+
+// Errorf writes an error line
+func (r *renderer) Errorf(format string, args ...any) {
+	fmt.Fprintf(r.status, "%s%s%s\n", r.style.red, fmt.Sprintf(format, args...), r.style.reset)
+}
+
+// line formats a line of markdown
+func (r *renderer) line(line string) string {
+	if !r.markdown {
+		return line
+	}
+	s := r.style
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "```") {
+		r.inCode = !r.inCode
+		if language := strings.TrimPrefix(trimmed, "```"); r.inCode && language != "" {
+			return s.dim + language + s.reset
+		}
+		return ""
+	}
+	if r.inCode {
+		return s.code + "    " + line + s.reset
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "#"):
+		heading := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		return s.heading + r.inline(heading, s.heading) + s.reset
+	case trimmed == "---" || trimmed == "***" || trimmed == "___":
+		return s.dim + strings.Repeat("─", 40) + s.reset
+	case strings.HasPrefix(trimmed, ">"):
+		return s.dim + "│ " + s.reset + s.italic + r.inline(strings.TrimSpace(strings.TrimPrefix(trimmed, ">")), s.italic) + s.reset
+	}
+	if match := listPattern.FindStringSubmatch(line); match != nil {
+		return match[1] + "• " + r.inline(line[len(match[0]):], "")
+	}
+	return r.inline(line, "")
+}
+
+// inline formats code spans, emphasis and links, restoring the style of the enclosing text after each
+func (r *renderer) inline(text string, enclosing string) string {
+	s := r.style
+	restore := s.reset + enclosing
+	segments := strings.Split(text, "`")
+	// An odd number of backticks leaves the last one unmatched, it is kept as text
+	unmatched := len(segments)%2 == 0
+	var b strings.Builder
+	for i, segment := range segments {
+		switch {
+		case unmatched && i == len(segments)-1:
+			b.WriteString("`" + r.emphasis(segment, restore))
+		case i%2 == 1:
+			b.WriteString(s.code + segment + restore)
+		default:
+			b.WriteString(r.emphasis(segment, restore))
+		}
+	}
+	return b.String()
+}
+
+// emphasis formats links, bold and italic text outside of code spans
+func (r *renderer) emphasis(text string, restore string) string {
+	s := r.style
+	text = linkPattern.ReplaceAllString(text, s.bold+"$1"+restore+" "+s.dim+"($2)"+restore)
+	text = boldPattern.ReplaceAllString(text, s.bold+"$1$2"+restore)
+	return italicPattern.ReplaceAllString(text, "$1"+s.italic+"$2"+restore)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const replHelp = `Type a message to ask the agent, messages of a session share one context.
+  /new            start a new conversation with a new context
+  /task [id]      show the state and answer of the last task, or of the given one
+  /cancel [id]    cancel the last task, or the given one
+  /help           show this help
+  /quit           exit, like Ctrl-D
+Ctrl-C cancels the running task.`
+
+// runREPL reads messages and commands until the input ends or the user quits
+func runREPL(s *session, in io.Reader, prompt io.Writer) error {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	s.render.Statusf("Context %s, /help lists the commands", s.contextID)
+	for {
+		fmt.Fprint(prompt, s.render.style.green+"> "+s.render.style.reset)
+		var line string
+		select {
+		case next, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(prompt)
+				return nil
+			}
+			line = strings.TrimSpace(next)
+		case <-s.signals:
+			fmt.Fprintln(prompt)
+			return nil
+		}
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "/") {
+			if err := s.send(line); err != nil {
+				if errors.Is(err, errInterrupted) {
+					continue
+				}
+				s.render.Errorf("%v", err)
+			}
+			continue
+		}
+		quit, err := s.command(line)
+		if err != nil {
+			s.render.Errorf("%v", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// command runs a REPL command, reporting whether the REPL should end
+func (s *session) command(line string) (bool, error) {
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+	taskID := func() (string, error) {
+		if len(args) > 0 {
+			return args[0], nil
+		}
+		if s.taskID == "" {
+			return "", fmt.Errorf("no task yet, send a message first or give a task id")
+		}
+		return s.taskID, nil
+	}
+
+	switch name {
+	case "/new":
+		s.reset()
+		s.render.Statusf("New conversation, context %s", s.contextID)
+	case "/task":
+		id, err := taskID()
+		if err != nil {
+			return false, err
+		}
+		return false, s.showTask(id)
+	case "/cancel":
+		id, err := taskID()
+		if err != nil {
+			return false, err
+		}
+		return false, s.cancel(id)
+	case "/help":
+		s.render.Statusf("%s", replHelp)
+	case "/quit", "/exit":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %s, /help lists the commands", name)
+	}
+	return false, nil
+}
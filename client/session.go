@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/client/agentclient"
+)
+
+// errInterrupted ends a stream after a second interrupt, while the first one cancels the task
+var errInterrupted = errors.New("interrupted")
+
+// session is a conversation with the agent. Every message carries the same context ID, and a task
+// that waits for input is continued by the next message.
+type session struct {
+	client  *agentclient.Client
+	render  *renderer
+	signals <-chan os.Signal
+
+	contextID string
+	// taskID is the task of the last turn, state its last known state
+	taskID string
+	state  types.TaskState
+	// artifactID is the artifact streamed last, a new one starts a new paragraph
+	artifactID string
+}
+
+func newSession(url string, render *renderer, signals <-chan os.Signal) *session {
+	return &session{
+		client:    agentclient.New(url),
+		render:    render,
+		signals:   signals,
+		contextID: uuid.NewString(),
+	}
+}
+
+// reset starts a new conversation
+func (s *session) reset() {
+	s.contextID = uuid.NewString()
+	s.taskID = ""
+	s.state = ""
+}
+
+// send streams the answer to a prompt
+func (s *session) send(prompt string) error {
+	message := &types.Message{
+		Kind:      "message",
+		MessageID: uuid.NewString(),
+		ContextID: s.contextID,
+		Role:      types.User,
+		Parts:     []types.Part{&types.TextPart{Kind: "text", Text: prompt}},
+	}
+	if s.taskID != "" && (s.state == types.InputRequired || s.state == types.AuthRequired) {
+		message.TaskID = s.taskID
+	} else {
+		s.taskID, s.state = "", ""
+	}
+	return s.stream(func(ctx context.Context) (*agentclient.Stream, error) {
+		return s.client.SendStream(ctx, types.MessageSendParam{Message: message})
+	})
+}
+
+// follow reattaches to the event stream of a task, replaying its events from the given index
+func (s *session) follow(taskID string, from int) error {
+	s.taskID, s.state = taskID, ""
+	return s.stream(func(ctx context.Context) (*agentclient.Stream, error) {
+		return s.client.Resubscribe(ctx, taskID, from)
+	})
+}
+
+// stream renders the events of a stream until it ends. An interrupt cancels the task, a second one
+// stops waiting for the stream.
+func (s *session) stream(start func(ctx context.Context) (*agentclient.Stream, error)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := start(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	events := make(chan types.Event)
+	done := make(chan error, 1)
+	go func() {
+		defer close(events)
+		for {
+			event, err := stream.Next()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					done <- err
+				}
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	s.artifactID = ""
+	defer s.render.Flush()
+
+	canceling := false
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				select {
+				case err := <-done:
+					if s.taskID != "" && !s.finished() {
+						s.render.Errorf("The stream broke, reattach with -task %s -from %d", s.taskID, stream.Received())
+					}
+					return err
+				default:
+					return nil
+				}
+			}
+			s.handle(event)
+		case <-s.signals:
+			if canceling || s.taskID == "" {
+				return errInterrupted
+			}
+			canceling = true
+			s.render.Statusf("Canceling task %s, interrupt again to stop waiting", s.taskID)
+			if err := s.cancel(s.taskID); err != nil {
+				s.render.Errorf("%v", err)
+			}
+		}
+	}
+}
+
+// handle renders a stream event
+func (s *session) handle(event types.Event) {
+	switch typed := event.(type) {
+	case *types.TaskArtifactUpdateEvent:
+		s.track(typed.TaskId, typed.ContextId)
+		s.artifact(typed)
+	case *types.TaskStatusUpdateEvent:
+		s.track(typed.TaskId, typed.ContextId)
+		s.transition(typed.Status)
+	case *types.Task:
+		s.track(typed.Id, typed.ContextId)
+		s.transition(typed.Status)
+	case *types.Message:
+		s.render.Text(agentclient.PartsText(typed.Parts))
+		s.render.Flush()
+	}
+}
+
+// track follows the task and the context the server assigned
+func (s *session) track(taskID string, contextID string) {
+	if taskID != "" {
+		s.taskID = taskID
+	}
+	if contextID != "" {
+		s.contextID = contextID
+	}
+}
+
+// transition shows a change of the task state and the message that came with it
+func (s *session) transition(status types.TaskStatus) {
+	text := agentclient.PartsText(messageParts(status.Message))
+	if status.State == s.state && text == "" {
+		return
+	}
+	if status.State != s.state {
+		s.render.Flush()
+		s.render.Statusf("» %s", status.State)
+		s.state = status.State
+	}
+	if text == "" {
+		return
+	}
+	switch status.State {
+	case types.WORKING, types.SUBMITTED:
+		s.render.Statusf("  %s", text)
+	default:
+		// Failures, rejections, questions for the user and notes on the outcome are part of the answer
+		s.render.Text(text)
+		s.render.Flush()
+	}
+}
+
+// artifact renders streamed answer text, and results of tools as progress
+func (s *session) artifact(event *types.TaskArtifactUpdateEvent) {
+	if event.Artifact == nil {
+		return
+	}
+	if event.Artifact.ArtifactId != s.artifactID && !event.Append {
+		s.render.Flush()
+	}
+	s.artifactID = event.Artifact.ArtifactId
+	for _, part := range event.Artifact.Parts {
+		switch typed := part.(type) {
+		case *types.TextPart:
+			s.render.Text(typed.Text)
+		case *types.DataPart:
+			s.render.Statusf("  %s", toolProgress(event.Artifact, typed))
+		case *types.FilePart:
+			s.render.Statusf("  file %s", event.Artifact.Name)
+		}
+	}
+	if event.LastChunk {
+		s.render.Flush()
+	}
+}
+
+// toolProgress describes the result of a tool call, the data artifacts are named after the tool
+func toolProgress(artifact *types.Artifact, part *types.DataPart) string {
+	tool := artifact.Name
+	if tool == "" {
+		tool = "tool"
+	}
+	if count, ok := part.Data["count"].(float64); ok {
+		if count == 1 {
+			return fmt.Sprintf("%s returned 1 result", tool)
+		}
+		return fmt.Sprintf("%s returned %d results", tool, int(count))
+	}
+	return fmt.Sprintf("%s returned a result", tool)
+}
+
+// finished reports whether the last known state of the task is final
+func (s *session) finished() bool {
+	task := types.Task{Status: types.TaskStatus{State: s.state}}
+	return task.Done()
+}
+
+// cancel asks the agent to cancel a task
+func (s *session) cancel(taskID string) error {
+	task, err := s.client.CancelTask(context.Background(), types.TaskIdParams{Id: taskID})
+	if err != nil {
+		return fmt.Errorf("cancel %s: %w", taskID, err)
+	}
+	s.render.Statusf("Task %s is %s", task.Id, task.Status.State)
+	if task.Id == s.taskID {
+		s.state = task.Status.State
+	}
+	return nil
+}
+
+// showTask prints the state and the answer of a task
+func (s *session) showTask(taskID string) error {
+	task, err := s.client.GetTask(context.Background(), types.TaskQueryParams{Id: taskID})
+	if err != nil {
+		return fmt.Errorf("get task %s: %w", taskID, err)
+	}
+	s.render.Statusf("Task %s in context %s is %s", task.Id, task.ContextId, task.Status.State)
+	if text := agentclient.PartsText(messageParts(task.Status.Message)); text != "" {
+		s.render.Statusf("  %s", text)
+	}
+	for _, artifact := range task.Artifacts {
+		if text := agentclient.PartsText(artifact.Parts); text != "" {
+			s.render.Text(text)
+			s.render.Flush()
+			continue
+		}
+		s.render.Statusf("  artifact %s", artifact.Name)
+	}
+	return nil
+}
+
+func messageParts(message *types.Message) []types.Part {
+	if message == nil {
+		return nil
+	}
+	return message.Parts
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/client/internal/fakeagent"
+)
+
+// answer is the stream of a task answering text in two chunks
+func answer(t *testing.T, first string, rest string) []string {
+	return []string{
+		fakeagent.Result(t, fakeagent.Status(types.SUBMITTED, false)),
+		fakeagent.Result(t, fakeagent.Status(types.WORKING, false)),
+		fakeagent.Result(t, fakeagent.Chunk("answer-1", first, false, false)),
+		fakeagent.Result(t, fakeagent.Chunk("answer-1", rest, true, true)),
+		fakeagent.Result(t, fakeagent.Status(types.COMPLETED, true)),
+	}
+}
+
+// newTestSession returns a session with the agent whose answers and status lines are written to buffers
+func newTestSession(agent *fakeagent.Agent) (*session, *bytes.Buffer, *bytes.Buffer) {
+	var out, status bytes.Buffer
+	return newSession(agent.URL, newRenderer(&out, &status, false), make(chan os.Signal)), &out, &status
+}
+
+func TestSessionReusesContext(t *testing.T) {
+	agent := fakeagent.New(t,
+		answer(t, "Two open ", "issues."),
+		answer(t, "One of them ", "is a bug."),
+	)
+	s, out, status := newTestSession(agent)
+	initial := s.contextID
+
+	if err := s.send("How many open issues?"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.send("Which are bugs?"); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := agent.Requests()
+	if len(requests) != 2 || requests[0].Method != types.MethodMessageStream {
+		t.Fatalf("requests %+v", requests)
+	}
+	// The first message carries the context of the session, later ones the context the agent assigned
+	if got := requests[0].Message()["context_id"]; got != initial {
+		t.Errorf("first message in context %v, want %s", got, initial)
+	}
+	if got := requests[1].Message()["context_id"]; got != fakeagent.ContextID {
+		t.Errorf("second message in context %v, want %s", got, fakeagent.ContextID)
+	}
+	// A completed task is not continued
+	if taskID, ok := requests[1].Message()["task_id"]; ok {
+		t.Errorf("second message continues task %v", taskID)
+	}
+
+	if got := out.String(); got != "Two open issues.\nOne of them is a bug.\n" {
+		t.Errorf("answers %q", got)
+	}
+	if !strings.Contains(status.String(), "» working") || !strings.Contains(status.String(), "» completed") {
+		t.Errorf("status lines %q", status.String())
+	}
+}
+
+func TestSessionContinuesTaskWaitingForInput(t *testing.T) {
+	question := fakeagent.Status(types.InputRequired, true)
+	question.Status.Message = &types.Message{Kind: "message", Role: types.Agent, Parts: []types.Part{&types.TextPart{Kind: "text", Text: "Which repository?"}}}
+	agent := fakeagent.New(t,
+		[]string{fakeagent.Result(t, fakeagent.Status(types.WORKING, false)), fakeagent.Result(t, question)},
+		answer(t, "octo/demo has ", "two."),
+	)
+	s, out, _ := newTestSession(agent)
+
+	if err := s.send("How many open issues?"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.send("octo/demo"); err != nil {
+		t.Fatal(err)
+	}
+	if got := agent.Requests()[1].Message()["task_id"]; got != fakeagent.TaskID {
+		t.Errorf("answer to the question sent for task %v, want %s", got, fakeagent.TaskID)
+	}
+	if got := out.String(); got != "Which repository?\nocto/demo has two.\n" {
+		t.Errorf("output %q", got)
+	}
+}
+
+func TestREPLCommands(t *testing.T) {
+	agent := fakeagent.New(t,
+		answer(t, "Two open ", "issues."),
+		[]string{fakeagent.Result(t, fakeagent.Task(types.COMPLETED, "Two open issues."))},
+		[]string{fakeagent.Result(t, fakeagent.Task(types.CANCELED, ""))},
+	)
+	s, out, status := newTestSession(agent)
+	var prompt bytes.Buffer
+
+	input := "/task\nHow many open issues?\n\n/task\n/cancel\n/bogus\n/new\n/quit\nnot sent\n"
+	if err := runREPL(s, strings.NewReader(input), &prompt); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := agent.Requests()
+	var methods []string
+	for _, request := range requests {
+		methods = append(methods, request.Method)
+	}
+	if strings.Join(methods, ",") != "message/stream,tasks/get,tasks/cancel" {
+		t.Errorf("methods %v", methods)
+	}
+	if id := requests[1].Params["id"]; id != fakeagent.TaskID {
+		t.Errorf("/task asked for %v", id)
+	}
+
+	for _, want := range []string{
+		"no task yet",
+		"Task task-1 in context context-1 is completed",
+		"Task task-1 is canceled",
+		"unknown command /bogus",
+		"New conversation, context " + s.contextID,
+	} {
+		if !strings.Contains(status.String(), want) {
+			t.Errorf("status output %q does not contain %q", status.String(), want)
+		}
+	}
+	// The answer is printed when streamed and again by /task
+	if got := out.String(); got != "Two open issues.\nTwo open issues.\n" {
+		t.Errorf("answers %q", got)
+	}
+	if s.contextID == fakeagent.ContextID || s.taskID != "" {
+		t.Errorf("/new kept context %s and task %s", s.contextID, s.taskID)
+	}
+	if strings.Count(prompt.String(), "> ") != 8 {
+		t.Errorf("%d prompts for 8 lines up to /quit", strings.Count(prompt.String(), "> "))
+	}
+}
+
+func TestOneShotExitCode(t *testing.T) {
+	failed := []string{
+		fakeagent.Result(t, fakeagent.Status(types.WORKING, false)),
+		fakeagent.Result(t, fakeagent.Status(types.FAILED, true)),
+	}
+	for name, test := range map[string]struct {
+		script []string
+		want   int
+	}{
+		"completed": {answer(t, "Two ", "issues."), 0},
+		"failed":    {failed, 1},
+		"no answer": {nil, 1},
+	} {
+		s, _, _ := newTestSession(fakeagent.New(t, test.script))
+		if got := exitCode(s, s.send("How many open issues?")); got != test.want {
+			t.Errorf("%s: exit code %d, want %d", name, got, test.want)
+		}
+	}
+}
+
+func TestRendererStreamsLines(t *testing.T) {
+	var out bytes.Buffer
+	r := newRenderer(&out, &out, false)
+
+	// Partial lines wait for the rest of the line
+	r.Text("## Open ")
+	if out.Len() != 0 {
+		t.Errorf("partial line rendered: %q", out.String())
+	}
+	r.Text("issues\n- #7 `flaky test`\n- #9")
+	if got := out.String(); got != "## Open issues\n- #7 `flaky test`\n" {
+		t.Errorf("plain output %q", got)
+	}
+	r.Flush()
+	if !strings.HasSuffix(out.String(), "- #9\n") {
+		t.Errorf("flushed output %q", out.String())
+	}
+
+	out.Reset()
+	r = newRenderer(&out, &out, true)
+	r.Text("## Open issues\n- #7 **flaky** `test`\n```go\nx := 1\n```\n")
+	for _, want := range []string{
+		ansi.heading + "Open issues" + ansi.reset,
+		"• #7 " + ansi.bold + "flaky" + ansi.reset + " " + ansi.code + "test" + ansi.reset,
+		ansi.code + "    x := 1" + ansi.reset,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("markdown output %q does not contain %q", out.String(), want)
+		}
+	}
+	if strings.Contains(out.String(), "```") {
+		t.Errorf("code fence rendered: %q", out.String())
+	}
+}
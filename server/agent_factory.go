@@ -3,6 +3,7 @@ package main
 import (
 	_ "embed"
 
+	a2atypes "github.com/yeeaiclub/a2a-go/sdk/types"
 	toolset2 "github.com/yeeaiclub/github-a2a/server/toolset"
 	"github.com/yeeaiclub/github-a2a/types"
 )
@@ -13,16 +14,22 @@ var systemPrompt string
 // AgentConfig represents the configuration for an agent
 type AgentConfig struct {
 	Tools        map[string]types.Function `json:"tools"`
+	Skills       []a2atypes.AgentSkill     `json:"skills"`
 	SystemPrompt string                    `json:"system_prompt"`
 }
 
-// GithubAgent creates a GitHub agent with its tools
-func GithubAgent() *AgentConfig {
-	toolset := toolset2.NewGitHubToolset()
-	tools := toolset.GetTools()
+// GithubAgent creates a GitHub agent with the tools enabled for this deployment
+func GithubAgent(config toolset2.RegistryConfig, githubToken string, opts ...toolset2.GitHubOption) (*AgentConfig, error) {
+	toolset := toolset2.NewGitHubToolset(githubToken, opts...)
+	registry := toolset2.NewRegistry(config)
+	toolset.Register(registry)
+	if err := registry.Validate(); err != nil {
+		return nil, err
+	}
 
 	return &AgentConfig{
-		Tools:        tools,
+		Tools:        registry.Tools(),
+		Skills:       registry.Skills(),
 		SystemPrompt: systemPrompt,
-	}
+	}, nil
 }
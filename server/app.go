@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/yeeaiclub/a2a-go/sdk/server/handler"
+	"github.com/yeeaiclub/a2a-go/sdk/server/tasks"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/config"
+	"github.com/yeeaiclub/github-a2a/server/metrics"
+	"github.com/yeeaiclub/github-a2a/server/push"
+	"github.com/yeeaiclub/github-a2a/server/schedule"
+	"github.com/yeeaiclub/github-a2a/server/store"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+	"github.com/yeeaiclub/github-a2a/server/webhook"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// App is the agent wired from a configuration, ready to be served
+type App struct {
+	// Handler serves the agent card and the JSON-RPC API
+	Handler http.Handler
+	// Debug serves the debug endpoints, it belongs on a listener clients cannot reach
+	Debug  http.Handler
+	Card   AgentCard
+	Queues *QueueManager
+	// Push delivers push notifications, nil when they are disabled
+	Push *push.Notifier
+	// Scheduler runs the scheduled digests, nil when none are configured
+	Scheduler *schedule.Scheduler
+	// Webhooks receives GitHub webhook deliveries, nil when no webhook secret is configured
+	Webhooks *webhook.Receiver
+
+	closeStore func() error
+}
+
+// AppOption configures optional App dependencies
+type AppOption func(o *appOptions)
+
+type appOptions struct {
+	chatClient    toolset.ChatClient
+	githubOptions []toolset.GitHubOption
+}
+
+// WithAppChatClient answers completion requests with client instead of the configured DeepSeek API
+func WithAppChatClient(client toolset.ChatClient) AppOption {
+	return func(o *appOptions) {
+		o.chatClient = client
+	}
+}
+
+// WithAppGitHubOptions adds options to the GitHub toolset, applied after the ones from the configuration
+func WithAppGitHubOptions(opts ...toolset.GitHubOption) AppOption {
+	return func(o *appOptions) {
+		o.githubOptions = append(o.githubOptions, opts...)
+	}
+}
+
+// NewApp builds the agent from a validated configuration. Tracing, logging and metrics
+// registration are process wide and left to the caller.
+func NewApp(cfg config.Config, opts ...AppOption) (*App, error) {
+	options := &appOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// Create agent configuration
+	var githubOptions []toolset.GitHubOption
+	if cfg.GitHub.APIURL != "" {
+		githubOptions = append(githubOptions, toolset.WithGitHubBaseURL(cfg.GitHub.APIURL))
+	}
+	githubOptions = append(githubOptions, options.githubOptions...)
+	agentConfig, err := GithubAgent(toolset.RegistryConfig{
+		EnabledSkills:  cfg.Tools.EnabledSkills,
+		DisabledSkills: cfg.Tools.DisabledSkills,
+		EnabledTools:   cfg.Tools.EnabledTools,
+		DisabledTools:  cfg.Tools.DisabledTools,
+	}, cfg.GitHub.Token, githubOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tool configuration: %w", err)
+	}
+	if len(agentConfig.Tools) == 0 {
+		return nil, fmt.Errorf("no tools are enabled, check the enabled skills and tools")
+	}
+
+	agentCard := NewAgentCard(CardConfig{
+		Name:                 cfg.Agent.Name,
+		Description:          cfg.Agent.Description,
+		Version:              cfg.Agent.Version,
+		URL:                  cfg.Agent.URL,
+		DocumentationURL:     cfg.Agent.DocumentationURL,
+		IconURL:              cfg.Agent.IconURL,
+		ProviderOrganization: cfg.Agent.Provider,
+		ProviderURL:          cfg.Agent.ProviderURL,
+		Streaming:            true,
+		PushNotifications:    cfg.Push.Enabled,
+	}, agentConfig.Skills)
+	if err := agentCard.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid agent card: %w", err)
+	}
+
+	taskStore, closeStore, err := store.Open(store.Config{
+		Driver:        cfg.Store.Driver,
+		Path:          cfg.Store.Path,
+		Retention:     cfg.Store.Retention,
+		CompactOnOpen: cfg.Store.CompactOnOpen,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open task store: %w", err)
+	}
+
+	queueOptions := []QueueOption{
+		WithQueueCapacity(cfg.Queue.Capacity),
+		WithIdleTTL(cfg.Queue.IdleTTL),
+		WithEventLog(cfg.Queue.EventLogSize, cfg.Queue.EventLogTTL),
+		WithAbortGrace(cfg.Server.AbortGrace),
+		WithEventListener(taskMetrics{}),
+	}
+
+	var pushNotifier *push.Notifier
+	if cfg.Push.Enabled {
+		pushNotifier = push.NewNotifier(push.Config{
+			SigningSecret: cfg.Push.SigningSecret,
+			AllowedHosts:  cfg.Push.AllowedHosts,
+			MaxAttempts:   cfg.Push.MaxAttempts,
+		}, nil)
+		queueOptions = append(queueOptions, WithEventListener(pushNotifier))
+	}
+
+	queueManager := NewQueueManager(queueOptions...)
+
+	handlerOptions := []handler.HandlerOption{
+		handler.WithQueueManger(queueManager),
+	}
+	// A nil *push.Notifier must not end up in the interface, the handlers check for nil
+	var notifier tasks.PushNotifier
+	if pushNotifier != nil {
+		notifier = pushNotifier
+		handlerOptions = append(handlerOptions, handler.WithPushNotifier(pushNotifier))
+	}
+
+	executorOptions := executorOptionsFor(cfg)
+	if options.chatClient != nil {
+		executorOptions = append(executorOptions, toolset.WithChatClient(options.chatClient))
+	}
+	executor := toolset.NewExecutor(
+		taskStore,
+		&agentCard.AgentCard,
+		agentConfig.Tools,
+		cfg.LLM.APIKey,
+		agentConfig.SystemPrompt,
+		executorOptions...,
+	)
+	defaultHandler := handler.NewDefaultHandler(
+		taskStore,
+		queueManager.Wrap(executor, taskStore),
+		handlerOptions...,
+	)
+
+	taskHandler := NewTaskHandler(defaultHandler, taskStore, queueManager, notifier)
+	server := handler.NewServer(
+		cfg.Server.CardPath,
+		cfg.Server.APIPath,
+		agentCard.AgentCard,
+		taskHandler,
+	)
+
+	var scheduler *schedule.Scheduler
+	if len(cfg.Schedule.Jobs) > 0 {
+		scheduler, err = newScheduler(cfg, taskHandler, taskStore, pushNotifier, githubOptions)
+		if err != nil {
+			queueManager.Stop()
+			closeStore()
+			return nil, fmt.Errorf("invalid schedule: %w", err)
+		}
+	}
+
+	var webhooks *webhook.Receiver
+	if cfg.GitHub.Webhook.Secret != "" {
+		webhooks, err = newWebhookReceiver(cfg.GitHub.Webhook, taskHandler, taskStore, pushNotifier)
+		if err != nil {
+			queueManager.Stop()
+			closeStore()
+			return nil, fmt.Errorf("invalid webhook rules: %w", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Server.CardPath, cardHandler(agentCard))
+	mux.Handle(types.AgentCardPath, cardHandler(agentCard))
+	// The request span continues the W3C trace context sent by the client and is named after the JSON-RPC method
+	mux.Handle(cfg.Server.APIPath, otelhttp.NewHandler(rpcGuard(server, taskStore, cfg.Push.Enabled), "a2a",
+		otelhttp.WithSpanNameFormatter(requestSpanName)))
+	mux.Handle("/schemas/", schemasHandler("/schemas/"))
+	mux.Handle("/metrics", metrics.Handler())
+	if webhooks != nil {
+		mux.Handle(cfg.GitHub.Webhook.Path, otelhttp.NewHandler(webhooks, "github-webhook"))
+	}
+
+	debug := http.NewServeMux()
+	debug.Handle("/debug/queues", queuesHandler(queueManager))
+	debug.Handle("/debug/contexts/", contextsHandler("/debug/contexts/", taskStore))
+	if pushNotifier != nil {
+		debug.Handle("/debug/push-deliveries", pushNotifier.DeliveriesHandler())
+	}
+	if scheduler != nil {
+		debug.Handle("/debug/schedules", scheduler.Handler())
+	}
+	if webhooks != nil {
+		debug.Handle("/debug/webhooks", webhooks.DeliveriesHandler())
+	}
+
+	return &App{
+		Handler:    mux,
+		Debug:      debug,
+		Card:       agentCard,
+		Queues:     queueManager,
+		Push:       pushNotifier,
+		Scheduler:  scheduler,
+		Webhooks:   webhooks,
+		closeStore: closeStore,
+	}, nil
+}
+
+// Close stops the scheduler and the queues and closes the task store, running tasks should be drained first
+func (a *App) Close() error {
+	if a.Scheduler != nil {
+		a.Scheduler.Stop()
+	}
+	a.Queues.Stop()
+	return a.closeStore()
+}
+
+// executorOptionsFor returns the executor settings of the configuration
+func executorOptionsFor(cfg config.Config) []toolset.ExecutorOption {
+	return []toolset.ExecutorOption{
+		toolset.WithModel(cfg.LLM.Model),
+		toolset.WithBaseURL(cfg.LLM.BaseURL),
+		toolset.WithMaxIterations(cfg.LLM.MaxIterations),
+		toolset.WithContextBudget(toolset.ContextBudget{
+			MaxContextTokens:    cfg.LLM.MaxContextTokens,
+			MaxToolResultTokens: cfg.LLM.MaxToolResultTokens,
+			CompletionReserve:   cfg.LLM.CompletionReserve,
+		}),
+		toolset.WithMaxRepairAttempts(cfg.Tools.MaxRepairAttempts),
+		toolset.WithInputLimits(toolset.InputLimits{
+			MaxFileBytes:     cfg.Input.MaxFileBytes,
+			MaxInputBytes:    cfg.Input.MaxInputBytes,
+			AllowedFileHosts: cfg.Input.AllowedFileHosts,
+		}),
+	}
+}
+
+// contextsHandler serves the stored tasks of the context named by the path after the prefix as JSON
+func contextsHandler(prefix string, taskStore store.TaskStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		contextID := strings.TrimPrefix(r.URL.Path, prefix)
+		if contextID == "" {
+			http.NotFound(w, r)
+			return
+		}
+		found, err := taskStore.ListByContext(r.Context(), contextID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if found == nil {
+			found = []*types.Task{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(found); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+)
+
+// CardConfig holds the deployment specific parts of the agent card
+type CardConfig struct {
+	Name                 string `json:"name"`
+	Description          string `json:"description"`
+	Version              string `json:"version"`
+	URL                  string `json:"url"`
+	DocumentationURL     string `json:"documentation_url,omitempty"`
+	IconURL              string `json:"icon_url,omitempty"`
+	ProviderOrganization string `json:"provider_organization,omitempty"`
+	ProviderURL          string `json:"provider_url,omitempty"`
+	Streaming            bool   `json:"streaming"`
+	PushNotifications    bool   `json:"push_notifications"`
+}
+
+// AgentCapabilities extends the SDK capabilities with the push notification flag
+type AgentCapabilities struct {
+	types.AgentCapabilities
+	PushNotifications bool `json:"push_notifications,omitempty"`
+}
+
+// AgentCard extends the SDK agent card with the fields it does not model yet.
+// The outer Capabilities field shadows the embedded one when encoding.
+type AgentCard struct {
+	types.AgentCard
+	DocumentationURL string             `json:"documentation_url,omitempty"`
+	Capabilities     *AgentCapabilities `json:"capabilities,omitempty"`
+}
+
+// NewAgentCard builds the agent card from configuration and the enabled skills
+func NewAgentCard(config CardConfig, skills []types.AgentSkill) AgentCard {
+	capabilities := &AgentCapabilities{
+		AgentCapabilities: types.AgentCapabilities{
+			Streaming: config.Streaming,
+		},
+		PushNotifications: config.PushNotifications,
+	}
+
+	card := AgentCard{
+		AgentCard: types.AgentCard{
+			Name:              config.Name,
+			Description:       config.Description,
+			URL:               config.URL,
+			Version:           config.Version,
+			IconUrl:           config.IconURL,
+			Skills:            skills,
+			DefaultInputModes: toolset.InputModes,
+			DefaultOutputModes: []string{
+				"text",
+				toolset.DataMimeType,
+			},
+			Capabilities: &capabilities.AgentCapabilities,
+		},
+		DocumentationURL: config.DocumentationURL,
+		Capabilities:     capabilities,
+	}
+	if config.ProviderOrganization != "" {
+		card.Provider = &types.AgentProvider{
+			Organization: config.ProviderOrganization,
+			URL:          config.ProviderURL,
+		}
+	}
+	return card
+}
+
+// Validate reports every problem that would make the card unusable for A2A clients
+func (c AgentCard) Validate() error {
+	var errs []error
+	if strings.TrimSpace(c.Name) == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if strings.TrimSpace(c.Description) == "" {
+		errs = append(errs, errors.New("description is required"))
+	}
+	if strings.TrimSpace(c.Version) == "" {
+		errs = append(errs, errors.New("version is required"))
+	}
+	if err := validateURL("url", c.URL, true); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("documentation_url", c.DocumentationURL, false); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("icon_url", c.IconUrl, false); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Provider != nil {
+		if err := validateURL("provider url", c.Provider.URL, false); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(c.DefaultInputModes) == 0 || len(c.DefaultOutputModes) == 0 {
+		errs = append(errs, errors.New("default input and output modes are required"))
+	}
+
+	if len(c.Skills) == 0 {
+		errs = append(errs, errors.New("at least one skill is required"))
+	}
+	seen := make(map[string]bool)
+	for i, skill := range c.Skills {
+		if skill.Id == "" || skill.Name == "" || skill.Description == "" {
+			errs = append(errs, fmt.Errorf("skill %d needs an id, name and description", i))
+		}
+		if seen[skill.Id] {
+			errs = append(errs, fmt.Errorf("skill id %q is duplicated", skill.Id))
+		}
+		seen[skill.Id] = true
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid agent card: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func validateURL(field string, value string, required bool) error {
+	if value == "" {
+		if required {
+			return fmt.Errorf("%s is required", field)
+		}
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s must be an absolute http(s) URL, got %q", field, value)
+	}
+	return nil
+}
+
+// cardHandler serves the agent card as JSON
+func cardHandler(card AgentCard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(card); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// schemasHandler serves the JSON schemas of the data artifacts, all of them at the prefix
+// and a single one at the prefix followed by its name
+func schemasHandler(prefix string) http.HandlerFunc {
+	schemas := toolset.ArtifactSchemas()
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body any = schemas
+		if name := strings.TrimPrefix(r.URL.Path, prefix); name != "" {
+			schema, ok := schemas[name]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			body = schema
+		}
+		w.Header().Set("Content-Type", "application/schema+json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
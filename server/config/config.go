@@ -0,0 +1,558 @@
+// Package config loads the server configuration from a YAML file, environment variables and flags
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete server configuration
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Agent    AgentConfig    `yaml:"agent"`
+	LLM      LLMConfig      `yaml:"llm"`
+	GitHub   GitHubConfig   `yaml:"github"`
+	Tools    ToolsConfig    `yaml:"tools"`
+	Input    InputConfig    `yaml:"input"`
+	Store    StoreConfig    `yaml:"store"`
+	Queue    QueueConfig    `yaml:"queue"`
+	Push     PushConfig     `yaml:"push"`
+	Schedule ScheduleConfig `yaml:"schedule"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+// ServerConfig configures the HTTP listener
+type ServerConfig struct {
+	Addr         string        `yaml:"addr"`
+	CardPath     string        `yaml:"card_path"`
+	APIPath      string        `yaml:"api_path"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout is how long running tasks may take to finish after a shutdown signal. It includes
+	// the abort grace, tasks still running are canceled that long and a second before it ends.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// AbortGrace is how long a task canceled at shutdown may take to record its final status
+	AbortGrace time.Duration `yaml:"abort_grace"`
+	// DebugAddr is a separate listener for the debug endpoints, such as localhost:6060. They list task IDs
+	// and other users' data, so they are not served when it is empty and must not be reachable by clients.
+	DebugAddr string `yaml:"debug_addr"`
+}
+
+// AgentConfig describes the agent on its card
+type AgentConfig struct {
+	Name             string `yaml:"name"`
+	Description      string `yaml:"description"`
+	Version          string `yaml:"version"`
+	URL              string `yaml:"url"`
+	DocumentationURL string `yaml:"documentation_url"`
+	IconURL          string `yaml:"icon_url"`
+	Provider         string `yaml:"provider"`
+	ProviderURL      string `yaml:"provider_url"`
+}
+
+// LLMConfig configures the chat completion API
+type LLMConfig struct {
+	APIKey        string `yaml:"api_key"`
+	BaseURL       string `yaml:"base_url"`
+	Model         string `yaml:"model"`
+	MaxIterations int    `yaml:"max_iterations"`
+	// MaxContextTokens is the context window of the model, older tool turns are evicted to stay within it
+	MaxContextTokens int `yaml:"max_context_tokens"`
+	// MaxToolResultTokens bounds a single tool result, larger results are truncated
+	MaxToolResultTokens int `yaml:"max_tool_result_tokens"`
+	// CompletionReserve is kept free in the context window for the answer
+	CompletionReserve int `yaml:"completion_reserve"`
+}
+
+// GitHubConfig configures access to GitHub
+type GitHubConfig struct {
+	Token string `yaml:"token"`
+	// APIURL is the REST API root, set it for GitHub Enterprise; the public API when empty
+	APIURL  string              `yaml:"api_url"`
+	Webhook GitHubWebhookConfig `yaml:"webhook"`
+}
+
+// GitHubWebhookConfig configures the endpoint receiving GitHub webhook deliveries, it is mounted when
+// a secret is set
+type GitHubWebhookConfig struct {
+	Path string `yaml:"path"`
+	// Secret is the secret of the webhook on GitHub, deliveries without a valid signature are refused
+	Secret       string        `yaml:"secret"`
+	MaxBodyBytes int64         `yaml:"max_body_bytes"`
+	Rules        []WebhookRule `yaml:"rules"`
+}
+
+// WebhookRule starts a task for the deliveries of one event that match its filters
+type WebhookRule struct {
+	Name string `yaml:"name"`
+	// Event is push, pull_request, issues or workflow_run
+	Event string `yaml:"event"`
+	// Actions such as opened or closed, Conclusions such as failure for workflow runs; any when empty
+	Actions     []string `yaml:"actions"`
+	Conclusions []string `yaml:"conclusions"`
+	// Repos and Branches are glob patterns such as "octo/*", any when empty
+	Repos    []string `yaml:"repos"`
+	Branches []string `yaml:"branches"`
+	// Prompt is a text/template with the fields of the event such as .Repo, .Number and .Title, a prompt for the event when empty
+	Prompt string `yaml:"prompt"`
+	// ResultWebhook receives the finished task as a push notification, ResultWebhookToken is sent as a Bearer token
+	ResultWebhook      string `yaml:"result_webhook"`
+	ResultWebhookToken string `yaml:"result_webhook_token"`
+}
+
+// ToolsConfig selects the tools offered to the model
+type ToolsConfig struct {
+	EnabledSkills     []string `yaml:"enabled_skills"`
+	DisabledSkills    []string `yaml:"disabled_skills"`
+	EnabledTools      []string `yaml:"enabled_tools"`
+	DisabledTools     []string `yaml:"disabled_tools"`
+	MaxRepairAttempts int      `yaml:"max_repair_attempts"`
+}
+
+// StoreConfig configures the task store
+type StoreConfig struct {
+	Driver        string        `yaml:"driver"`
+	Path          string        `yaml:"path"`
+	Retention     time.Duration `yaml:"retention"`
+	CompactOnOpen bool          `yaml:"compact_on_open"`
+}
+
+// QueueConfig configures the per-task event queues
+type QueueConfig struct {
+	Capacity     uint          `yaml:"capacity"`
+	IdleTTL      time.Duration `yaml:"idle_ttl"`
+	EventLogSize int           `yaml:"event_log_size"`
+	EventLogTTL  time.Duration `yaml:"event_log_ttl"`
+}
+
+// InputConfig bounds the files and structured data of incoming messages
+type InputConfig struct {
+	MaxFileBytes  int `yaml:"max_file_bytes"`
+	MaxInputBytes int `yaml:"max_input_bytes"`
+	// AllowedFileHosts are the hosts file parts may be downloaded from, files given by URL are refused when empty
+	AllowedFileHosts []string `yaml:"allowed_file_hosts"`
+}
+
+// PushConfig configures push notification delivery
+type PushConfig struct {
+	// Enabled lets clients register webhooks the server POSTs task contents to, it requires a SigningSecret
+	Enabled       bool   `yaml:"enabled"`
+	SigningSecret string `yaml:"signing_secret"`
+	// AllowedHosts restricts webhooks to these hosts, internal addresses are only reached when their host is listed
+	AllowedHosts []string `yaml:"allowed_hosts"`
+	MaxAttempts  int      `yaml:"max_attempts"`
+}
+
+// ScheduleConfig configures the digests the agent runs on its own
+type ScheduleConfig struct {
+	// StatePath keeps the fingerprints of the repositories covered by the last digests across restarts
+	StatePath string        `yaml:"state_path"`
+	Jobs      []ScheduleJob `yaml:"jobs"`
+}
+
+// ScheduleJob is a prompt run on a cron schedule for a set of repositories
+type ScheduleJob struct {
+	Name string `yaml:"name"`
+	// Cron is a five field cron expression such as "0 8 * * 1-5", or @daily, @weekly and the like
+	Cron string `yaml:"cron"`
+	// Timezone is the IANA time zone the expression is evaluated in, the local one when empty
+	Timezone string   `yaml:"timezone"`
+	Repos    []string `yaml:"repos"`
+	// Window is how far back a digest looks, 24h when zero
+	Window time.Duration `yaml:"window"`
+	// Prompt is a text/template with .Repos, .Skipped, .Since, .Until and .Window, a digest prompt when empty
+	Prompt string `yaml:"prompt"`
+	// Webhook receives the finished task as a push notification, WebhookToken is sent as a Bearer token
+	Webhook      string `yaml:"webhook"`
+	WebhookToken string `yaml:"webhook_token"`
+	// OutputDir receives every digest as a markdown file
+	OutputDir string `yaml:"output_dir"`
+}
+
+// TracingConfig configures the OpenTelemetry trace exporter
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// LogConfig configures logging, message bodies and emails are only logged at debug level
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			CardPath:        "/agent_card",
+			APIPath:         "/api",
+			ReadTimeout:     time.Minute,
+			WriteTimeout:    time.Minute,
+			IdleTimeout:     time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+			AbortGrace:      5 * time.Second,
+		},
+		Agent: AgentConfig{
+			Name:        "GitHub Agent",
+			Description: "An A2A-compliant agent that provides GitHub capabilities",
+			Version:     "1.0.0",
+			URL:         "http://localhost:8080/api",
+		},
+		LLM: LLMConfig{
+			Model:               "deepseek-chat",
+			MaxIterations:       10,
+			MaxContextTokens:    56000,
+			MaxToolResultTokens: 4000,
+			CompletionReserve:   4000,
+		},
+		GitHub: GitHubConfig{
+			Webhook: GitHubWebhookConfig{
+				Path:         "/github/webhook",
+				MaxBodyBytes: 5 << 20,
+			},
+		},
+		Tools: ToolsConfig{
+			MaxRepairAttempts: 2,
+		},
+		Input: InputConfig{
+			MaxFileBytes:  100 * 1024,
+			MaxInputBytes: 150 * 1024,
+		},
+		Store: StoreConfig{
+			Driver: "memory",
+			Path:   "tasks.db",
+		},
+		Queue: QueueConfig{
+			Capacity:     10,
+			IdleTTL:      10 * time.Minute,
+			EventLogSize: 1000,
+			EventLogTTL:  10 * time.Minute,
+		},
+		Push: PushConfig{
+			MaxAttempts: 5,
+		},
+		Tracing: TracingConfig{
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "github-a2a",
+			SampleRatio: 1,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+	}
+}
+
+// LoadFile merges a YAML file into the configuration, unknown keys are rejected
+func (c *Config) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// envBinding maps an environment variable onto a configuration field
+type envBinding struct {
+	name  string
+	apply func(c *Config, value string) error
+}
+
+var envBindings = []envBinding{
+	{"SERVER_ADDR", setString(func(c *Config) *string { return &c.Server.Addr })},
+	{"DEBUG_ADDR", setString(func(c *Config) *string { return &c.Server.DebugAddr })},
+	{"SHUTDOWN_TIMEOUT", setDuration(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"AGENT_NAME", setString(func(c *Config) *string { return &c.Agent.Name })},
+	{"AGENT_DESCRIPTION", setString(func(c *Config) *string { return &c.Agent.Description })},
+	{"AGENT_VERSION", setString(func(c *Config) *string { return &c.Agent.Version })},
+	{"AGENT_URL", setString(func(c *Config) *string { return &c.Agent.URL })},
+	{"AGENT_DOCUMENTATION_URL", setString(func(c *Config) *string { return &c.Agent.DocumentationURL })},
+	{"AGENT_ICON_URL", setString(func(c *Config) *string { return &c.Agent.IconURL })},
+	{"AGENT_PROVIDER", setString(func(c *Config) *string { return &c.Agent.Provider })},
+	{"AGENT_PROVIDER_URL", setString(func(c *Config) *string { return &c.Agent.ProviderURL })},
+	{"DEEPSEEK_API_KEY", setString(func(c *Config) *string { return &c.LLM.APIKey })},
+	{"DEEPSEEK_BASE_URL", setString(func(c *Config) *string { return &c.LLM.BaseURL })},
+	{"LLM_MODEL", setString(func(c *Config) *string { return &c.LLM.Model })},
+	{"LLM_MAX_ITERATIONS", setInt(func(c *Config) *int { return &c.LLM.MaxIterations })},
+	{"LLM_MAX_CONTEXT_TOKENS", setInt(func(c *Config) *int { return &c.LLM.MaxContextTokens })},
+	{"LLM_MAX_TOOL_RESULT_TOKENS", setInt(func(c *Config) *int { return &c.LLM.MaxToolResultTokens })},
+	{"LLM_COMPLETION_RESERVE", setInt(func(c *Config) *int { return &c.LLM.CompletionReserve })},
+	{"GITHUB_TOKEN", setString(func(c *Config) *string { return &c.GitHub.Token })},
+	{"GITHUB_API_URL", setString(func(c *Config) *string { return &c.GitHub.APIURL })},
+	{"GITHUB_WEBHOOK_PATH", setString(func(c *Config) *string { return &c.GitHub.Webhook.Path })},
+	{"GITHUB_WEBHOOK_SECRET", setString(func(c *Config) *string { return &c.GitHub.Webhook.Secret })},
+	{"ENABLED_SKILLS", setList(func(c *Config) *[]string { return &c.Tools.EnabledSkills })},
+	{"DISABLED_SKILLS", setList(func(c *Config) *[]string { return &c.Tools.DisabledSkills })},
+	{"ENABLED_TOOLS", setList(func(c *Config) *[]string { return &c.Tools.EnabledTools })},
+	{"DISABLED_TOOLS", setList(func(c *Config) *[]string { return &c.Tools.DisabledTools })},
+	{"INPUT_MAX_FILE_BYTES", setInt(func(c *Config) *int { return &c.Input.MaxFileBytes })},
+	{"INPUT_MAX_BYTES", setInt(func(c *Config) *int { return &c.Input.MaxInputBytes })},
+	{"INPUT_ALLOWED_FILE_HOSTS", setList(func(c *Config) *[]string { return &c.Input.AllowedFileHosts })},
+	{"TASK_STORE", setString(func(c *Config) *string { return &c.Store.Driver })},
+	{"TASK_STORE_PATH", setString(func(c *Config) *string { return &c.Store.Path })},
+	{"TASK_RETENTION", setDuration(func(c *Config) *time.Duration { return &c.Store.Retention })},
+	{"TASK_STORE_COMPACT", setBool(func(c *Config) *bool { return &c.Store.CompactOnOpen })},
+	{"QUEUE_CAPACITY", func(c *Config, value string) error {
+		capacity, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.Queue.Capacity = uint(capacity)
+		return nil
+	}},
+	{"QUEUE_IDLE_TTL", setDuration(func(c *Config) *time.Duration { return &c.Queue.IdleTTL })},
+	{"EVENT_LOG_SIZE", setInt(func(c *Config) *int { return &c.Queue.EventLogSize })},
+	{"EVENT_LOG_TTL", setDuration(func(c *Config) *time.Duration { return &c.Queue.EventLogTTL })},
+	{"PUSH_NOTIFICATIONS", setBool(func(c *Config) *bool { return &c.Push.Enabled })},
+	{"PUSH_SIGNING_SECRET", setString(func(c *Config) *string { return &c.Push.SigningSecret })},
+	{"PUSH_ALLOWED_HOSTS", setList(func(c *Config) *[]string { return &c.Push.AllowedHosts })},
+	{"PUSH_MAX_ATTEMPTS", setInt(func(c *Config) *int { return &c.Push.MaxAttempts })},
+	{"SCHEDULE_STATE_PATH", setString(func(c *Config) *string { return &c.Schedule.StatePath })},
+	{"LOG_LEVEL", setString(func(c *Config) *string { return &c.Log.Level })},
+	{"LOG_FORMAT", setString(func(c *Config) *string { return &c.Log.Format })},
+	{"TRACING_ENABLED", setBool(func(c *Config) *bool { return &c.Tracing.Enabled })},
+	{"TRACING_ENDPOINT", setString(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{"TRACING_INSECURE", setBool(func(c *Config) *bool { return &c.Tracing.Insecure })},
+	{"TRACING_SERVICE_NAME", setString(func(c *Config) *string { return &c.Tracing.ServiceName })},
+	{"TRACING_SAMPLE_RATIO", func(c *Config, value string) error {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		c.Tracing.SampleRatio = ratio
+		return nil
+	}},
+}
+
+// ApplyEnv overrides the configuration with the environment variables that are set
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	for _, binding := range envBindings {
+		value, ok := lookup(binding.name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := binding.apply(c, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", binding.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr is required")
+	check(strings.HasPrefix(c.Server.CardPath, "/"), "server.card_path must start with /")
+	check(strings.HasPrefix(c.Server.APIPath, "/"), "server.api_path must start with /")
+	check(c.Server.CardPath != c.Server.APIPath, "server.card_path and server.api_path must differ")
+	check(c.Server.DebugAddr == "" || c.Server.DebugAddr != c.Server.Addr, "server.debug_addr must differ from server.addr")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.ShutdownTimeout >= 0, "server.shutdown_timeout must not be negative")
+	check(c.Server.AbortGrace >= 0, "server.abort_grace must not be negative")
+
+	check(c.LLM.APIKey != "", "llm.api_key is required, set it in the config file or DEEPSEEK_API_KEY")
+	check(c.LLM.Model != "", "llm.model is required")
+	check(c.LLM.MaxIterations >= 1, "llm.max_iterations must be at least 1")
+	check(c.LLM.CompletionReserve >= 0, "llm.completion_reserve must not be negative")
+	check(c.LLM.MaxContextTokens > c.LLM.CompletionReserve, "llm.max_context_tokens must exceed llm.completion_reserve")
+	check(c.LLM.MaxToolResultTokens >= 100, "llm.max_tool_result_tokens must be at least 100")
+	if c.LLM.BaseURL != "" {
+		u, err := url.Parse(c.LLM.BaseURL)
+		check(err == nil && u.Scheme != "" && u.Host != "", "llm.base_url must be an absolute URL")
+	}
+
+	if c.GitHub.APIURL != "" {
+		u, err := url.Parse(c.GitHub.APIURL)
+		check(err == nil && u.Scheme != "" && u.Host != "", "github.api_url must be an absolute URL")
+	}
+	webhook := c.GitHub.Webhook
+	check(strings.HasPrefix(webhook.Path, "/"), "github.webhook.path must start with /")
+	check(webhook.Path != c.Server.APIPath && webhook.Path != c.Server.CardPath, "github.webhook.path must differ from server.api_path and server.card_path")
+	check(webhook.MaxBodyBytes >= 1, "github.webhook.max_body_bytes must be at least 1")
+	check(len(webhook.Rules) == 0 || webhook.Secret != "", "github.webhook.rules require github.webhook.secret")
+	for i, rule := range webhook.Rules {
+		field := fmt.Sprintf("github.webhook.rules[%d]", i)
+		switch rule.Event {
+		case "push", "pull_request", "issues", "workflow_run":
+		default:
+			check(false, "%s.event must be push, pull_request, issues or workflow_run", field)
+		}
+		if rule.ResultWebhook != "" {
+			u, err := url.Parse(rule.ResultWebhook)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "%s.result_webhook must be an absolute http(s) URL", field)
+			check(c.Push.Enabled, "%s.result_webhook requires push.enabled", field)
+		}
+	}
+
+	check(c.Tools.MaxRepairAttempts >= 0, "tools.max_repair_attempts must not be negative")
+
+	check(c.Input.MaxFileBytes >= 1, "input.max_file_bytes must be at least 1")
+	check(c.Input.MaxInputBytes >= c.Input.MaxFileBytes, "input.max_input_bytes must be at least input.max_file_bytes")
+
+	check(c.Store.Driver == "memory" || c.Store.Driver == "bolt", "store.driver must be memory or bolt")
+	check(c.Store.Driver != "bolt" || c.Store.Path != "", "store.path is required for the bolt driver")
+	check(c.Store.Retention >= 0, "store.retention must not be negative")
+
+	check(c.Queue.Capacity >= 1, "queue.capacity must be at least 1")
+	check(c.Queue.IdleTTL >= 0, "queue.idle_ttl must not be negative")
+	check(c.Queue.EventLogSize >= 0, "queue.event_log_size must not be negative")
+	check(c.Queue.EventLogTTL >= 0, "queue.event_log_ttl must not be negative")
+
+	check(c.Push.MaxAttempts >= 1, "push.max_attempts must be at least 1")
+	check(!c.Push.Enabled || c.Push.SigningSecret != "", "push.signing_secret is required when push notifications are enabled")
+
+	jobNames := map[string]bool{}
+	for i, job := range c.Schedule.Jobs {
+		field := fmt.Sprintf("schedule.jobs[%d]", i)
+		check(job.Name != "", "%s.name is required", field)
+		check(!jobNames[job.Name], "%s.name %q is used twice", field, job.Name)
+		jobNames[job.Name] = true
+		check(job.Cron != "", "%s.cron is required", field)
+		if job.Timezone != "" {
+			_, err := time.LoadLocation(job.Timezone)
+			check(err == nil, "%s.timezone %q is not a known time zone", field, job.Timezone)
+		}
+		check(len(job.Repos) > 0, "%s.repos needs at least one repository", field)
+		for _, repo := range job.Repos {
+			owner, name, ok := strings.Cut(repo, "/")
+			check(ok && owner != "" && name != "" && !strings.Contains(name, "/"), "%s.repos entry %q must be in format owner/repo", field, repo)
+		}
+		check(job.Window >= 0, "%s.window must not be negative", field)
+		check(job.Webhook != "" || job.OutputDir != "", "%s needs a webhook or an output_dir to deliver to", field)
+		if job.Webhook != "" {
+			u, err := url.Parse(job.Webhook)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "%s.webhook must be an absolute http(s) URL", field)
+			check(c.Push.Enabled, "%s.webhook requires push.enabled", field)
+		}
+	}
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level must be debug, info, warn or error")
+	check(c.Log.Format == "text" || c.Log.Format == "json", "log.format must be text or json")
+
+	check(!c.Tracing.Enabled || c.Tracing.Endpoint != "", "tracing.endpoint is required when tracing is enabled")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Redacted returns a copy with every secret replaced, for printing
+func (c Config) Redacted() Config {
+	redact := func(value *string) {
+		if *value != "" {
+			*value = "REDACTED"
+		}
+	}
+	redact(&c.LLM.APIKey)
+	redact(&c.GitHub.Token)
+	redact(&c.GitHub.Webhook.Secret)
+	c.GitHub.Webhook.Rules = append([]WebhookRule(nil), c.GitHub.Webhook.Rules...)
+	for i := range c.GitHub.Webhook.Rules {
+		redact(&c.GitHub.Webhook.Rules[i].ResultWebhookToken)
+	}
+	redact(&c.Push.SigningSecret)
+	// The jobs share their backing array with the original configuration
+	c.Schedule.Jobs = append([]ScheduleJob(nil), c.Schedule.Jobs...)
+	for i := range c.Schedule.Jobs {
+		redact(&c.Schedule.Jobs[i].WebhookToken)
+	}
+	return c
+}
+
+// YAML encodes the configuration, callers should redact it first
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+func setString(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func setInt(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setList(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = SplitList(value)
+		return nil
+	}
+}
+
+// SplitList parses a comma separated list, ignoring blank entries
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+server:
+  addr: ":7000"
+  read_timeout: 5s
+  shutdown_timeout: 10s
+llm:
+  model: file-model
+  max_iterations: 3
+  max_tool_result_tokens: 1000
+  completion_reserve: 1000
+tools:
+  enabled_skills: [repos, issues]
+  disabled_tools: [get_repo]
+store:
+  driver: bolt
+  path: file.db
+`)
+	cfg, options, err := Load("server", []string{"-model", "flag-model", "-enabled-skills", " ci, ,pulls ", "-shutdown-timeout", "1m",
+		"-max-context-tokens", "32000", "-max-tool-result-tokens", "500"}, env(map[string]string{
+		"CONFIG_FILE":            path,
+		"LLM_MODEL":              "env-model",
+		"SHUTDOWN_TIMEOUT":       "20s",
+		"ENABLED_SKILLS":         "repos",
+		"DISABLED_TOOLS":         "list_issues,get_issue",
+		"TASK_STORE_PATH":        "env.db",
+		"LLM_COMPLETION_RESERVE": "2000",
+		// Empty variables are ignored
+		"SERVER_ADDR": "",
+	}), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Path != path || options.PrintConfig {
+		t.Errorf("options %+v", options)
+	}
+
+	want := Default()
+	want.Server.Addr = ":7000"
+	want.Server.ReadTimeout = 5 * time.Second
+	want.Server.ShutdownTimeout = time.Minute
+	want.LLM.Model = "flag-model"
+	want.LLM.MaxIterations = 3
+	want.LLM.MaxContextTokens = 32000
+	want.LLM.MaxToolResultTokens = 500
+	want.LLM.CompletionReserve = 2000
+	want.Tools.EnabledSkills = []string{"ci", "pulls"}
+	want.Tools.DisabledTools = []string{"list_issues", "get_issue"}
+	want.Store.Driver = "bolt"
+	want.Store.Path = "env.db"
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config\n%+v\nwant\n%+v", cfg, want)
+	}
+
+	// -config wins over CONFIG_FILE
+	other := writeFile(t, "llm:\n  model: other-model\n")
+	cfg, options, err = Load("server", []string{"-config", other}, env(map[string]string{"CONFIG_FILE": path}), io.Discard)
+	if err != nil || options.Path != other || cfg.LLM.Model != "other-model" {
+		t.Errorf("config file %s, model %s, %v", options.Path, cfg.LLM.Model, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]struct {
+		file string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		"misspelled key": {file: "llm:\n  modle: x\n", want: "field modle not found"},
+		"bad value":      {file: "server:\n  read_timeout: soon\n", want: "parse config file"},
+		"bad env":        {env: map[string]string{"LLM_MAX_ITERATIONS": "many", "TRACING_ENABLED": "maybe"}, want: "invalid LLM_MAX_ITERATIONS"},
+		"bad flag":       {args: []string{"-read-timeout", "soon"}, want: "invalid value"},
+		"unknown flag":   {args: []string{"-verbose"}, want: "not defined"},
+	}
+	for name, test := range tests {
+		values := test.env
+		if test.file != "" {
+			values = map[string]string{"CONFIG_FILE": writeFile(t, test.file)}
+		}
+		_, _, err := Load("server", test.args, env(values), io.Discard)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: error %v, want %q", name, err, test.want)
+		}
+	}
+
+	// Every invalid variable is reported
+	_, _, err := Load("server", nil, env(map[string]string{"LLM_MAX_ITERATIONS": "many", "TRACING_ENABLED": "maybe"}), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "invalid TRACING_ENABLED") {
+		t.Errorf("error %v, want both variables", err)
+	}
+	if _, _, err := Load("server", []string{"-h"}, env(nil), io.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("help returned %v", err)
+	}
+}
+
+func validConfig() Config {
+	cfg := Default()
+	cfg.LLM.APIKey = "sk-test"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	valid := validConfig()
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("default config rejected: %v", err)
+	}
+
+	tests := []struct {
+		change func(c *Config)
+		want   string
+	}{
+		{func(c *Config) { c.Server.Addr = "" }, "server.addr is required"},
+		{func(c *Config) { c.Server.CardPath = "card" }, "server.card_path must start with /"},
+		{func(c *Config) { c.Server.APIPath = "api" }, "server.api_path must start with /"},
+		{func(c *Config) { c.Server.CardPath = c.Server.APIPath }, "server.card_path and server.api_path must differ"},
+		{func(c *Config) { c.Server.DebugAddr = c.Server.Addr }, "server.debug_addr must differ from server.addr"},
+		{func(c *Config) { c.Server.ReadTimeout = 0 }, "server.read_timeout must be positive"},
+		{func(c *Config) { c.Server.WriteTimeout = 0 }, "server.write_timeout must be positive"},
+		{func(c *Config) { c.Server.IdleTimeout = 0 }, "server.idle_timeout must be positive"},
+		{func(c *Config) { c.Server.ShutdownTimeout = -1 }, "server.shutdown_timeout must not be negative"},
+		{func(c *Config) { c.Server.AbortGrace = -1 }, "server.abort_grace must not be negative"},
+		{func(c *Config) { c.LLM.APIKey = "" }, "llm.api_key is required, set it in the config file or DEEPSEEK_API_KEY"},
+		{func(c *Config) { c.LLM.Model = "" }, "llm.model is required"},
+		{func(c *Config) { c.LLM.MaxIterations = 0 }, "llm.max_iterations must be at least 1"},
+		{func(c *Config) { c.LLM.CompletionReserve = -1 }, "llm.completion_reserve must not be negative"},
+		{func(c *Config) { c.LLM.MaxContextTokens = c.LLM.CompletionReserve }, "llm.max_context_tokens must exceed llm.completion_reserve"},
+		{func(c *Config) { c.LLM.MaxToolResultTokens = 99 }, "llm.max_tool_result_tokens must be at least 100"},
+		{func(c *Config) { c.LLM.BaseURL = "api.deepseek.com" }, "llm.base_url must be an absolute URL"},
+		{func(c *Config) { c.GitHub.APIURL = "/api/v3" }, "github.api_url must be an absolute URL"},
+		{func(c *Config) { c.GitHub.Webhook.Path = "hook" }, "github.webhook.path must start with /"},
+		{func(c *Config) { c.GitHub.Webhook.Path = c.Server.APIPath }, "github.webhook.path must differ from server.api_path and server.card_path"},
+		{func(c *Config) { c.GitHub.Webhook.MaxBodyBytes = 0 }, "github.webhook.max_body_bytes must be at least 1"},
+		{func(c *Config) { c.GitHub.Webhook.Rules = []WebhookRule{{Event: "push"}} }, "github.webhook.rules require github.webhook.secret"},
+		{func(c *Config) {
+			c.GitHub.Webhook.Secret = "secret"
+			c.GitHub.Webhook.Rules = []WebhookRule{{Event: "push"}, {Event: "release"}}
+		}, "github.webhook.rules[1].event must be push, pull_request, issues or workflow_run"},
+		{func(c *Config) {
+			c.GitHub.Webhook.Secret = "secret"
+			c.GitHub.Webhook.Rules = []WebhookRule{{Event: "push", ResultWebhook: "ftp://example.com"}}
+			c.Push = PushConfig{Enabled: true, SigningSecret: "secret", MaxAttempts: 1}
+		}, "github.webhook.rules[0].result_webhook must be an absolute http(s) URL"},
+		{func(c *Config) {
+			c.GitHub.Webhook.Secret = "secret"
+			c.GitHub.Webhook.Rules = []WebhookRule{{Event: "push", ResultWebhook: "https://example.com"}}
+		}, "github.webhook.rules[0].result_webhook requires push.enabled"},
+		{func(c *Config) { c.Tools.MaxRepairAttempts = -1 }, "tools.max_repair_attempts must not be negative"},
+		{func(c *Config) { c.Input.MaxFileBytes = 0 }, "input.max_file_bytes must be at least 1"},
+		{func(c *Config) { c.Input.MaxInputBytes = c.Input.MaxFileBytes - 1 }, "input.max_input_bytes must be at least input.max_file_bytes"},
+		{func(c *Config) { c.Store.Driver = "redis" }, "store.driver must be memory or bolt"},
+		{func(c *Config) { c.Store = StoreConfig{Driver: "bolt"} }, "store.path is required for the bolt driver"},
+		{func(c *Config) { c.Store.Retention = -1 }, "store.retention must not be negative"},
+		{func(c *Config) { c.Queue.Capacity = 0 }, "queue.capacity must be at least 1"},
+		{func(c *Config) { c.Queue.IdleTTL = -1 }, "queue.idle_ttl must not be negative"},
+		{func(c *Config) { c.Queue.EventLogSize = -1 }, "queue.event_log_size must not be negative"},
+		{func(c *Config) { c.Queue.EventLogTTL = -1 }, "queue.event_log_ttl must not be negative"},
+		{func(c *Config) { c.Push.MaxAttempts = 0 }, "push.max_attempts must be at least 1"},
+		{func(c *Config) { c.Push.Enabled = true }, "push.signing_secret is required when push notifications are enabled"},
+		{func(c *Config) {
+			c.Schedule.Jobs = []ScheduleJob{{Cron: "@daily", Repos: []string{"a/b"}, OutputDir: "out"}}
+		}, "schedule.jobs[0].name is required"},
+		{func(c *Config) {
+			job := ScheduleJob{Name: "digest", Cron: "@daily", Repos: []string{"a/b"}, OutputDir: "out"}
+			c.Schedule.Jobs = []ScheduleJob{job, job}
+		}, `schedule.jobs[1].name "digest" is used twice`},
+		{func(c *Config) {
+			c.Schedule.Jobs = []ScheduleJob{{Name: "digest", Repos: []string{"a/b"}, OutputDir: "out"}}
+		}, "schedule.jobs[0].cron is required"},
+		{func(c *Config) {
+			c.Schedule.Jobs = []ScheduleJob{{Name: "digest", Cron: "@daily", Timezone: "Mars/Olympus", Repos: []string{"a/b"}, OutputDir: "out"}}
+		}, `schedule.jobs[0].timezone "Mars/Olympus" is not a known time zone`},
+		{func(c *Config) { c.Schedule.Jobs = []ScheduleJob{{Name: "digest", Cron: "@daily", OutputDir: "out"}} }, "schedule.jobs[0].repos needs at least one repository"},
+		{func(c *Config) {
+			c.Schedule.Jobs = []ScheduleJob{{Name: "digest", Cron: "@daily", Repos: []string{"a/b/c"}, OutputDir: "out"}}
+		}, `schedule.jobs[0].repos entry "a/b/c" must be in format owner/repo`},
+		{func(c *Config) {
+			c.Schedule.Jobs = []ScheduleJob{{Name: "digest", Cron: "@daily", Repos: []string{"a/b"}, Window: -1, OutputDir: "out"}}
+		}, "schedule.jobs[0].window must not be negative"},
+		{func(c *Config) {
+			c.Schedule.Jobs = []ScheduleJob{{Name: "digest", Cron: "@daily", Repos: []string{"a/b"}}}
+		}, "schedule.jobs[0] needs a webhook or an output_dir to deliver to"},
+		{func(c *Config) {
+			c.Push = PushConfig{Enabled: true, SigningSecret: "secret", MaxAttempts: 1}
+			c.Schedule.Jobs = []ScheduleJob{{Name: "digest", Cron: "@daily", Repos: []string{"a/b"}, Webhook: "example.com/hook"}}
+		}, "schedule.jobs[0].webhook must be an absolute http(s) URL"},
+		{func(c *Config) {
+			c.Schedule.Jobs = []ScheduleJob{{Name: "digest", Cron: "@daily", Repos: []string{"a/b"}, Webhook: "https://example.com/hook"}}
+		}, "schedule.jobs[0].webhook requires push.enabled"},
+		{func(c *Config) { c.Log.Level = "verbose" }, "log.level must be debug, info, warn or error"},
+		{func(c *Config) { c.Log.Format = "xml" }, "log.format must be text or json"},
+		{func(c *Config) { c.Tracing = TracingConfig{Enabled: true, SampleRatio: 1} }, "tracing.endpoint is required when tracing is enabled"},
+		{func(c *Config) { c.Tracing.SampleRatio = 1.5 }, "tracing.sample_ratio must be between 0 and 1"},
+	}
+	for _, test := range tests {
+		cfg := validConfig()
+		test.change(&cfg)
+		err := cfg.Validate()
+		if err == nil {
+			t.Errorf("accepted, want %q", test.want)
+			continue
+		}
+		// Every change breaks exactly one rule
+		if want := "invalid configuration: " + test.want; err.Error() != want {
+			t.Errorf("error %q, want %q", err, want)
+		}
+	}
+
+	// All problems are reported at once
+	cfg := validConfig()
+	cfg.Server.Addr, cfg.LLM.Model = "", ""
+	if err := cfg.Validate(); err == nil || err.Error() != "invalid configuration: server.addr is required\nllm.model is required" {
+		t.Errorf("error %v", err)
+	}
+}
+
+func secretConfig() Config {
+	cfg := validConfig()
+	cfg.LLM.APIKey = "sk-llm-secret"
+	cfg.GitHub.Token = "ghp_github_secret"
+	cfg.GitHub.Webhook.Secret = "webhook-secret"
+	cfg.GitHub.Webhook.Rules = []WebhookRule{{Name: "ci", Event: "workflow_run", ResultWebhook: "https://example.com/ci", ResultWebhookToken: "rule-token-secret"}}
+	cfg.Push = PushConfig{Enabled: true, SigningSecret: "push-secret", MaxAttempts: 5}
+	cfg.Schedule.Jobs = []ScheduleJob{{Name: "digest", Cron: "@daily", Repos: []string{"octo/repo"}, Webhook: "https://example.com/digest", WebhookToken: "job-token-secret"}}
+	return cfg
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := secretConfig()
+	redacted := cfg.Redacted()
+
+	for name, value := range map[string]string{
+		"llm.api_key":           redacted.LLM.APIKey,
+		"github.token":          redacted.GitHub.Token,
+		"github.webhook.secret": redacted.GitHub.Webhook.Secret,
+		"github.webhook.rules.result_webhook_token": redacted.GitHub.Webhook.Rules[0].ResultWebhookToken,
+		"push.signing_secret":                       redacted.Push.SigningSecret,
+		"schedule.jobs.webhook_token":               redacted.Schedule.Jobs[0].WebhookToken,
+	} {
+		if value != "REDACTED" {
+			t.Errorf("%s is %q", name, value)
+		}
+	}
+	// The original keeps its secrets and unset secrets stay empty
+	if !reflect.DeepEqual(cfg, secretConfig()) {
+		t.Error("redacting changed the original configuration")
+	}
+	if empty := validConfig(); empty.Redacted().GitHub.Token != "" {
+		t.Error("unset token printed as redacted")
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	cfg, options, err := Load("server", []string{"-print-config", "-model", "flag-model"}, env(map[string]string{
+		"CONFIG_FILE": writeFile(t, `
+github:
+  token: ghp_github_secret
+  webhook:
+    secret: webhook-secret
+push:
+  enabled: true
+  signing_secret: push-secret
+`),
+		"DEEPSEEK_API_KEY": "sk-llm-secret",
+	}), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !options.PrintConfig {
+		t.Fatal("-print-config not set")
+	}
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"ghp_github_secret", "webhook-secret", "push-secret", "sk-llm-secret"} {
+		if bytes.Contains(out, []byte(secret)) {
+			t.Errorf("printed config contains %s:\n%s", secret, out)
+		}
+	}
+	for _, line := range []string{"model: flag-model", "api_key: REDACTED", "token: REDACTED", "signing_secret: REDACTED", "shutdown_timeout: 30s"} {
+		if !bytes.Contains(out, []byte(line)) {
+			t.Errorf("printed config lacks %q:\n%s", line, out)
+		}
+	}
+
+	// The printed configuration is a valid config file
+	path := writeFile(t, string(out))
+	printed := Default()
+	if err := printed.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := printed.YAML(); err != nil || !bytes.Equal(again, out) {
+		t.Errorf("printed config reads back as\n%s\nwant\n%s", again, out)
+	}
+}
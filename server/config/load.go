@@ -0,0 +1,109 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Options are the command line options that are not part of the configuration itself
+type Options struct {
+	// Path of the YAML config file, empty when none is used
+	Path string
+	// PrintConfig prints the effective configuration and exits
+	PrintConfig bool
+}
+
+// Load builds the configuration from the defaults, the config file, the environment and the flags,
+// in increasing order of precedence. The config file is taken from -config or CONFIG_FILE.
+func Load(name string, args []string, lookup func(string) (string, bool), output io.Writer) (Config, Options, error) {
+	config := Default()
+	var options Options
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&options.Path, "config", "", "path of the YAML config file, defaults to CONFIG_FILE")
+	flags.BoolVar(&options.PrintConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+
+	// Flags are collected first and applied last, so they win over the file and the environment
+	var overrides []func(c *Config)
+	stringFlag := func(name string, usage string, field func(c *Config) *string) {
+		flags.Func(name, usage, func(value string) error {
+			overrides = append(overrides, func(c *Config) { *field(c) = value })
+			return nil
+		})
+	}
+	intFlag := func(name string, usage string, field func(c *Config) *int) {
+		flags.Func(name, usage, func(value string) error {
+			var parsed int
+			if _, err := fmt.Sscan(value, &parsed); err != nil {
+				return err
+			}
+			overrides = append(overrides, func(c *Config) { *field(c) = parsed })
+			return nil
+		})
+	}
+	durationFlag := func(name string, usage string, field func(c *Config) *time.Duration) {
+		flags.Func(name, usage, func(value string) error {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			overrides = append(overrides, func(c *Config) { *field(c) = parsed })
+			return nil
+		})
+	}
+	listFlag := func(name string, usage string, field func(c *Config) *[]string) {
+		flags.Func(name, usage, func(value string) error {
+			overrides = append(overrides, func(c *Config) { *field(c) = SplitList(value) })
+			return nil
+		})
+	}
+
+	stringFlag("addr", "listen address", func(c *Config) *string { return &c.Server.Addr })
+	durationFlag("read-timeout", "HTTP read timeout", func(c *Config) *time.Duration { return &c.Server.ReadTimeout })
+	durationFlag("write-timeout", "HTTP write timeout", func(c *Config) *time.Duration { return &c.Server.WriteTimeout })
+	durationFlag("shutdown-timeout", "how long running tasks may finish after a shutdown signal, including the abort grace", func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })
+	stringFlag("agent-url", "public URL of the JSON-RPC endpoint", func(c *Config) *string { return &c.Agent.URL })
+	stringFlag("model", "chat model", func(c *Config) *string { return &c.LLM.Model })
+	stringFlag("llm-base-url", "base URL of a DeepSeek compatible API", func(c *Config) *string { return &c.LLM.BaseURL })
+	intFlag("max-iterations", "model round trips per request", func(c *Config) *int { return &c.LLM.MaxIterations })
+	intFlag("max-context-tokens", "context window of the model in tokens", func(c *Config) *int { return &c.LLM.MaxContextTokens })
+	intFlag("completion-reserve", "tokens kept free in the context window for the answer", func(c *Config) *int { return &c.LLM.CompletionReserve })
+	intFlag("max-tool-result-tokens", "tokens a single tool result may take before it is truncated", func(c *Config) *int { return &c.LLM.MaxToolResultTokens })
+	listFlag("enabled-skills", "comma separated skills to enable", func(c *Config) *[]string { return &c.Tools.EnabledSkills })
+	listFlag("disabled-skills", "comma separated skills to disable", func(c *Config) *[]string { return &c.Tools.DisabledSkills })
+	listFlag("enabled-tools", "comma separated tools to enable", func(c *Config) *[]string { return &c.Tools.EnabledTools })
+	listFlag("disabled-tools", "comma separated tools to disable", func(c *Config) *[]string { return &c.Tools.DisabledTools })
+	stringFlag("task-store", "task store driver, memory or bolt", func(c *Config) *string { return &c.Store.Driver })
+	stringFlag("log-level", "log level, debug logs message bodies", func(c *Config) *string { return &c.Log.Level })
+	stringFlag("log-format", "log format, text or json", func(c *Config) *string { return &c.Log.Format })
+	stringFlag("task-store-path", "path of the bolt task store", func(c *Config) *string { return &c.Store.Path })
+
+	if err := flags.Parse(args); err != nil {
+		return config, options, err
+	}
+
+	if options.Path == "" {
+		options.Path, _ = lookup("CONFIG_FILE")
+	}
+	if options.Path != "" {
+		if err := config.LoadFile(options.Path); err != nil {
+			return config, options, err
+		}
+	}
+	if err := config.ApplyEnv(lookup); err != nil {
+		return config, options, err
+	}
+	for _, override := range overrides {
+		override(&config)
+	}
+	return config, options, nil
+}
+
+// LoadOS loads the configuration from the process arguments and environment
+func LoadOS() (Config, Options, error) {
+	return Load(os.Args[0], os.Args[1:], os.LookupEnv, os.Stderr)
+}
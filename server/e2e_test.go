@@ -0,0 +1,776 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cohesion-org/deepseek-go"
+	"github.com/google/go-github/v62/github"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/config"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+	"github.com/yeeaiclub/github-a2a/server/toolset/fakegithub"
+	"github.com/yeeaiclub/github-a2a/server/toolset/fakellm"
+)
+
+// The suite checks the wire format of a2a-go v0.2.2, which names fields in snake case
+// ("context_id", "task_status"), and the JSON-RPC 2.0 envelope and error codes of the A2A specification
+
+// agent is the server under test, started in process with a fake model and a fake GitHub API
+type agent struct {
+	*httptest.Server
+	app    *App
+	github *fakegithub.Server
+	// debug serves the debug endpoints of the app, which the agent itself does not serve
+	debug *httptest.Server
+}
+
+func startAgent(t *testing.T, llm toolset.ChatClient, configure ...func(cfg *config.Config)) *agent {
+	t.Helper()
+	github := fakegithub.New(githubFixtures())
+	t.Cleanup(github.Close)
+
+	cfg := config.Default()
+	cfg.GitHub.Token = "test-token"
+	cfg.LLM.APIKey = "test-key"
+	for _, apply := range configure {
+		apply(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	app, err := NewApp(cfg,
+		WithAppChatClient(llm),
+		WithAppGitHubOptions(toolset.WithGitHubBaseURL(github.URL)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(app.Handler)
+	debug := httptest.NewServer(app.Debug)
+	t.Cleanup(func() {
+		server.Close()
+		debug.Close()
+		app.Close()
+	})
+	return &agent{Server: server, app: app, github: github, debug: debug}
+}
+
+// enablePush turns on push notifications, which are off by default
+func enablePush(cfg *config.Config) {
+	cfg.Push.Enabled = true
+	cfg.Push.SigningSecret = "push-secret"
+}
+
+func githubFixtures() fakegithub.Fixtures {
+	updated := &github.Timestamp{Time: time.Now().Add(-time.Hour)}
+	return fakegithub.Fixtures{
+		Pulls: map[string][]*github.PullRequest{
+			"octo/demo": {{
+				Number: github.Int(7), Title: github.String("Add docs"), State: github.String("open"),
+				User: &github.User{Login: github.String("mona")}, CreatedAt: updated, UpdatedAt: updated,
+			}},
+		},
+	}
+}
+
+// gatedLLM holds every completion until the gate is opened, so tests can act on running tasks
+type gatedLLM struct {
+	*fakellm.LLM
+	gate chan struct{}
+}
+
+func newGatedLLM(steps ...fakellm.Step) *gatedLLM {
+	return &gatedLLM{LLM: fakellm.New(steps...), gate: make(chan struct{})}
+}
+
+func (g *gatedLLM) CreateChatCompletion(ctx context.Context, request *deepseek.ChatCompletionRequest) (*deepseek.ChatCompletionResponse, error) {
+	select {
+	case <-g.gate:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return g.LLM.CreateChatCompletion(ctx, request)
+}
+
+// rpcResponse is a JSON-RPC response as received
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	fields map[string]json.RawMessage
+}
+
+// request builds a JSON-RPC request body, id is encoded as given
+func request(id any, method string, params any) []byte {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
+
+func userMessage(text string) map[string]any {
+	return map[string]any{"message": map[string]any{
+		"role": "user", "message_id": "message-1", "kind": "message",
+		"parts": []any{map[string]any{"kind": "text", "text": text}},
+	}}
+}
+
+// call posts a request and returns its single response
+func (a *agent) call(t *testing.T, body []byte) rpcResponse {
+	t.Helper()
+	responses := a.post(t, body, "application/json")
+	if len(responses) != 1 {
+		t.Fatalf("%d responses, want 1", len(responses))
+	}
+	return responses[0]
+}
+
+// stream posts a request answered with an event stream and returns all its responses
+func (a *agent) stream(t *testing.T, body []byte) []rpcResponse {
+	t.Helper()
+	return a.post(t, body, "text/event-stream")
+}
+
+func (a *agent) post(t *testing.T, body []byte, contentType string) []rpcResponse {
+	t.Helper()
+	resp, err := http.Post(a.URL+"/api", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("HTTP status %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, contentType) {
+		t.Errorf("Content-Type %q, want %q", got, contentType)
+	}
+
+	var responses []rpcResponse
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 1<<20), 16<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		responses = append(responses, decodeResponse(t, line, body))
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return responses
+}
+
+// decodeResponse checks the JSON-RPC 2.0 envelope of a response to request
+func decodeResponse(t *testing.T, line []byte, request []byte) rpcResponse {
+	t.Helper()
+	var response rpcResponse
+	if err := json.Unmarshal(line, &response); err != nil {
+		t.Fatalf("response %s is not JSON: %v", line, err)
+	}
+	if err := json.Unmarshal(line, &response.fields); err != nil {
+		t.Fatalf("response %s is not an object: %v", line, err)
+	}
+	for name := range response.fields {
+		switch name {
+		case "jsonrpc", "id", "result", "error":
+		default:
+			t.Errorf("response has unexpected member %q: %s", name, line)
+		}
+	}
+	if response.JSONRPC != "2.0" {
+		t.Errorf(`response jsonrpc %q, want "2.0": %s`, response.JSONRPC, line)
+	}
+	_, hasResult := response.fields["result"]
+	if hasResult == (response.Error != nil) {
+		t.Errorf("response must have exactly one of result and error: %s", line)
+	}
+	if _, ok := response.fields["id"]; !ok {
+		t.Errorf("response has no id: %s", line)
+	}
+
+	// Requests with a valid id get it back unchanged, others are answered with a null id
+	var sent struct {
+		Id any `json:"id"`
+	}
+	if json.Unmarshal(request, &sent) != nil {
+		return response
+	}
+	switch sent.Id.(type) {
+	case string, float64:
+	default:
+		sent.Id = nil
+	}
+	if want, _ := json.Marshal(sent.Id); !bytes.Equal(response.Id, want) {
+		t.Errorf("response id %s, want %s", response.Id, want)
+	}
+	return response
+}
+
+// strict decodes raw into value, failing on members the protocol types do not define
+func strict(t *testing.T, raw json.RawMessage, value any) {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		t.Fatalf("%s does not match the protocol schema: %v", raw, err)
+	}
+}
+
+var taskStates = map[types.TaskState]bool{
+	types.SUBMITTED: true, types.WORKING: true, types.InputRequired: true, types.COMPLETED: true,
+	types.CANCELED: true, types.FAILED: true, types.REJECTED: true, types.AuthRequired: true, types.UNKNOWN: true,
+}
+
+// checkTask validates a task result
+func checkTask(t *testing.T, raw json.RawMessage) *types.Task {
+	t.Helper()
+	var task types.Task
+	strict(t, raw, &task)
+	if task.Id == "" || task.ContextId == "" {
+		t.Errorf("task without id or context id: %s", raw)
+	}
+	checkStatus(t, task.Status, raw)
+	for _, artifact := range task.Artifacts {
+		if artifact.ArtifactId == "" || len(artifact.Parts) == 0 {
+			t.Errorf("artifact without id or parts: %s", raw)
+		}
+	}
+	return &task
+}
+
+func checkStatus(t *testing.T, status types.TaskStatus, raw json.RawMessage) {
+	t.Helper()
+	if !taskStates[status.State] {
+		t.Errorf("unknown task state %q: %s", status.State, raw)
+	}
+	if _, err := time.Parse(time.RFC3339, status.TimeStamp); err != nil {
+		t.Errorf("status timestamp %q is not RFC 3339: %s", status.TimeStamp, raw)
+	}
+}
+
+// streamEvent is an event of a stream, exactly one of the fields is set
+type streamEvent struct {
+	task     *types.Task
+	status   *types.TaskStatusUpdateEvent
+	artifact *types.TaskArtifactUpdateEvent
+}
+
+// checkEvent validates a stream event, telling the event types apart by their members
+func checkEvent(t *testing.T, raw json.RawMessage) streamEvent {
+	t.Helper()
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		t.Fatalf("event %s is not an object: %v", raw, err)
+	}
+	switch {
+	case members["artifact"] != nil:
+		var event types.TaskArtifactUpdateEvent
+		strict(t, raw, &event)
+		if event.TaskId == "" || event.ContextId == "" || event.Artifact == nil || len(event.Artifact.Parts) == 0 {
+			t.Errorf("incomplete artifact update: %s", raw)
+		}
+		return streamEvent{artifact: &event}
+	case members["status"] != nil:
+		var event types.TaskStatusUpdateEvent
+		strict(t, raw, &event)
+		if event.TaskId == "" || event.ContextId == "" {
+			t.Errorf("status update without task or context: %s", raw)
+		}
+		checkStatus(t, event.Status, raw)
+		return streamEvent{status: &event}
+	case members["task_status"] != nil:
+		return streamEvent{task: checkTask(t, raw)}
+	}
+	t.Fatalf("unknown event: %s", raw)
+	return streamEvent{}
+}
+
+// checkStream validates the events of a stream of one task, which must end with a single final status
+func checkStream(t *testing.T, responses []rpcResponse) (taskId string, states []types.TaskState, artifacts int) {
+	t.Helper()
+	if len(responses) == 0 {
+		t.Fatal("empty stream")
+	}
+	for i, response := range responses {
+		if response.Error != nil {
+			t.Fatalf("stream error %d: %s", response.Error.Code, response.Error.Message)
+		}
+		event := checkEvent(t, response.Result)
+		last := i == len(responses)-1
+		var id string
+		switch {
+		case event.status != nil:
+			id = event.status.TaskId
+			states = append(states, event.status.Status.State)
+			if event.status.Final != last {
+				t.Errorf("event %d final=%v, only the last event is final", i, event.status.Final)
+			}
+		case event.artifact != nil:
+			id = event.artifact.TaskId
+			artifacts++
+		case event.task != nil:
+			id = event.task.Id
+			states = append(states, event.task.Status.State)
+		}
+		if last && event.status == nil && (event.task == nil || !event.task.Done()) {
+			t.Errorf("stream ends with a non final event: %s", response.Result)
+		}
+		if taskId == "" {
+			taskId = id
+		} else if id != taskId {
+			t.Errorf("event %d belongs to task %s, want %s", i, id, taskId)
+		}
+	}
+	return taskId, states, artifacts
+}
+
+func TestAgentCard(t *testing.T) {
+	agent := startAgent(t, fakellm.New())
+
+	for _, path := range []string{"/.well-known/agent.json", "/agent_card"} {
+		resp, err := http.Get(agent.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var raw json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&raw)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			t.Errorf("%s: Content-Type %q", path, resp.Header.Get("Content-Type"))
+		}
+
+		var card AgentCard
+		strict(t, raw, &card)
+		if card.Name == "" || card.Description == "" || card.URL == "" || card.Version == "" {
+			t.Errorf("%s: card misses required members: %s", path, raw)
+		}
+		if card.Capabilities == nil || !card.Capabilities.Streaming {
+			t.Errorf("%s: card does not announce streaming", path)
+		}
+		if len(card.DefaultInputModes) == 0 || len(card.DefaultOutputModes) == 0 {
+			t.Errorf("%s: card has no default modes", path)
+		}
+		if len(card.Skills) == 0 {
+			t.Fatalf("%s: card has no skills", path)
+		}
+		for _, skill := range card.Skills {
+			if skill.Id == "" || skill.Name == "" || skill.Description == "" {
+				t.Errorf("%s: incomplete skill %+v", path, skill)
+			}
+		}
+	}
+}
+
+func TestMessageSend(t *testing.T) {
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("list_pull_requests", map[string]any{"repoName": "octo/demo"})),
+		fakellm.Reply("There is one open pull request."),
+	)
+	agent := startAgent(t, llm)
+
+	// Numeric ids are echoed as numbers
+	response := agent.call(t, request(42, types.MethodMessageSend, userMessage("Which pull requests are open in octo/demo?")))
+	if response.Error != nil {
+		t.Fatalf("error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	task := checkTask(t, response.Result)
+	if task.Status.State != types.COMPLETED {
+		t.Fatalf("task %s, want completed", task.Status.State)
+	}
+	if len(task.History) == 0 || task.History[0].Role != types.User || task.History[0].TaskID != task.Id {
+		t.Errorf("history does not start with the user message: %s", response.Result)
+	}
+
+	var text, data bool
+	for _, artifact := range task.Artifacts {
+		for _, part := range artifact.Parts {
+			switch typed := part.(type) {
+			case *types.TextPart:
+				text = text || strings.Contains(typed.Text, "one open pull request")
+			case *types.DataPart:
+				data = true
+			}
+		}
+	}
+	if !text || !data {
+		t.Errorf("artifacts lack the answer or the tool result: %s", response.Result)
+	}
+	if got := agent.github.Requests(); len(got) != 1 || !strings.HasPrefix(got[0], "GET /repos/octo/demo/pulls") {
+		t.Errorf("GitHub requests %v", got)
+	}
+
+	// tasks/get returns the stored task
+	got := agent.call(t, request("get-1", types.MethodTasksGet, map[string]any{"id": task.Id}))
+	if got.Error != nil {
+		t.Fatalf("tasks/get error %d: %s", got.Error.Code, got.Error.Message)
+	}
+	stored := checkTask(t, got.Result)
+	if stored.Id != task.Id || stored.Status.State != types.COMPLETED || len(stored.Artifacts) != len(task.Artifacts) {
+		t.Errorf("tasks/get returned %s", got.Result)
+	}
+}
+
+func TestMessageSendModelFailure(t *testing.T) {
+	agent := startAgent(t, fakellm.New(fakellm.Fail(context.DeadlineExceeded)))
+
+	// A failing execution is a failed task, not a JSON-RPC error
+	response := agent.call(t, request("1", types.MethodMessageSend, userMessage("hello")))
+	if response.Error != nil {
+		t.Fatalf("error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	if task := checkTask(t, response.Result); task.Status.State != types.FAILED || task.Status.Message == nil {
+		t.Errorf("task %s, want failed with a reason", response.Result)
+	}
+}
+
+func TestMessageStream(t *testing.T) {
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("list_pull_requests", map[string]any{"repoName": "octo/demo"})),
+		fakellm.Reply("There is one open pull request."),
+	)
+	agent := startAgent(t, llm)
+
+	responses := agent.stream(t, request("stream-1", types.MethodMessageStream, userMessage("Which pull requests are open?")))
+	taskId, states, artifacts := checkStream(t, responses)
+	if len(states) < 3 || states[0] != types.SUBMITTED || states[1] != types.WORKING || states[len(states)-1] != types.COMPLETED {
+		t.Errorf("states %v, want submitted, working, ..., completed", states)
+	}
+	if artifacts != 2 {
+		t.Errorf("%d artifact updates, want the tool result and the answer", artifacts)
+	}
+
+	got := agent.call(t, request("get-1", types.MethodTasksGet, map[string]any{"id": taskId}))
+	if got.Error != nil || checkTask(t, got.Result).Status.State != types.COMPLETED {
+		t.Errorf("tasks/get after the stream: %+v", got)
+	}
+}
+
+// startRunning streams a message to the gated model in the background and returns the task id
+// once it was submitted, with a channel receiving the rest of the stream
+func startRunning(t *testing.T, agent *agent) (string, <-chan []rpcResponse) {
+	t.Helper()
+	body := request("stream-1", types.MethodMessageStream, userMessage("hello"))
+	resp, err := http.Post(agent.URL+"/api", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 1<<20), 16<<20)
+	if !scanner.Scan() {
+		t.Fatalf("stream ended before the first event: %v", scanner.Err())
+	}
+	first := decodeResponse(t, scanner.Bytes(), body)
+	event := checkEvent(t, first.Result)
+	if event.status == nil || event.status.Status.State != types.SUBMITTED {
+		t.Fatalf("first event %s, want the submitted status", first.Result)
+	}
+
+	rest := make(chan []rpcResponse, 1)
+	go func() {
+		responses := []rpcResponse{first}
+		for scanner.Scan() {
+			var response rpcResponse
+			if json.Unmarshal(scanner.Bytes(), &response) == nil {
+				responses = append(responses, response)
+			}
+		}
+		rest <- responses
+	}()
+	return event.status.TaskId, rest
+}
+
+func waitStream(t *testing.T, rest <-chan []rpcResponse) []rpcResponse {
+	t.Helper()
+	select {
+	case responses := <-rest:
+		return responses
+	case <-time.After(10 * time.Second):
+		t.Fatal("stream did not end")
+		return nil
+	}
+}
+
+func TestTasksCancel(t *testing.T) {
+	llm := newGatedLLM(fakellm.Reply("never sent"))
+	agent := startAgent(t, llm)
+	taskId, rest := startRunning(t, agent)
+
+	response := agent.call(t, request(7, types.MethodTasksCancel, map[string]any{"id": taskId}))
+	if response.Error != nil {
+		t.Fatalf("error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	if task := checkTask(t, response.Result); task.Id != taskId || task.Status.State != types.CANCELED {
+		t.Errorf("tasks/cancel returned %s", response.Result)
+	}
+
+	_, states, _ := checkStream(t, waitStream(t, rest))
+	if states[len(states)-1] != types.CANCELED {
+		t.Errorf("stream states %v, want it to end canceled", states)
+	}
+
+	again := agent.call(t, request(8, types.MethodTasksCancel, map[string]any{"id": taskId}))
+	if again.Error == nil || again.Error.Code != -32002 {
+		t.Errorf("canceling a canceled task: %+v, want TaskNotCancelableError", again)
+	}
+}
+
+// drain starts draining the agent in the background with the shutdown timeout and returns the tasks
+// it canceled once it is done
+func drain(t *testing.T, agent *agent, timeout time.Duration) <-chan []string {
+	t.Helper()
+	canceled := make(chan []string, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		canceled <- agent.app.Queues.Drain(ctx)
+	}()
+	for !agent.app.Queues.Draining() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return canceled
+}
+
+func TestShutdownWaitsForRunningTasks(t *testing.T) {
+	llm := newGatedLLM(fakellm.Reply("Done."))
+	agent := startAgent(t, llm)
+	taskId, rest := startRunning(t, agent)
+	canceled := drain(t, agent, 10*time.Second)
+
+	// New messages are rejected while the running task finishes, the server reports an internal error
+	response := agent.call(t, request(1, types.MethodMessageSend, userMessage("hello")))
+	if response.Error == nil || response.Error.Code != -32603 {
+		t.Errorf("message/send while draining: %s %+v, want an internal error", response.Result, response.Error)
+	}
+	streamed := agent.stream(t, request(2, types.MethodMessageStream, userMessage("hello")))
+	if len(streamed) != 1 || streamed[0].Error == nil || streamed[0].Error.Code != -32603 {
+		t.Errorf("message/stream while draining: %+v, want an internal error", streamed)
+	}
+
+	close(llm.gate)
+	select {
+	case ids := <-canceled:
+		if len(ids) != 0 {
+			t.Errorf("canceled %v, want none", ids)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("drain did not end after the task finished")
+	}
+	if _, states, _ := checkStream(t, waitStream(t, rest)); states[len(states)-1] != types.COMPLETED {
+		t.Errorf("stream states %v, want it to end completed", states)
+	}
+	got := agent.call(t, request(3, types.MethodTasksGet, map[string]any{"id": taskId}))
+	if got.Error != nil || checkTask(t, got.Result).Status.State != types.COMPLETED {
+		t.Errorf("tasks/get after draining: %+v", got)
+	}
+}
+
+func TestShutdownCancelsTasksAtDeadline(t *testing.T) {
+	// The gate is never opened, the task only stops when it is canceled
+	llm := newGatedLLM(fakellm.Reply("never sent"))
+	agent := startAgent(t, llm, func(cfg *config.Config) { cfg.Server.AbortGrace = 200 * time.Millisecond })
+	taskId, rest := startRunning(t, agent)
+
+	// The task is canceled the abort grace and a second before the shutdown timeout, which bounds the drain
+	start := time.Now()
+	timeout := 2 * time.Second
+	ids := <-drain(t, agent, timeout)
+	if elapsed := time.Since(start); elapsed > timeout+200*time.Millisecond {
+		t.Errorf("drain took %s, over the shutdown timeout of %s", elapsed, timeout)
+	}
+	if len(ids) != 1 || ids[0] != taskId {
+		t.Errorf("canceled %v, want %s", ids, taskId)
+	}
+
+	if _, states, _ := checkStream(t, waitStream(t, rest)); states[len(states)-1] != types.CANCELED {
+		t.Errorf("stream states %v, want it to end canceled", states)
+	}
+	got := agent.call(t, request(1, types.MethodTasksGet, map[string]any{"id": taskId}))
+	if got.Error != nil {
+		t.Fatalf("tasks/get error %d: %s", got.Error.Code, got.Error.Message)
+	}
+	task := checkTask(t, got.Result)
+	if task.Status.State != types.CANCELED || task.Status.Message == nil {
+		t.Fatalf("task %s, want canceled with a reason", got.Result)
+	}
+	want := "Task canceled: " + ErrShuttingDown.Error()
+	if text, ok := task.Status.Message.Parts[0].(*types.TextPart); !ok || text.Text != want {
+		t.Errorf("status message %s, want %q", got.Result, want)
+	}
+}
+
+func TestTasksResubscribe(t *testing.T) {
+	llm := newGatedLLM(fakellm.Reply("Done."))
+	agent := startAgent(t, llm)
+	taskId, rest := startRunning(t, agent)
+
+	// A second client follows the running task from the first event
+	resubscribed := make(chan []rpcResponse, 1)
+	go func() {
+		resubscribed <- agent.stream(t, request("resubscribe-1", types.MethodTasksResubscribe, map[string]any{
+			"id": taskId, "metadata": map[string]any{"from": 0},
+		}))
+	}()
+	// Give the resubscription time to attach before the task finishes
+	time.Sleep(100 * time.Millisecond)
+	close(llm.gate)
+
+	_, original, _ := checkStream(t, waitStream(t, rest))
+	id, replayed, artifacts := checkStream(t, waitStream(t, resubscribed))
+	if id != taskId {
+		t.Errorf("resubscribed to task %s, want %s", id, taskId)
+	}
+	if len(replayed) != len(original) || replayed[0] != types.SUBMITTED || replayed[len(replayed)-1] != types.COMPLETED {
+		t.Errorf("resubscribed states %v, want %v", replayed, original)
+	}
+	if artifacts != 1 {
+		t.Errorf("%d artifacts on the resubscribed stream, want 1", artifacts)
+	}
+
+	// After the task finished the retained events are replayed from the requested index
+	late := agent.stream(t, request("resubscribe-2", types.MethodTasksResubscribe, map[string]any{
+		"id": taskId, "metadata": map[string]any{"from": 1},
+	}))
+	if _, states, _ := checkStream(t, late); states[0] != types.WORKING || states[len(states)-1] != types.COMPLETED {
+		t.Errorf("late resubscription states %v, want working ... completed", states)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	withPush := startAgent(t, fakellm.New(), enablePush)
+	noPush := startAgent(t, fakellm.New())
+
+	tests := []struct {
+		name  string
+		agent *agent
+		body  string
+		code  int
+	}{
+		{name: "parse error", body: `{"jsonrpc": "2.0", "id": 1, "method": `, code: -32700},
+		{name: "not an object", body: `[1, 2]`, code: -32700},
+		{name: "missing version", body: `{"id": 1, "method": "tasks/get", "params": {"id": "x"}}`, code: -32600},
+		{name: "wrong version", body: `{"jsonrpc": "1.0", "id": 1, "method": "tasks/get", "params": {"id": "x"}}`, code: -32600},
+		{name: "missing method", body: `{"jsonrpc": "2.0", "id": 1}`, code: -32600},
+		{name: "object id", body: `{"jsonrpc": "2.0", "id": {}, "method": "tasks/get", "params": {"id": "x"}}`, code: -32600},
+		{name: "unknown method", body: `{"jsonrpc": "2.0", "id": 1, "method": "tasks/list"}`, code: -32601},
+		{name: "missing params", body: `{"jsonrpc": "2.0", "id": 1, "method": "message/send"}`, code: -32602},
+		{name: "missing message", body: `{"jsonrpc": "2.0", "id": 1, "method": "message/send", "params": {}}`, code: -32602},
+		{name: "message without parts", body: `{"jsonrpc": "2.0", "id": 1, "method": "message/stream", "params": {"message": {"role": "user"}}}`, code: -32602},
+		{name: "agent message", body: `{"jsonrpc": "2.0", "id": 1, "method": "message/send", "params": {"message": {"role": "agent", "parts": [{"kind": "text", "text": "hi"}]}}}`, code: -32602},
+		{name: "missing task id", body: `{"jsonrpc": "2.0", "id": 1, "method": "tasks/get", "params": {}}`, code: -32602},
+		{name: "wrong params type", body: `{"jsonrpc": "2.0", "id": 1, "method": "tasks/get", "params": {"id": 5}}`, code: -32602},
+		{name: "get unknown task", body: `{"jsonrpc": "2.0", "id": "a", "method": "tasks/get", "params": {"id": "missing"}}`, code: -32001},
+		{name: "cancel unknown task", body: `{"jsonrpc": "2.0", "id": "a", "method": "tasks/cancel", "params": {"id": "missing"}}`, code: -32001},
+		{name: "resubscribe unknown task", body: `{"jsonrpc": "2.0", "id": "a", "method": "tasks/resubscribe", "params": {"id": "missing"}}`, code: -32001},
+		{name: "push config of unknown task", body: `{"jsonrpc": "2.0", "id": "a", "method": "tasks/pushNotificationConfig/get", "params": {"id": "missing"}}`, code: -32001},
+		{name: "push disabled", agent: noPush, body: `{"jsonrpc": "2.0", "id": "a", "method": "tasks/pushNotificationConfig/get", "params": {"id": "missing"}}`, code: -32003},
+		{name: "push config with message", agent: noPush, body: `{"jsonrpc": "2.0", "id": "a", "method": "message/send", "params": {"message": {"role": "user", "parts": [{"kind": "text", "text": "hi"}]}, "configuration": {"push_notification_config": {"url": "https://example.com/hook"}}}}`, code: -32003},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.agent
+			if target == nil {
+				target = withPush
+			}
+			response := target.call(t, []byte(tt.body))
+			if response.Error == nil {
+				t.Fatalf("result %s, want error %d", response.Result, tt.code)
+			}
+			if response.Error.Code != tt.code || response.Error.Message == "" {
+				t.Errorf("error %d %q, want code %d", response.Error.Code, response.Error.Message, tt.code)
+			}
+			// Requests whose id could not be read are answered with a null id
+			if tt.code == -32700 && string(response.Id) != "null" {
+				t.Errorf("id %s, want null", response.Id)
+			}
+		})
+	}
+}
+
+func TestDebugEndpointsAreNotPublic(t *testing.T) {
+	agent := startAgent(t, fakellm.New(), enablePush)
+	for _, test := range []struct {
+		url  string
+		want int
+	}{
+		{agent.URL + "/debug/queues", http.StatusNotFound},
+		{agent.debug.URL + "/debug/queues", http.StatusOK},
+		{agent.URL + "/debug/push-deliveries", http.StatusNotFound},
+		{agent.debug.URL + "/debug/push-deliveries", http.StatusOK},
+	} {
+		resp, err := http.Get(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.want {
+			t.Errorf("GET %s: status %d, want %d", test.url, resp.StatusCode, test.want)
+		}
+	}
+}
+
+func TestDebugContextLookup(t *testing.T) {
+	for _, driver := range []string{"memory", "bolt"} {
+		t.Run(driver, func(t *testing.T) {
+			agent := startAgent(t, fakellm.New(fakellm.Reply("First answer."), fakellm.Reply("Second answer.")), func(cfg *config.Config) {
+				cfg.Store.Driver = driver
+				cfg.Store.Path = filepath.Join(t.TempDir(), "tasks.db")
+			})
+			var ids []string
+			for _, text := range []string{"Which pull requests are open?", "And in octo/docs?"} {
+				params := userMessage(text)
+				params["message"].(map[string]any)["context_id"] = "github/octo/demo/pull/7"
+				response := agent.call(t, request(1, types.MethodMessageSend, params))
+				if response.Error != nil {
+					t.Fatalf("error %d: %s", response.Error.Code, response.Error.Message)
+				}
+				ids = append(ids, checkTask(t, response.Result).Id)
+			}
+			sort.Strings(ids)
+
+			resp, err := http.Get(agent.debug.URL + "/debug/contexts/github/octo/demo/pull/7")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var tasks []*types.Task
+			if err := json.NewDecoder(resp.Body).Decode(&tasks); err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != 2 || tasks[0].Id != ids[0] || tasks[1].Id != ids[1] || tasks[1].Status.State != types.COMPLETED {
+				t.Errorf("tasks of the context %+v, want %v", tasks, ids)
+			}
+
+			unknown, err := http.Get(agent.debug.URL + "/debug/contexts/github/octo/demo/pull/8")
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(unknown.Body)
+			unknown.Body.Close()
+			if strings.TrimSpace(string(body)) != "[]" {
+				t.Errorf("tasks of an unknown context %s", body)
+			}
+		})
+	}
+}
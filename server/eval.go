@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/cohesion-org/deepseek-go"
+	"github.com/yeeaiclub/a2a-go/sdk/server/execution"
+	"github.com/yeeaiclub/github-a2a/server/config"
+	"github.com/yeeaiclub/github-a2a/server/eval"
+	"github.com/yeeaiclub/github-a2a/server/logging"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+	"github.com/yeeaiclub/github-a2a/server/toolset/replay"
+	"github.com/yeeaiclub/github-a2a/types"
+)
+
+// evalOptions are the flags of the eval command
+type evalOptions struct {
+	configPath   string
+	dataset      string
+	fixtures     string
+	record       bool
+	run          string
+	jsonPath     string
+	markdownPath string
+	judgeModel   string
+	timeout      time.Duration
+	pricing      eval.Pricing
+}
+
+// runEval runs the eval command: the cases of a JSONL dataset are answered by the agent with the
+// configured model, while the GitHub API is replayed from recorded fixtures. It returns the exit
+// code, 1 when a case failed and 2 when the evaluation could not run.
+func runEval(args []string, lookup func(string) (string, bool), stdout io.Writer, stderr io.Writer) int {
+	var options evalOptions
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&options.configPath, "config", "", "path of the YAML config file, defaults to CONFIG_FILE")
+	flags.StringVar(&options.dataset, "dataset", "server/eval/requests.jsonl", "JSONL dataset of cases")
+	flags.StringVar(&options.fixtures, "fixtures", "server/toolset/testdata/github", "directory of the recorded GitHub API responses")
+	flags.BoolVar(&options.record, "record", false, "record missing GitHub API responses from the API, needs GITHUB_TOKEN")
+	flags.StringVar(&options.run, "run", "", "only run the cases whose id matches this regular expression")
+	flags.StringVar(&options.jsonPath, "json", "", "write the JSON report to this file, - for stdout")
+	flags.StringVar(&options.markdownPath, "markdown", "", "write the markdown report to this file, - for stdout, the default without -json")
+	flags.StringVar(&options.judgeModel, "judge-model", "", "model grading judge assertions, defaults to the agent model")
+	flags.DurationVar(&options.timeout, "timeout", 2*time.Minute, "time limit of a single case")
+	flags.Float64Var(&options.pricing.InputPerMillion, "price-input", 0.27, "USD per million prompt tokens")
+	flags.Float64Var(&options.pricing.OutputPerMillion, "price-output", 1.10, "USD per million completion tokens")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if options.jsonPath == "" && options.markdownPath == "" {
+		options.markdownPath = "-"
+	}
+
+	report, err := evaluate(options, lookup, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "eval: %v\n", err)
+		return 2
+	}
+	if err := writeReport(options.jsonPath, stdout, report.WriteJSON); err != nil {
+		fmt.Fprintf(stderr, "eval: write JSON report: %v\n", err)
+		return 2
+	}
+	if err := writeReport(options.markdownPath, stdout, report.WriteMarkdown); err != nil {
+		fmt.Fprintf(stderr, "eval: write markdown report: %v\n", err)
+		return 2
+	}
+	if report.Failed() {
+		return 1
+	}
+	return 0
+}
+
+// evaluate loads the configuration and the dataset and runs the cases
+func evaluate(options evalOptions, lookup func(string) (string, bool), stderr io.Writer) (*eval.Report, error) {
+	cfg := config.Default()
+	if options.configPath == "" {
+		options.configPath, _ = lookup("CONFIG_FILE")
+	}
+	if options.configPath != "" {
+		if err := cfg.LoadFile(options.configPath); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.ApplyEnv(lookup); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := logging.Setup(logging.Config{Level: cfg.Log.Level, Format: cfg.Log.Format}, stderr); err != nil {
+		return nil, err
+	}
+
+	cases, err := eval.LoadDataset(options.dataset)
+	if err != nil {
+		return nil, err
+	}
+	if options.run != "" {
+		pattern, err := regexp.Compile(options.run)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -run: %w", err)
+		}
+		var selected []eval.Case
+		for _, c := range cases {
+			if pattern.MatchString(c.ID) {
+				selected = append(selected, c)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("no case matches -run %s", options.run)
+		}
+		cases = selected
+	}
+
+	// Recorded responses carry no credentials, replaying them needs no token
+	transport := &replay.Transport{Dir: options.fixtures, Mode: replay.Replay}
+	token := cfg.GitHub.Token
+	if options.record {
+		transport.Mode = replay.Record
+		if token == "" {
+			return nil, fmt.Errorf("recording needs GITHUB_TOKEN")
+		}
+	} else if token == "" {
+		token = "replay-token"
+	}
+	githubOptions := []toolset.GitHubOption{toolset.WithGitHubTransport(transport)}
+	if cfg.GitHub.APIURL != "" {
+		githubOptions = append([]toolset.GitHubOption{toolset.WithGitHubBaseURL(cfg.GitHub.APIURL)}, githubOptions...)
+	}
+	agentConfig, err := GithubAgent(toolset.RegistryConfig{
+		EnabledSkills:  cfg.Tools.EnabledSkills,
+		DisabledSkills: cfg.Tools.DisabledSkills,
+		EnabledTools:   cfg.Tools.EnabledTools,
+		DisabledTools:  cfg.Tools.DisabledTools,
+	}, token, githubOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tool configuration: %w", err)
+	}
+
+	var clientOptions []deepseek.Option
+	if cfg.LLM.BaseURL != "" {
+		clientOptions = append(clientOptions, deepseek.WithBaseURL(cfg.LLM.BaseURL))
+	}
+	client, err := deepseek.NewClientWithOptions(cfg.LLM.APIKey, clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("create DeepSeek client: %w", err)
+	}
+	judgeModel := options.judgeModel
+	if judgeModel == "" {
+		judgeModel = cfg.LLM.Model
+	}
+
+	runner := &eval.Runner{
+		Agent: func(client toolset.ChatClient, tools map[string]types.Function) execution.AgentExecutor {
+			opts := append(executorOptionsFor(cfg), toolset.WithChatClient(client))
+			return toolset.NewExecutor(nil, nil, tools, cfg.LLM.APIKey, agentConfig.SystemPrompt, opts...)
+		},
+		Client:  client,
+		Tools:   agentConfig.Tools,
+		Judge:   &eval.Judge{Client: client, Model: judgeModel},
+		Pricing: options.pricing,
+		Timeout: options.timeout,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return runner.Run(ctx, cases), nil
+}
+
+// writeReport writes a report to a file, to stdout for - and nowhere for an empty path
+func writeReport(path string, stdout io.Writer, write func(w io.Writer) error) error {
+	switch path {
+	case "":
+		return nil
+	case "-":
+		return write(stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,243 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Transcript is what the agent did for a case, json_path assertions are evaluated against its JSON form
+type Transcript struct {
+	// State is the final task state
+	State  string `json:"state"`
+	Answer string `json:"answer"`
+	// AnswerJSON is the answer decoded, when it is JSON
+	AnswerJSON any        `json:"answer_json,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls"`
+	Artifacts  []Artifact `json:"artifacts"`
+}
+
+// ToolCall is a tool call made by the model
+type ToolCall struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+	// Error is the message of a tool that answered with an error
+	Error string `json:"error,omitempty"`
+}
+
+// Artifact is a data artifact emitted for a tool result
+type Artifact struct {
+	Tool   string         `json:"tool"`
+	Schema string         `json:"schema,omitempty"`
+	Data   map[string]any `json:"data"`
+}
+
+// AssertionResult is the outcome of one assertion
+type AssertionResult struct {
+	Type   string `json:"type"`
+	Passed bool   `json:"passed"`
+	// Detail explains a failure, or gives the reason of the judge
+	Detail string `json:"detail,omitempty"`
+}
+
+// check evaluates an assertion, the judge is only needed by judge assertions
+func (a Assertion) check(ctx context.Context, c Case, transcript Transcript, judge *Judge) AssertionResult {
+	result := AssertionResult{Type: a.Type}
+	switch a.Type {
+	case AssertContains:
+		answer, value := transcript.Answer, a.Value
+		if a.IgnoreCase {
+			answer, value = strings.ToLower(answer), strings.ToLower(value)
+		}
+		result.Passed = strings.Contains(answer, value)
+		if !result.Passed {
+			result.Detail = fmt.Sprintf("answer does not contain %q", a.Value)
+		}
+	case AssertRegex:
+		pattern, err := a.pattern()
+		if err != nil {
+			result.Detail = err.Error()
+			break
+		}
+		result.Passed = pattern.MatchString(transcript.Answer)
+		if !result.Passed {
+			result.Detail = fmt.Sprintf("answer does not match %s", pattern)
+		}
+	case AssertJSONPath:
+		result.Passed, result.Detail = a.checkPath(transcript)
+	case AssertJudge:
+		if judge == nil {
+			result.Detail = "no judge model is configured"
+			break
+		}
+		verdict, err := judge.Grade(ctx, c.Prompt, transcript.Answer, a.Rubric)
+		if err != nil {
+			result.Detail = fmt.Sprintf("judge failed: %v", err)
+			break
+		}
+		result.Passed, result.Detail = verdict.Passed, verdict.Reason
+	default:
+		result.Detail = fmt.Sprintf("unknown assertion type %q", a.Type)
+	}
+	return result
+}
+
+func (a Assertion) checkPath(transcript Transcript) (bool, string) {
+	segments, err := parsePath(a.Path)
+	if err != nil {
+		return false, err.Error()
+	}
+	encoded, err := json.Marshal(transcript)
+	if err != nil {
+		return false, fmt.Sprintf("encode transcript: %v", err)
+	}
+	var document any
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return false, fmt.Sprintf("decode transcript: %v", err)
+	}
+
+	value, ok := lookup(document, segments)
+	if !ok {
+		return false, fmt.Sprintf("%s does not exist", a.Path)
+	}
+	if a.Equals == nil {
+		return true, ""
+	}
+	if !reflect.DeepEqual(normalize(value), normalize(a.Equals)) {
+		got, _ := json.Marshal(value)
+		want, _ := json.Marshal(a.Equals)
+		return false, fmt.Sprintf("%s is %s, want %s", a.Path, got, want)
+	}
+	return true, ""
+}
+
+// pathSegment is a key, or an index when key is empty
+type pathSegment struct {
+	key   string
+	index int
+}
+
+// parsePath parses the supported JSON path subset: $ followed by .key, ["key"] and [index] steps,
+// negative indexes count from the end
+func parsePath(path string) ([]pathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("json path %q must start with $", path)
+	}
+	var segments []pathSegment
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("json path %q has an empty key", path)
+			}
+			segments = append(segments, pathSegment{key: key})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("json path %q has an unclosed [", path)
+			}
+			inner := rest[1:end]
+			if key, err := strconv.Unquote(inner); err == nil {
+				segments = append(segments, pathSegment{key: key})
+			} else if index, err := strconv.Atoi(inner); err == nil {
+				segments = append(segments, pathSegment{index: index})
+			} else {
+				return nil, fmt.Errorf("json path %q: %s is neither an index nor a quoted key", path, inner)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("json path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return segments, nil
+}
+
+// lookup follows the path through decoded JSON
+func lookup(value any, segments []pathSegment) (any, bool) {
+	for _, segment := range segments {
+		if segment.key != "" {
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if value, ok = object[segment.key]; !ok {
+				return nil, false
+			}
+			continue
+		}
+		array, ok := value.([]any)
+		if !ok {
+			return nil, false
+		}
+		index := segment.index
+		if index < 0 {
+			index += len(array)
+		}
+		if index < 0 || index >= len(array) {
+			return nil, false
+		}
+		value = array[index]
+	}
+	return value, true
+}
+
+// normalize converts a value to its decoded JSON form, so integers compare equal to JSON numbers
+func normalize(value any) any {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
+
+// matchTools pairs every expected tool with a distinct call of that tool whose arguments contain
+// the expected ones, returning the expected tools left without a call
+func matchTools(expected []ExpectedTool, calls []ToolCall) (matched int, missing []string) {
+	used := make([]bool, len(calls))
+	for _, want := range expected {
+		found := false
+		for i, call := range calls {
+			if used[i] || call.Name != want.Name || !containsArguments(call.Arguments, want.Arguments) {
+				continue
+			}
+			used[i] = true
+			found = true
+			break
+		}
+		if found {
+			matched++
+			continue
+		}
+		description := want.Name
+		if len(want.Arguments) > 0 {
+			arguments, _ := json.Marshal(want.Arguments)
+			description += string(arguments)
+		}
+		missing = append(missing, description)
+	}
+	return matched, missing
+}
+
+func containsArguments(arguments map[string]any, want map[string]any) bool {
+	for key, value := range want {
+		got, ok := arguments[key]
+		if !ok || !reflect.DeepEqual(normalize(got), normalize(value)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+// Package eval runs a dataset of prompts against the agent offline and scores the answers.
+// Every case names the tools the model is expected to call and assertions on the answer,
+// the runner records the tool calls, latency and token usage of each case and the report
+// summarizes them as JSON and markdown.
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// Assertion types
+const (
+	// AssertContains passes when the answer contains Value
+	AssertContains = "contains"
+	// AssertRegex passes when the answer matches the regular expression Value
+	AssertRegex = "regex"
+	// AssertJSONPath passes when Path exists in the transcript, and equals Equals when it is set
+	AssertJSONPath = "json_path"
+	// AssertJudge asks the judge model whether the answer satisfies Rubric
+	AssertJudge = "judge"
+)
+
+// Case is a prompt of the dataset with its expectations
+type Case struct {
+	ID     string `json:"id"`
+	Prompt string `json:"prompt"`
+	// ExpectedTools are the tool calls the answer needs. Tool accuracy is not scored when it is
+	// omitted, an empty list expects no tool calls.
+	ExpectedTools []ExpectedTool `json:"expected_tools"`
+	Assertions    []Assertion    `json:"assertions,omitempty"`
+}
+
+// ExpectedTool is a tool call the model should make
+type ExpectedTool struct {
+	Name string `json:"name"`
+	// Arguments must be contained in the arguments of the call, other arguments are ignored
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// Assertion checks the answer of a case
+type Assertion struct {
+	Type string `json:"type"`
+	// Value is the text of contains and the pattern of regex
+	Value      string `json:"value,omitempty"`
+	IgnoreCase bool   `json:"ignore_case,omitempty"`
+	// Path is the JSON path of json_path, like $.artifacts[0].data.data[1].title
+	Path string `json:"path,omitempty"`
+	// Equals is the value expected at Path, any value passes when it is omitted
+	Equals any `json:"equals,omitempty"`
+	// Rubric tells the judge what a passing answer looks like
+	Rubric string `json:"rubric,omitempty"`
+}
+
+// UnmarshalJSON accepts request_id and body as names of the id and the prompt,
+// so backlogs written as requests.jsonl can be run as they are
+func (c *Case) UnmarshalJSON(data []byte) error {
+	type plain Case
+	var decoded struct {
+		plain
+		RequestID string `json:"request_id"`
+		Body      string `json:"body"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*c = Case(decoded.plain)
+	if c.ID == "" {
+		c.ID = decoded.RequestID
+	}
+	if c.Prompt == "" {
+		c.Prompt = decoded.Body
+	}
+	return nil
+}
+
+// LoadDataset reads a JSONL dataset from a file
+func LoadDataset(path string) ([]Case, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	cases, err := ReadDataset(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cases, nil
+}
+
+// ReadDataset reads one case per line, blank lines are skipped
+func ReadDataset(r io.Reader) ([]Case, error) {
+	var cases []Case
+	ids := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4<<20)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if ids[c.ID] {
+			return nil, fmt.Errorf("line %d: duplicate case id %q", line, c.ID)
+		}
+		ids[c.ID] = true
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("dataset has no cases")
+	}
+	return cases, nil
+}
+
+func (c Case) validate() error {
+	if c.ID == "" {
+		return fmt.Errorf("case id is required")
+	}
+	if c.Prompt == "" {
+		return fmt.Errorf("case %s: prompt is required", c.ID)
+	}
+	for _, tool := range c.ExpectedTools {
+		if tool.Name == "" {
+			return fmt.Errorf("case %s: expected tool name is required", c.ID)
+		}
+	}
+	for i, assertion := range c.Assertions {
+		if err := assertion.validate(); err != nil {
+			return fmt.Errorf("case %s: assertion %d: %w", c.ID, i+1, err)
+		}
+	}
+	return nil
+}
+
+func (a Assertion) validate() error {
+	switch a.Type {
+	case AssertContains:
+		if a.Value == "" {
+			return fmt.Errorf("contains needs a value")
+		}
+	case AssertRegex:
+		if _, err := a.pattern(); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	case AssertJSONPath:
+		if _, err := parsePath(a.Path); err != nil {
+			return err
+		}
+	case AssertJudge:
+		if a.Rubric == "" {
+			return fmt.Errorf("judge needs a rubric")
+		}
+	default:
+		return fmt.Errorf("unknown assertion type %q, use contains, regex, json_path or judge", a.Type)
+	}
+	return nil
+}
+
+// pattern compiles the regular expression of a regex assertion
+func (a Assertion) pattern() (*regexp.Regexp, error) {
+	if a.Value == "" {
+		return nil, fmt.Errorf("regex needs a value")
+	}
+	if a.IgnoreCase {
+		return regexp.Compile("(?i)" + a.Value)
+	}
+	return regexp.Compile(a.Value)
+}
@@ -0,0 +1,279 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yeeaiclub/a2a-go/sdk/server/execution"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+	"github.com/yeeaiclub/github-a2a/server/toolset/fakellm"
+	"github.com/yeeaiclub/github-a2a/server/toolset/replay"
+	itypes "github.com/yeeaiclub/github-a2a/types"
+)
+
+// newRunner runs cases with the scripted model, both as the agent and as the judge, and
+// the GitHub tools answering from the fixtures recorded for the toolset tests
+func newRunner(llm *fakellm.LLM) *Runner {
+	registry := toolset.NewRegistry(toolset.RegistryConfig{})
+	transport := &replay.Transport{Dir: "../toolset/testdata/github", Mode: replay.Replay}
+	toolset.NewGitHubToolset("replay-token", toolset.WithGitHubTransport(transport)).Register(registry)
+	return &Runner{
+		Agent: func(client toolset.ChatClient, tools map[string]itypes.Function) execution.AgentExecutor {
+			return toolset.NewExecutor(nil, nil, tools, "", "You are a test agent.", toolset.WithChatClient(client))
+		},
+		Client:  llm,
+		Tools:   registry.Tools(),
+		Judge:   &Judge{Client: llm, Model: "judge"},
+		Pricing: Pricing{InputPerMillion: 1, OutputPerMillion: 2},
+	}
+}
+
+func caseResult(t *testing.T, report *Report, id string) CaseResult {
+	t.Helper()
+	for _, c := range report.Cases {
+		if c.ID == id {
+			return c
+		}
+	}
+	t.Fatalf("no result for case %s", id)
+	return CaseResult{}
+}
+
+func TestRunShippedDataset(t *testing.T) {
+	cases, err := LoadDataset("requests.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("list_issues", map[string]any{"repoName": "octo/demo", "limit": 5})),
+		fakellm.Reply("The most recently updated open issues are Issue 149, Issue 148, Issue 147, Issue 146 and Issue 145."),
+
+		fakellm.CallTools(fakellm.ToolCall("list_pull_requests", map[string]any{"repoName": "octo/demo", "state": "all"})),
+		fakellm.Reply("Only #7 \"Add docs\" is open, #6 was merged."),
+		fakellm.Reply("PASS\nThe answer names #7 as the only open pull request."),
+
+		fakellm.CallTools(fakellm.ToolCall("list_workflow_runs", map[string]any{"repoName": "octo/demo", "limit": 5})),
+		fakellm.Reply("The latest CI run on main failed."),
+		fakellm.Reply("FAIL: the run in progress on docs is not mentioned."),
+
+		fakellm.Reply("I answer questions about GitHub repositories, issues, pull requests and CI."),
+	)
+
+	report := newRunner(llm).Run(context.Background(), cases)
+	if llm.Remaining() != 0 {
+		t.Fatalf("%d scripted steps left", llm.Remaining())
+	}
+
+	summary := report.Summary
+	if summary.Cases != 4 || summary.Passed != 3 || summary.Failed != 1 || !report.Failed() {
+		t.Fatalf("summary %+v", summary)
+	}
+	if summary.ToolPrecision != 1 || summary.ToolRecall != 1 {
+		t.Errorf("tool precision %v, recall %v", summary.ToolPrecision, summary.ToolRecall)
+	}
+	if summary.PromptTokens == 0 || summary.CompletionTokens == 0 || summary.JudgeTokens == 0 {
+		t.Errorf("token usage not counted: %+v", summary)
+	}
+
+	issues := caseResult(t, report, "open-issues")
+	if !issues.Passed || issues.Transcript.State != "completed" {
+		t.Errorf("open-issues failed: %+v", issues)
+	}
+	if len(issues.Transcript.ToolCalls) != 1 || issues.Transcript.ToolCalls[0].Error != "" {
+		t.Errorf("tool calls %+v", issues.Transcript.ToolCalls)
+	}
+	if len(issues.Transcript.Artifacts) != 1 || issues.Transcript.Artifacts[0].Tool != "list_issues" ||
+		issues.Transcript.Artifacts[0].Schema != itypes.SchemaIssues {
+		t.Errorf("artifacts %+v", issues.Transcript.Artifacts)
+	}
+	if issues.Usage.Completions != 2 || issues.Usage.JudgeTokens != 0 {
+		t.Errorf("usage %+v", issues.Usage)
+	}
+	if want := report.Pricing.Cost(issues.Usage.PromptTokens, issues.Usage.CompletionTokens); issues.Usage.CostUSD != want {
+		t.Errorf("cost %v, want %v", issues.Usage.CostUSD, want)
+	}
+
+	pulls := caseResult(t, report, "open-pull-request")
+	if !pulls.Passed || pulls.Usage.JudgeTokens == 0 {
+		t.Errorf("open-pull-request: %+v", pulls)
+	}
+	if judged := pulls.Assertions[1]; !judged.Passed || !strings.Contains(judged.Detail, "only open pull request") {
+		t.Errorf("judge assertion %+v", judged)
+	}
+
+	runs := caseResult(t, report, "failing-workflow")
+	if runs.Passed {
+		t.Fatal("failing-workflow passed although the judge failed it")
+	}
+	if !runs.Assertions[0].Passed || !runs.Assertions[1].Passed {
+		t.Errorf("assertions %+v", runs.Assertions)
+	}
+	if judged := runs.Assertions[2]; judged.Passed || judged.Detail != "the run in progress on docs is not mentioned." {
+		t.Errorf("judge assertion %+v", judged)
+	}
+
+	capabilities := caseResult(t, report, "capabilities")
+	if !capabilities.Passed || capabilities.Tools == nil || capabilities.Tools.Expected != 0 || capabilities.Tools.Called != 0 {
+		t.Errorf("capabilities: %+v", capabilities)
+	}
+
+	var markdown bytes.Buffer
+	if err := report.WriteMarkdown(&markdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"3 of 4 cases passed", "| failing-workflow | **fail** |", "## Failures", "- judge: the run in progress on docs is not mentioned."} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("markdown report lacks %q:\n%s", want, markdown.String())
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := report.WriteJSON(&encoded); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(encoded.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Summary != report.Summary || len(decoded.Cases) != 4 {
+		t.Errorf("JSON report decodes to summary %+v with %d cases", decoded.Summary, len(decoded.Cases))
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	cases := []Case{
+		{
+			ID:            "wrong-tool",
+			Prompt:        "List the open issues of octo/demo",
+			ExpectedTools: []ExpectedTool{{Name: "list_issues", Arguments: map[string]any{"repoName": "octo/demo"}}},
+		},
+		{ID: "model-down", Prompt: "Hello", Assertions: []Assertion{{Type: AssertContains, Value: "Hello"}}},
+		{
+			ID:            "not-recorded",
+			Prompt:        "List the open issues of octo/other",
+			ExpectedTools: []ExpectedTool{{Name: "list_issues"}},
+		},
+		{ID: "no-judge", Prompt: "Hi", Assertions: []Assertion{{Type: AssertJudge, Rubric: "Greets back."}}},
+	}
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("list_pull_requests", map[string]any{"repoName": "octo/demo", "state": "all"})),
+		fakellm.Reply("Here are the pull requests."),
+		fakellm.Fail(errors.New("model unavailable")),
+		fakellm.CallTools(fakellm.ToolCall("list_issues", map[string]any{"repoName": "octo/other"})),
+		fakellm.Reply("I could not list the issues."),
+		fakellm.Reply("Hi!"),
+	)
+	runner := newRunner(llm)
+	report := runner.Run(context.Background(), cases[:3])
+	runner.Judge = nil
+	report.Cases = append(report.Cases, runner.Run(context.Background(), cases[3:]).Cases...)
+	report.summarize()
+
+	if report.Summary.Passed != 1 || report.Summary.ToolPrecision != 0.5 || report.Summary.ToolRecall != 0.5 {
+		t.Fatalf("summary %+v", report.Summary)
+	}
+
+	wrongTool := caseResult(t, report, "wrong-tool")
+	if wrongTool.Passed || wrongTool.Tools.Precision != 0 || wrongTool.Tools.Recall != 0 ||
+		len(wrongTool.Tools.Missing) != 1 || wrongTool.Tools.Missing[0] != `list_issues{"repoName":"octo/demo"}` {
+		t.Errorf("wrong-tool: %+v", wrongTool.Tools)
+	}
+
+	modelDown := caseResult(t, report, "model-down")
+	if modelDown.Passed || modelDown.Transcript.State != "failed" || modelDown.Error != "model unavailable" {
+		t.Errorf("model-down: %+v", modelDown)
+	}
+
+	// The call counts as made, the answer to a request missing from the fixtures is an error
+	notRecorded := caseResult(t, report, "not-recorded")
+	if !notRecorded.Passed || len(notRecorded.Transcript.ToolCalls) != 1 || notRecorded.Transcript.ToolCalls[0].Error == "" {
+		t.Errorf("not-recorded: %+v", notRecorded)
+	}
+
+	noJudge := caseResult(t, report, "no-judge")
+	if noJudge.Passed || noJudge.Assertions[0].Detail != "no judge model is configured" {
+		t.Errorf("no-judge: %+v", noJudge.Assertions)
+	}
+}
+
+func TestReadDataset(t *testing.T) {
+	cases, err := ReadDataset(strings.NewReader(`
+{"id": "a", "prompt": "first", "expected_tools": []}
+
+{"request_id": "b", "title": "ignored", "body": "second"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) != 2 || cases[0].ExpectedTools == nil || cases[1].ID != "b" || cases[1].Prompt != "second" || cases[1].ExpectedTools != nil {
+		t.Fatalf("cases %+v", cases)
+	}
+
+	for name, dataset := range map[string]string{
+		"no cases":       "\n",
+		"invalid JSON":   `{"id": "a"`,
+		"no prompt":      `{"id": "a"}`,
+		"duplicate id":   `{"id": "a", "prompt": "p"}` + "\n" + `{"id": "a", "prompt": "p"}`,
+		"unknown type":   `{"id": "a", "prompt": "p", "assertions": [{"type": "equals"}]}`,
+		"invalid regex":  `{"id": "a", "prompt": "p", "assertions": [{"type": "regex", "value": "("}]}`,
+		"invalid path":   `{"id": "a", "prompt": "p", "assertions": [{"type": "json_path", "path": "answer"}]}`,
+		"no rubric":      `{"id": "a", "prompt": "p", "assertions": [{"type": "judge"}]}`,
+		"no tool name":   `{"id": "a", "prompt": "p", "expected_tools": [{"arguments": {}}]}`,
+		"empty contains": `{"id": "a", "prompt": "p", "assertions": [{"type": "contains"}]}`,
+	} {
+		if _, err := ReadDataset(strings.NewReader(dataset)); err == nil {
+			t.Errorf("%s: dataset accepted", name)
+		}
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	transcript := Transcript{
+		State:      "completed",
+		Answer:     `{"count": 2}`,
+		AnswerJSON: map[string]any{"count": 2},
+		ToolCalls:  []ToolCall{{Name: "list_issues", Arguments: map[string]any{"repoName": "octo/demo"}}},
+		Artifacts:  []Artifact{{Tool: "list_issues", Data: map[string]any{"data": []any{map[string]any{"title": "first"}, map[string]any{"title": "last"}}}}},
+	}
+	for _, test := range []struct {
+		path   string
+		equals any
+		passed bool
+	}{
+		{path: "$.state", equals: "completed", passed: true},
+		{path: "$.answer_json.count", equals: 2, passed: true},
+		{path: "$.tool_calls[0].arguments.repoName", equals: "octo/demo", passed: true},
+		{path: `$.artifacts[0]["data"].data[-1].title`, equals: "last", passed: true},
+		{path: "$.artifacts[0].data.data[1]", passed: true},
+		{path: "$.artifacts[0].data.data[2]", passed: false},
+		{path: "$.artifacts[0].data.data[0].title", equals: "last", passed: false},
+		{path: "$.answer.count", passed: false},
+		{path: "$.missing", passed: false},
+	} {
+		assertion := Assertion{Type: AssertJSONPath, Path: test.path, Equals: test.equals}
+		result := assertion.check(context.Background(), Case{}, transcript, nil)
+		if result.Passed != test.passed {
+			t.Errorf("%s equals %v: passed %v (%s), want %v", test.path, test.equals, result.Passed, result.Detail, test.passed)
+		}
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	for content, want := range map[string]Verdict{
+		"PASS\nNames the open pull request.": {Passed: true, Reason: "Names the open pull request."},
+		"**FAIL**: misses the docs branch":   {Passed: false, Reason: "misses the docs branch"},
+		"pass":                               {Passed: true},
+	} {
+		got, err := parseVerdict(content)
+		if err != nil || got != want {
+			t.Errorf("parseVerdict(%q) = %+v, %v, want %+v", content, got, err, want)
+		}
+	}
+	if _, err := parseVerdict("The answer looks good"); err == nil {
+		t.Error("verdict without PASS or FAIL accepted")
+	}
+}
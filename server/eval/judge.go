@@ -0,0 +1,63 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cohesion-org/deepseek-go"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+)
+
+// judgePrompt instructs the judge model, the verdict is read from the first word of its answer
+const judgePrompt = `You grade the answers of an assistant that answers questions about GitHub repositories.
+You are given the question, the answer and a rubric. Decide whether the answer satisfies the rubric,
+judging only what the rubric asks for. Reply with PASS or FAIL on the first line and a one sentence
+reason on the second line.`
+
+// Judge grades answers against a rubric with a chat model
+type Judge struct {
+	Client toolset.ChatClient
+	Model  string
+}
+
+// Verdict is the grade of the judge
+type Verdict struct {
+	Passed bool
+	Reason string
+}
+
+// Grade asks the judge whether the answer to the prompt satisfies the rubric
+func (j *Judge) Grade(ctx context.Context, prompt string, answer string, rubric string) (Verdict, error) {
+	response, err := j.Client.CreateChatCompletion(ctx, &deepseek.ChatCompletionRequest{
+		Model: j.Model,
+		Messages: []deepseek.ChatCompletionMessage{
+			{Role: deepseek.ChatMessageRoleSystem, Content: judgePrompt},
+			{Role: deepseek.ChatMessageRoleUser, Content: fmt.Sprintf("Question:\n%s\n\nAnswer:\n%s\n\nRubric:\n%s", prompt, answer, rubric)},
+		},
+	})
+	if err != nil {
+		return Verdict{}, err
+	}
+	if len(response.Choices) == 0 {
+		return Verdict{}, fmt.Errorf("judge returned no choices")
+	}
+	return parseVerdict(response.Choices[0].Message.Content)
+}
+
+// parseVerdict reads PASS or FAIL from the first word, the rest is the reason
+func parseVerdict(content string) (Verdict, error) {
+	content = strings.TrimSpace(content)
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return Verdict{}, fmt.Errorf("judge returned an empty verdict")
+	}
+	reason := strings.TrimSpace(strings.TrimLeft(content[len(fields[0]):], " \t\r\n:.-"))
+	switch strings.ToUpper(strings.Trim(fields[0], "*:.,")) {
+	case "PASS":
+		return Verdict{Passed: true, Reason: reason}, nil
+	case "FAIL":
+		return Verdict{Passed: false, Reason: reason}, nil
+	}
+	return Verdict{}, fmt.Errorf("judge verdict %q does not start with PASS or FAIL", content)
+}
@@ -0,0 +1,227 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// Report is the result of a run
+type Report struct {
+	StartedAt time.Time    `json:"started_at"`
+	Pricing   Pricing      `json:"pricing"`
+	Summary   Summary      `json:"summary"`
+	Cases     []CaseResult `json:"cases"`
+}
+
+// Summary aggregates the cases of a report
+type Summary struct {
+	Cases    int     `json:"cases"`
+	Passed   int     `json:"passed"`
+	Failed   int     `json:"failed"`
+	PassRate float64 `json:"pass_rate"`
+	// ToolPrecision and ToolRecall are summed over the cases that score tool calls
+	ToolPrecision    float64 `json:"tool_precision"`
+	ToolRecall       float64 `json:"tool_recall"`
+	MeanLatencyMs    int64   `json:"mean_latency_ms"`
+	P95LatencyMs     int64   `json:"p95_latency_ms"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	JudgeTokens      int     `json:"judge_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// CaseResult is the outcome of a case
+type CaseResult struct {
+	ID     string `json:"id"`
+	Prompt string `json:"prompt"`
+	// Passed is set when the task completed, every expected tool was called and every assertion passed
+	Passed bool `json:"passed"`
+	// Error is the error the agent returned
+	Error      string            `json:"error,omitempty"`
+	LatencyMs  int64             `json:"latency_ms"`
+	Tools      *ToolScore        `json:"tools,omitempty"`
+	Assertions []AssertionResult `json:"assertions"`
+	Usage      Usage             `json:"usage"`
+	Transcript Transcript        `json:"transcript"`
+}
+
+// ToolScore compares the tool calls of a case with the expected ones
+type ToolScore struct {
+	Expected  int      `json:"expected"`
+	Called    int      `json:"called"`
+	Matched   int      `json:"matched"`
+	Precision float64  `json:"precision"`
+	Recall    float64  `json:"recall"`
+	Missing   []string `json:"missing,omitempty"`
+}
+
+// Usage is the token usage of a case, the cost includes the tokens of the judge
+type Usage struct {
+	Completions      int     `json:"completions"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	JudgeTokens      int     `json:"judge_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+func scoreTools(expected []ExpectedTool, calls []ToolCall) *ToolScore {
+	matched, missing := matchTools(expected, calls)
+	return &ToolScore{
+		Expected:  len(expected),
+		Called:    len(calls),
+		Matched:   matched,
+		Precision: ratio(matched, len(calls)),
+		Recall:    ratio(matched, len(expected)),
+		Missing:   missing,
+	}
+}
+
+// ratio is part of whole, an empty whole counts as fully right
+func ratio(part int, whole int) float64 {
+	if whole == 0 {
+		return 1
+	}
+	return float64(part) / float64(whole)
+}
+
+func (r CaseResult) passed() bool {
+	if r.Error != "" || r.Transcript.State != string(types.COMPLETED) {
+		return false
+	}
+	if r.Tools != nil && r.Tools.Matched < r.Tools.Expected {
+		return false
+	}
+	for _, assertion := range r.Assertions {
+		if !assertion.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed reports whether any case failed
+func (r *Report) Failed() bool {
+	return r.Summary.Failed > 0
+}
+
+func (r *Report) summarize() {
+	summary := Summary{Cases: len(r.Cases)}
+	var matched, called, expected int
+	var latencies []int64
+	var totalLatency int64
+	for _, c := range r.Cases {
+		if c.Passed {
+			summary.Passed++
+		}
+		if c.Tools != nil {
+			matched += c.Tools.Matched
+			called += c.Tools.Called
+			expected += c.Tools.Expected
+		}
+		latencies = append(latencies, c.LatencyMs)
+		totalLatency += c.LatencyMs
+		summary.PromptTokens += c.Usage.PromptTokens
+		summary.CompletionTokens += c.Usage.CompletionTokens
+		summary.JudgeTokens += c.Usage.JudgeTokens
+		summary.CostUSD += c.Usage.CostUSD
+	}
+	summary.Failed = summary.Cases - summary.Passed
+	summary.PassRate = ratio(summary.Passed, summary.Cases)
+	summary.ToolPrecision = ratio(matched, called)
+	summary.ToolRecall = ratio(matched, expected)
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		summary.MeanLatencyMs = totalLatency / int64(len(latencies))
+		summary.P95LatencyMs = latencies[(len(latencies)*95+99)/100-1]
+	}
+	r.Summary = summary
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown writes the summary, a table of the cases and the reasons of the failures
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	s := r.Summary
+	fmt.Fprintf(&b, "# Evaluation report\n\n")
+	fmt.Fprintf(&b, "Run at %s, %d of %d cases passed (%.0f%%).\n\n", r.StartedAt.Format(time.RFC3339), s.Passed, s.Cases, s.PassRate*100)
+	fmt.Fprintf(&b, "| Metric | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Tool precision | %.2f |\n", s.ToolPrecision)
+	fmt.Fprintf(&b, "| Tool recall | %.2f |\n", s.ToolRecall)
+	fmt.Fprintf(&b, "| Mean latency | %d ms |\n", s.MeanLatencyMs)
+	fmt.Fprintf(&b, "| p95 latency | %d ms |\n", s.P95LatencyMs)
+	fmt.Fprintf(&b, "| Prompt tokens | %d |\n", s.PromptTokens)
+	fmt.Fprintf(&b, "| Completion tokens | %d |\n", s.CompletionTokens)
+	fmt.Fprintf(&b, "| Judge tokens | %d |\n", s.JudgeTokens)
+	fmt.Fprintf(&b, "| Cost | $%.4f |\n\n", s.CostUSD)
+
+	fmt.Fprintf(&b, "## Cases\n\n")
+	fmt.Fprintf(&b, "| Case | Result | Tools | Assertions | Latency | Tokens | Cost |\n|---|---|---|---|---|---|---|\n")
+	for _, c := range r.Cases {
+		result := "pass"
+		if !c.Passed {
+			result = "**fail**"
+		}
+		tools := "-"
+		if c.Tools != nil {
+			tools = fmt.Sprintf("%d/%d expected, %d called", c.Tools.Matched, c.Tools.Expected, c.Tools.Called)
+		}
+		passedAssertions := 0
+		for _, assertion := range c.Assertions {
+			if assertion.Passed {
+				passedAssertions++
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %d/%d | %d ms | %d | $%.4f |\n", markdownCell(c.ID), result, tools,
+			passedAssertions, len(c.Assertions), c.LatencyMs,
+			c.Usage.PromptTokens+c.Usage.CompletionTokens+c.Usage.JudgeTokens, c.Usage.CostUSD)
+	}
+
+	var failures strings.Builder
+	for _, c := range r.Cases {
+		if c.Passed {
+			continue
+		}
+		fmt.Fprintf(&failures, "\n### %s\n\n", c.ID)
+		if c.Error != "" {
+			fmt.Fprintf(&failures, "- error: %s\n", markdownCell(c.Error))
+		}
+		if c.Transcript.State != string(types.COMPLETED) {
+			fmt.Fprintf(&failures, "- task ended %s\n", c.Transcript.State)
+		}
+		if c.Tools != nil {
+			for _, missing := range c.Tools.Missing {
+				fmt.Fprintf(&failures, "- expected tool call not made: `%s`\n", missing)
+			}
+		}
+		for _, assertion := range c.Assertions {
+			if !assertion.Passed {
+				fmt.Fprintf(&failures, "- %s: %s\n", assertion.Type, markdownCell(assertion.Detail))
+			}
+		}
+	}
+	if failures.Len() > 0 {
+		fmt.Fprintf(&b, "\n## Failures\n%s", failures.String())
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell keeps text on one line and escapes the table separator
+func markdownCell(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.ReplaceAll(text, "|", `\|`)
+}
@@ -0,0 +1,256 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cohesion-org/deepseek-go"
+	"github.com/yeeaiclub/a2a-go/sdk/server/event"
+	"github.com/yeeaiclub/a2a-go/sdk/server/execution"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+	itypes "github.com/yeeaiclub/github-a2a/types"
+)
+
+// AgentFactory builds the agent for a case, answering with client and calling tools
+type AgentFactory func(client toolset.ChatClient, tools map[string]itypes.Function) execution.AgentExecutor
+
+// Pricing is the price of the model in USD per million tokens
+type Pricing struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// Cost returns the price of the tokens
+func (p Pricing) Cost(promptTokens int, completionTokens int) float64 {
+	return float64(promptTokens)*p.InputPerMillion/1e6 + float64(completionTokens)*p.OutputPerMillion/1e6
+}
+
+// Runner runs the cases of a dataset one after another
+type Runner struct {
+	Agent AgentFactory
+	// Client answers the completions of the agent
+	Client toolset.ChatClient
+	// Tools are offered to the agent, usually answering from recorded GitHub fixtures
+	Tools map[string]itypes.Function
+	// Judge grades judge assertions, they fail when it is nil
+	Judge   *Judge
+	Pricing Pricing
+	// Timeout bounds a single case, zero means no limit
+	Timeout time.Duration
+}
+
+// Run runs the cases and reports their results
+func (r *Runner) Run(ctx context.Context, cases []Case) *Report {
+	report := &Report{StartedAt: time.Now().UTC(), Pricing: r.Pricing}
+	for _, c := range cases {
+		result := r.runCase(ctx, c)
+		slog.InfoContext(ctx, "Evaluated case", "case", c.ID, "passed", result.Passed,
+			"latency_ms", result.LatencyMs, "tool_calls", len(result.Transcript.ToolCalls))
+		report.Cases = append(report.Cases, result)
+	}
+	report.summarize()
+	return report
+}
+
+func (r *Runner) runCase(ctx context.Context, c Case) CaseResult {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	agentMeter := &meter{client: r.Client}
+	recorder := &recorder{}
+	executor := r.Agent(agentMeter, recorder.wrap(r.Tools))
+
+	queue := event.NewQueue(1024)
+	requestContext := &execution.RequestContext{
+		TaskId:    "eval-" + c.ID,
+		ContextId: "eval-" + c.ID,
+		Params: types.MessageSendParam{
+			Message: &types.Message{
+				Role:      types.User,
+				MessageID: "eval-" + c.ID,
+				Parts:     []types.Part{&types.TextPart{Kind: "text", Text: c.Prompt}},
+			},
+		},
+	}
+	start := time.Now()
+	err := executor.Execute(ctx, requestContext, queue)
+	latency := time.Since(start)
+	queue.Close()
+
+	result := CaseResult{
+		ID:         c.ID,
+		Prompt:     c.Prompt,
+		LatencyMs:  latency.Milliseconds(),
+		Transcript: collect(queue),
+	}
+	result.Transcript.ToolCalls = recorder.calls()
+	// The server records a task whose executor returned an error as failed
+	if err != nil {
+		result.Error = err.Error()
+		result.Transcript.State = string(types.FAILED)
+	}
+
+	if c.ExpectedTools != nil {
+		result.Tools = scoreTools(c.ExpectedTools, result.Transcript.ToolCalls)
+	}
+
+	var judge *Judge
+	judgeMeter := &meter{}
+	if r.Judge != nil {
+		judgeMeter.client = r.Judge.Client
+		judge = &Judge{Client: judgeMeter, Model: r.Judge.Model}
+	}
+	for _, assertion := range c.Assertions {
+		result.Assertions = append(result.Assertions, assertion.check(ctx, c, result.Transcript, judge))
+	}
+
+	result.Usage = Usage{
+		Completions:      agentMeter.completions,
+		PromptTokens:     agentMeter.promptTokens,
+		CompletionTokens: agentMeter.completionTokens,
+		JudgeTokens:      judgeMeter.promptTokens + judgeMeter.completionTokens,
+	}
+	result.Usage.CostUSD = r.Pricing.Cost(agentMeter.promptTokens+judgeMeter.promptTokens,
+		agentMeter.completionTokens+judgeMeter.completionTokens)
+	result.Passed = result.passed()
+	return result
+}
+
+// collect reads the final state, the answer and the data artifacts from the events of a case
+func collect(queue *event.Queue) Transcript {
+	var transcript Transcript
+	var answers []string
+	var statusMessage string
+	for streamEvent := range queue.Subscribe(context.Background()) {
+		switch typed := streamEvent.Event.(type) {
+		case *types.TaskStatusUpdateEvent:
+			transcript.State = string(typed.Status.State)
+			statusMessage = messageText(typed.Status.Message)
+		case *types.TaskArtifactUpdateEvent:
+			for _, part := range typed.Artifact.Parts {
+				switch typedPart := part.(type) {
+				case *types.TextPart:
+					answers = append(answers, typedPart.Text)
+				case *types.DataPart:
+					artifact := Artifact{Data: typedPart.Data}
+					artifact.Tool, _ = typed.Artifact.Metadata["tool"].(string)
+					artifact.Schema, _ = typed.Artifact.Metadata["schema"].(string)
+					transcript.Artifacts = append(transcript.Artifacts, artifact)
+				}
+			}
+		}
+	}
+
+	// A task that failed or ran out of iterations explains itself in the status message
+	transcript.Answer = strings.Join(answers, "\n")
+	if transcript.Answer == "" {
+		transcript.Answer = statusMessage
+	}
+	var decoded any
+	if json.Unmarshal([]byte(transcript.Answer), &decoded) == nil {
+		transcript.AnswerJSON = decoded
+	}
+	return transcript
+}
+
+func messageText(message *types.Message) string {
+	if message == nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range message.Parts {
+		if textPart, ok := part.(*types.TextPart); ok {
+			texts = append(texts, textPart.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// meter counts the completions of a client and their tokens
+type meter struct {
+	client toolset.ChatClient
+
+	mu               sync.Mutex
+	completions      int
+	promptTokens     int
+	completionTokens int
+}
+
+func (m *meter) CreateChatCompletion(ctx context.Context, request *deepseek.ChatCompletionRequest) (*deepseek.ChatCompletionResponse, error) {
+	if m.client == nil {
+		return nil, fmt.Errorf("no chat client is configured")
+	}
+	response, err := m.client.CreateChatCompletion(ctx, request)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.completions++
+	if response != nil {
+		m.promptTokens += response.Usage.PromptTokens
+		m.completionTokens += response.Usage.CompletionTokens
+	}
+	return response, err
+}
+
+// recorder records the tool calls of a case
+type recorder struct {
+	mu       sync.Mutex
+	recorded []ToolCall
+}
+
+func (r *recorder) wrap(tools map[string]itypes.Function) map[string]itypes.Function {
+	wrapped := make(map[string]itypes.Function, len(tools))
+	for name, function := range tools {
+		wrapped[name] = recordedTool{Function: function, name: name, recorder: r}
+	}
+	return wrapped
+}
+
+func (r *recorder) calls() []ToolCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ToolCall(nil), r.recorded...)
+}
+
+// recordedTool answers like the wrapped tool and records its calls
+type recordedTool struct {
+	itypes.Function
+	name     string
+	recorder *recorder
+}
+
+func (t recordedTool) Call(ctx context.Context, args map[string]interface{}) interface{} {
+	result := t.Function.Call(ctx, args)
+	call := ToolCall{Name: t.name, Arguments: args, Error: toolError(result)}
+	t.recorder.mu.Lock()
+	t.recorder.recorded = append(t.recorder.recorded, call)
+	t.recorder.mu.Unlock()
+	return result
+}
+
+// toolError returns the message of a result with the error status every tool response carries
+func toolError(result interface{}) string {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err.Error()
+	}
+	var response struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(encoded, &response) != nil || response.Status != "error" {
+		return ""
+	}
+	if response.Message == "" {
+		return "tool returned an error"
+	}
+	return response.Message
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yeeaiclub/github-a2a/server/eval"
+	"github.com/yeeaiclub/github-a2a/server/toolset/fakellm"
+)
+
+func TestEvalCommand(t *testing.T) {
+	// The command sets up logging, which replaces the default logger
+	defer slog.SetDefault(slog.Default())
+
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("list_issues", map[string]any{"repoName": "octo/demo", "limit": 5})),
+		fakellm.Reply("Issue 149 was updated last."),
+		fakellm.Reply("GitHub repositories, issues and pull requests."),
+	)
+	server := httptest.NewServer(llm)
+	defer server.Close()
+	env := map[string]string{"DEEPSEEK_API_KEY": "test-key", "DEEPSEEK_BASE_URL": server.URL + "/"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	var stdout, stderr bytes.Buffer
+	code := runEval([]string{
+		"-dataset", "eval/requests.jsonl",
+		"-fixtures", "toolset/testdata/github",
+		"-run", "^(open-issues|capabilities)$",
+		"-json", reportPath,
+		"-markdown", "-",
+	}, lookup, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d\n%s\n%s", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "2 of 2 cases passed") {
+		t.Errorf("markdown report:\n%s", stdout.String())
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report eval.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Summary.Passed != 2 || report.Summary.PromptTokens == 0 || report.Summary.CostUSD == 0 {
+		t.Errorf("summary %+v", report.Summary)
+	}
+
+	// A failing case exits with 1, a dataset that cannot be read with 2
+	llm = fakellm.New(fakellm.Reply("I cannot help."))
+	server.Config.Handler = llm
+	stdout.Reset()
+	if code := runEval([]string{"-dataset", "eval/requests.jsonl", "-run", "capabilities"}, lookup, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d for a failing case, want 1", code)
+	}
+	if code := runEval([]string{"-dataset", "missing.jsonl"}, lookup, &stdout, &stderr); code != 2 {
+		t.Errorf("exit code %d for a missing dataset, want 2", code)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/server/event"
+	"github.com/yeeaiclub/a2a-go/sdk/server/execution"
+	"github.com/yeeaiclub/a2a-go/sdk/server/tasks"
+	"github.com/yeeaiclub/a2a-go/sdk/server/tasks/manager"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/logging"
+	"github.com/yeeaiclub/github-a2a/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fanOutExecutor runs the wrapped executor against a private queue and publishes its events through the manager.
+// The execution is detached from the request, so a task keeps running when its client disconnects.
+type fanOutExecutor struct {
+	execution.AgentExecutor
+	manager *QueueManager
+	store   tasks.TaskStore
+}
+
+// Cancel stops the running execution of the task, which then records the task as canceled.
+// Tasks without a running execution are canceled by the wrapped executor.
+func (f *fanOutExecutor) Cancel(ctx context.Context, requestContext *execution.RequestContext, queue *event.Queue) error {
+	if f.manager.Cancel(requestContext.TaskId, ErrTaskCanceled) {
+		return nil
+	}
+	return f.AgentExecutor.Cancel(ctx, requestContext, queue)
+}
+
+func (f *fanOutExecutor) Execute(ctx context.Context, requestContext *execution.RequestContext, queue *event.Queue) (err error) {
+	// The request context is released by the server once the request ends, only its done channel is kept.
+	// Until it closes the handler consumes the primary queue and persists the events.
+	requestDone := ctx.Done()
+
+	taskId := requestContext.TaskId
+	spanCtx, span := tracing.Tracer().Start(tracing.Detach(ctx), "a2a.task.execute", trace.WithAttributes(
+		attribute.String("a2a.task_id", taskId),
+		attribute.String("a2a.context_id", requestContext.ContextId),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	spanCtx = logging.WithTask(spanCtx, taskId, requestContext.ContextId)
+	runCtx, cancel := context.WithCancelCause(spanCtx)
+	defer cancel(nil)
+
+	run := f.manager.start(taskId, queue, cancel)
+	defer f.manager.finish(taskId, run)
+
+	source := event.NewQueue(f.manager.capacity)
+	result := make(chan error, 1)
+	go func() {
+		defer source.Close()
+		result <- f.AgentExecutor.Execute(runCtx, requestContext, source)
+	}()
+
+	var persist *manager.TaskManager
+	// aborted fires when Drain cancels the execution, abandon when it then does not stop in time
+	aborted := runCtx.Done()
+	var abandon <-chan time.Time
+	abandoned := false
+	events := source.Subscribe(context.Background())
+loop:
+	for {
+		select {
+		case <-aborted:
+			aborted = nil
+			abandon = time.After(f.manager.abortGrace)
+		case <-abandon:
+			slog.WarnContext(spanCtx, "Executor did not stop after being canceled, abandoning it")
+			abandoned = true
+			break loop
+		case <-requestDone:
+			// Events the handler consumed but did not persist yet at this point may be lost,
+			// the final status always arrives later and is persisted here.
+			requestDone = nil
+			span.AddEvent("client disconnected")
+			persist = manager.NewTaskManger(
+				f.store,
+				manager.WithTaskId(taskId),
+				manager.WithContextId(requestContext.ContextId),
+			)
+			run.detach()
+			slog.InfoContext(spanCtx, "Client went away, the task continues in the background")
+		case e, ok := <-events:
+			// Subscribe stops after a done or error event, the executor is expected to stop publishing then
+			if !ok || e.Type == types.EventClosed || e.Type == types.EventCanceled {
+				break loop
+			}
+			f.manager.publish(run, e)
+			f.manager.notify(e.Event)
+			if persist != nil && e.Event != nil {
+				if _, err := persist.Process(context.Background(), e.Event); err != nil {
+					slog.ErrorContext(spanCtx, "Failed to persist event", "error", err)
+				}
+			}
+		}
+	}
+
+	if abandoned {
+		// The executor may still be stuck, it is not waited for beyond the grace
+		select {
+		case err = <-result:
+		default:
+		}
+		if err == nil {
+			err = context.Cause(runCtx)
+		}
+	} else {
+		err = <-result
+	}
+	if err != nil {
+		// Record the failure so subscribers and the store see the task end, the handler
+		// still reports the error itself on the primary queue
+		state, reason := types.FAILED, err.Error()
+		switch cause := context.Cause(runCtx); {
+		case errors.Is(cause, ErrShuttingDown), errors.Is(cause, ErrTaskCanceled):
+			state, reason = types.CANCELED, "Task canceled: "+cause.Error()
+		}
+		failed := &types.TaskStatusUpdateEvent{
+			TaskId:    taskId,
+			ContextId: requestContext.ContextId,
+			Final:     true,
+			Status: types.TaskStatus{
+				State:     state,
+				TimeStamp: time.Now().Format(time.RFC3339),
+				Message: &types.Message{
+					Role:  types.Agent,
+					Parts: []types.Part{&types.TextPart{Kind: "text", Text: reason}},
+				},
+			},
+		}
+		f.manager.publish(run, types.StreamEvent{Type: types.EventDone, Event: failed})
+		f.manager.notify(failed)
+		if persist != nil {
+			if _, err := persist.Process(context.Background(), failed); err != nil {
+				slog.ErrorContext(spanCtx, "Failed to persist failure", "error", err)
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/yeeaiclub/a2a-go/sdk/server"
+	"github.com/yeeaiclub/a2a-go/sdk/server/handler"
+	"github.com/yeeaiclub/a2a-go/sdk/server/tasks"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// resubscribeFromKey is the tasks/resubscribe metadata key holding the index of the first event to replay
+const resubscribeFromKey = "from"
+
+// TaskHandler extends the default handler with tasks/resubscribe that replays the retained event log
+// and push notification configs for new tasks
+type TaskHandler struct {
+	*handler.DefaultHandler
+	store        tasks.TaskStore
+	queues       *QueueManager
+	pushNotifier tasks.PushNotifier
+}
+
+// NewTaskHandler wraps the default handler, pushNotifier may be nil when push notifications are disabled
+func NewTaskHandler(defaultHandler *handler.DefaultHandler, store tasks.TaskStore, queues *QueueManager, pushNotifier tasks.PushNotifier) *TaskHandler {
+	return &TaskHandler{
+		DefaultHandler: defaultHandler,
+		store:          store,
+		queues:         queues,
+		pushNotifier:   pushNotifier,
+	}
+}
+
+// OnMessageSend runs the default handler on a detached call context, see detach.
+// Messages are rejected once the server is shutting down.
+func (h *TaskHandler) OnMessageSend(ctx *server.CallContext, params types.MessageSendParam) (types.Event, error) {
+	traceMessage(ctx, types.MethodMessageSend, params)
+	if h.queues.Draining() {
+		return nil, ErrShuttingDown
+	}
+	if err := h.registerPushConfig(ctx, &params); err != nil {
+		return nil, err
+	}
+	return h.DefaultHandler.OnMessageSend(detach(ctx), params)
+}
+
+// OnMessageSendStream runs the default handler on a detached call context, see detach
+func (h *TaskHandler) OnMessageSendStream(ctx *server.CallContext, params types.MessageSendParam) <-chan types.StreamEvent {
+	traceMessage(ctx, types.MethodMessageStream, params)
+	err := ErrShuttingDown
+	if !h.queues.Draining() {
+		err = h.registerPushConfig(ctx, &params)
+	}
+	if err != nil {
+		out := make(chan types.StreamEvent, 1)
+		out <- types.StreamEvent{Type: types.EventError, Err: err}
+		close(out)
+		return out
+	}
+	return h.DefaultHandler.OnMessageSendStream(detach(ctx), params)
+}
+
+// OnCancelTask runs the default handler on a detached call context, see detach
+func (h *TaskHandler) OnCancelTask(ctx *server.CallContext, params types.TaskIdParams) (*types.Task, error) {
+	traceRequest(ctx, types.MethodTasksCancel, params.Id)
+	return h.DefaultHandler.OnCancelTask(detach(ctx), params)
+}
+
+// OnGetTask names the request span, see traceRequest
+func (h *TaskHandler) OnGetTask(ctx *server.CallContext, params types.TaskQueryParams) (*types.Task, error) {
+	traceRequest(ctx, types.MethodTasksGet, params.Id)
+	return h.DefaultHandler.OnGetTask(ctx, params)
+}
+
+// OnSetTaskPushNotificationConfig names the request span, see traceRequest
+func (h *TaskHandler) OnSetTaskPushNotificationConfig(ctx *server.CallContext, params types.TaskPushNotificationConfig) (*types.TaskPushNotificationConfig, error) {
+	traceRequest(ctx, types.MethodPushNotificationSet, params.TaskId)
+	return h.DefaultHandler.OnSetTaskPushNotificationConfig(ctx, params)
+}
+
+// OnGetTaskPushNotificationConfig names the request span, see traceRequest
+func (h *TaskHandler) OnGetTaskPushNotificationConfig(ctx *server.CallContext, params types.TaskIdParams) (*types.TaskPushNotificationConfig, error) {
+	traceRequest(ctx, types.MethodPushNotificationGet, params.Id)
+	return h.DefaultHandler.OnGetTaskPushNotificationConfig(ctx, params)
+}
+
+// OnResubscribeToTask streams the events of a task the client has not seen yet, then follows the live events.
+// Events are replayed from metadata "from", counting every event of the current execution from zero.
+func (h *TaskHandler) OnResubscribeToTask(ctx *server.CallContext, params types.TaskIdParams) <-chan types.StreamEvent {
+	traceRequest(ctx, types.MethodTasksResubscribe, params.Id)
+	out := make(chan types.StreamEvent, h.queues.capacity)
+
+	task, err := h.store.Get(ctx, params.Id)
+	if err != nil {
+		out <- types.StreamEvent{Type: types.EventError, Err: err}
+		close(out)
+		return out
+	}
+	if task == nil {
+		out <- types.StreamEvent{Type: types.EventError, Err: fmt.Errorf("task %s not found", params.Id)}
+		close(out)
+		return out
+	}
+
+	replay, _, live, ok := h.queues.Resubscribe(task.Id, resubscribeFrom(params.Metadata))
+	if !ok {
+		// Nothing is retained for the task, the stored task is the best that can be offered
+		streamType := types.EventData
+		if finished(task) {
+			streamType = types.EventDone
+		}
+		out <- types.StreamEvent{Type: streamType, Event: task}
+		close(out)
+		return out
+	}
+
+	// The call context is released once the request ends, keep only its done channel
+	requestDone := ctx.Done()
+	go func() {
+		defer close(out)
+
+		send := func(e types.StreamEvent) bool {
+			select {
+			case out <- e:
+				return true
+			case <-requestDone:
+				return false
+			}
+		}
+
+		for _, e := range replay {
+			streamType := types.EventData
+			if e.Done() {
+				streamType = types.EventDone
+			}
+			if !send(types.StreamEvent{Type: streamType, Event: e}) || e.Done() {
+				if live != nil {
+					h.queues.Untap(task.Id, live)
+				}
+				return
+			}
+		}
+		if live == nil {
+			return
+		}
+
+		subscription, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events := live.Subscribe(subscription)
+		for e := range events {
+			if e.Type == types.EventClosed || e.Type == types.EventCanceled {
+				return
+			}
+			if !send(e) {
+				h.queues.Untap(task.Id, live)
+				cancel()
+				// Drain so the subscription goroutine can exit
+				for range events {
+				}
+				return
+			}
+			if e.Type == types.EventDone || e.Type == types.EventError {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// registerPushConfig stores the push notification config sent along with a message.
+// The default handler only does so for existing tasks, so new tasks get their ID assigned here.
+func (h *TaskHandler) registerPushConfig(ctx context.Context, params *types.MessageSendParam) error {
+	if h.pushNotifier == nil || params.Message == nil || params.Configuration == nil || params.Configuration.PushNotificationConfig == nil {
+		return nil
+	}
+	if params.Message.TaskID == "" {
+		params.Message.TaskID = uuid.New().String()
+	}
+	return h.pushNotifier.SetInfo(ctx, params.Message.TaskID, params.Configuration.PushNotificationConfig)
+}
+
+// runTask returns a function running a message as a task of the agent, like one sent to message/send,
+// so tasks the server starts on its own are stored, measured and canceled like any other
+func runTask(taskHandler *TaskHandler, taskStore tasks.TaskStore) func(ctx context.Context, message *types.Message) (*types.Task, error) {
+	return func(ctx context.Context, message *types.Message) (*types.Task, error) {
+		event, err := taskHandler.OnMessageSend(server.NewCallContext(ctx), types.MessageSendParam{Message: message})
+		if err != nil {
+			return nil, err
+		}
+		task, err := taskStore.Get(ctx, event.GetTaskId())
+		if err != nil {
+			return nil, err
+		}
+		if task == nil {
+			return nil, fmt.Errorf("task %s not found", event.GetTaskId())
+		}
+		return task, nil
+	}
+}
+
+// detach copies a call context into one that is never returned to the pool.
+// The server releases its call context when the request ends, which clears the underlying
+// context while goroutines started by the default handler may still use it. The copy is
+// canceled when the request ends, but stays safe to use afterwards.
+func detach(ctx *server.CallContext) *server.CallContext {
+	detached := server.NewCallContext(tracing.Detach(ctx))
+	detached.SetUser(ctx.GetUser())
+	detached.SetRequest(ctx.Request())
+
+	requestDone := ctx.Done()
+	go func() {
+		select {
+		case <-requestDone:
+			detached.Cancel()
+		case <-detached.Done():
+		}
+	}()
+	return detached
+}
+
+// traceRequest names the span of the HTTP request after the JSON-RPC method and tags it with the task
+func traceRequest(ctx context.Context, method string, taskId string) {
+	span := trace.SpanFromContext(ctx)
+	span.SetName("a2a " + method)
+	span.SetAttributes(attribute.String("rpc.system", "jsonrpc"), attribute.String("rpc.method", method))
+	if taskId != "" {
+		span.SetAttributes(attribute.String("a2a.task_id", taskId))
+	}
+}
+
+// requestSpanName keeps the name traceRequest gave the request span, otelhttp renames the span
+// with its formatter once the handler returned
+func requestSpanName(operation string, r *http.Request) string {
+	if span, ok := trace.SpanFromContext(r.Context()).(sdktrace.ReadOnlySpan); ok && span.Name() != "" {
+		return span.Name()
+	}
+	return operation
+}
+
+// traceMessage names the request span of a message, tagging the context as well when the client set it
+func traceMessage(ctx context.Context, method string, params types.MessageSendParam) {
+	if params.Message == nil {
+		traceRequest(ctx, method, "")
+		return
+	}
+	traceRequest(ctx, method, params.Message.TaskID)
+	if params.Message.ContextID != "" {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("a2a.context_id", params.Message.ContextID))
+	}
+}
+
+// resubscribeFrom reads the replay start index from the request metadata
+func resubscribeFrom(metadata map[string]any) int {
+	switch from := metadata[resubscribeFromKey].(type) {
+	case float64:
+		return int(from)
+	case int:
+		return from
+	}
+	return 0
+}
+
+// finished reports whether a task reached a terminal state
+func finished(task *types.Task) bool {
+	switch task.Status.State {
+	case types.COMPLETED, types.CANCELED, types.FAILED, types.REJECTED:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// Package fakeagent provides the task runner and push notifier for tests of the components that
+// start agent tasks on their own, the scheduler and the webhook receiver
+package fakeagent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// Agent records the messages it is asked to run and finishes a task for each of them
+type Agent struct {
+	// State is the state of the finished tasks, completed when empty
+	State types.TaskState
+	// Answer is the text artifact of the finished tasks, none when empty
+	Answer string
+	// Err fails the runs instead of finishing a task
+	Err error
+
+	mu       sync.Mutex
+	messages []*types.Message
+	answered int
+}
+
+// Run runs the message, the task keeps the ID assigned by the caller or is numbered task-1, task-2, …
+func (a *Agent) Run(ctx context.Context, message *types.Message) (*types.Task, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.messages = append(a.messages, message)
+	if a.Err != nil {
+		return nil, a.Err
+	}
+	a.answered++
+	task := &types.Task{Id: message.TaskID, ContextId: message.ContextID, Kind: "task", Status: types.TaskStatus{State: a.State}}
+	if task.Id == "" {
+		task.Id = fmt.Sprintf("task-%d", a.answered)
+	}
+	if task.Status.State == "" {
+		task.Status.State = types.COMPLETED
+	}
+	if a.Answer != "" {
+		task.Artifacts = []types.Artifact{{ArtifactId: "answer", Parts: []types.Part{&types.TextPart{Kind: "text", Text: a.Answer}}}}
+	}
+	return task, nil
+}
+
+// Messages returns the messages run so far
+func (a *Agent) Messages() []*types.Message {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*types.Message(nil), a.messages...)
+}
+
+// Prompts returns the text of the messages run so far
+func (a *Agent) Prompts() []string {
+	var prompts []string
+	for _, message := range a.Messages() {
+		prompts = append(prompts, message.Parts[0].(*types.TextPart).Text)
+	}
+	return prompts
+}
+
+// Notifier records the push notification configs and the tasks sent
+type Notifier struct {
+	mu      sync.Mutex
+	configs map[string]*types.PushNotificationConfig
+	sent    []*types.Task
+}
+
+func (n *Notifier) SetInfo(ctx context.Context, taskId string, config *types.PushNotificationConfig) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.configs == nil {
+		n.configs = map[string]*types.PushNotificationConfig{}
+	}
+	n.configs[taskId] = config
+	return nil
+}
+
+func (n *Notifier) SendNotification(task *types.Task) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, task)
+	return nil
+}
+
+// Config returns the config registered for the task
+func (n *Notifier) Config(taskID string) *types.PushNotificationConfig {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.configs[taskID]
+}
+
+// Sent returns the tasks sent so far
+func (n *Notifier) Sent() []*types.Task {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*types.Task(nil), n.sent...)
+}
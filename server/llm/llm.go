@@ -18,7 +18,7 @@ func NewDeepSeek(client *deepseek.Client) *DeepSeekHandler {
 }
 
 func (d *DeepSeekHandler) Handle(ctx context.Context, message []types.LLMRequest) error {
-	completion, err := d.client.CreateChatCompletion(ctx, &deepseek.ChatCompletionRequest{
+	_, err := d.client.CreateChatCompletion(ctx, &deepseek.ChatCompletionRequest{
 		Model:    d.model,
 		Messages: d.toMessage(message),
 	})
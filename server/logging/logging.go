@@ -0,0 +1,166 @@
+// Package logging configures structured logging with log/slog.
+// Records carry the task and trace of their context, and secrets, emails and message bodies
+// are redacted unless debug logging is enabled.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config configures the default logger
+type Config struct {
+	// Level is debug, info, warn or error
+	Level string
+	// Format is text or json
+	Format string
+}
+
+// Keys of attributes holding user content, redacted unless debug logging is enabled
+const (
+	KeyMessage   = "message"
+	KeyContent   = "content"
+	KeyResult    = "result"
+	KeyArguments = "arguments"
+	KeyBody      = "body"
+)
+
+var bodyKeys = map[string]bool{
+	KeyMessage:   true,
+	KeyContent:   true,
+	KeyResult:    true,
+	KeyArguments: true,
+	KeyBody:      true,
+}
+
+var (
+	// tokenPattern matches GitHub tokens, API keys and bearer credentials. A value after the word token
+	// must be as long as a credential, so prose such as "token estimate" is kept.
+	tokenPattern = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,}|sk-[A-Za-z0-9]{16,})\b|(?i)\bbearer\s+[A-Za-z0-9._~+/=-]{8,}|(?i)\btoken[\s:=]+[A-Za-z0-9._~+/=-]{20,}`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// New creates a logger writing to w
+func New(config Config, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", config.Level, err)
+	}
+
+	options := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactor(level <= slog.LevelDebug),
+	}
+	var handler slog.Handler
+	switch strings.ToLower(config.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, use text or json", config.Format)
+	}
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// Setup installs a logger writing to w as the default, which the log package then writes through too
+func Setup(config Config, w io.Writer) error {
+	logger, err := New(config, w)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// Redact masks tokens and email addresses in s
+func Redact(s string) string {
+	return scrub(s, false)
+}
+
+// scrub masks tokens in s, and email addresses as well unless debug is set
+func scrub(s string, debug bool) string {
+	s = tokenPattern.ReplaceAllString(s, "[REDACTED]")
+	if debug {
+		return s
+	}
+	return emailPattern.ReplaceAllString(s, "[EMAIL]")
+}
+
+// redactor scrubs string attributes and errors and hides user content unless debug is set, other
+// values keep their type
+func redactor(debug bool) func(groups []string, attr slog.Attr) slog.Attr {
+	return func(groups []string, attr slog.Attr) slog.Attr {
+		switch attr.Value.Kind() {
+		case slog.KindString:
+		case slog.KindAny:
+			if bodyKeys[attr.Key] {
+				break
+			}
+			switch value := attr.Value.Any().(type) {
+			case error:
+				return slog.String(attr.Key, scrub(value.Error(), debug))
+			case []string:
+				scrubbed := make([]string, len(value))
+				for i, item := range value {
+					scrubbed[i] = scrub(item, debug)
+				}
+				return slog.Any(attr.Key, scrubbed)
+			}
+			return attr
+		default:
+			return attr
+		}
+		value := attr.Value.String()
+		if !debug && bodyKeys[attr.Key] {
+			return slog.String(attr.Key, fmt.Sprintf("[%d bytes]", len(value)))
+		}
+		return slog.String(attr.Key, scrub(value, debug))
+	}
+}
+
+type taskKey struct{}
+
+type taskIDs struct {
+	taskId    string
+	contextId string
+}
+
+// WithTask returns a context whose log records carry the task and context IDs
+func WithTask(ctx context.Context, taskId string, contextId string) context.Context {
+	return context.WithValue(ctx, taskKey{}, taskIDs{taskId: taskId, contextId: contextId})
+}
+
+// contextHandler adds the task and trace IDs found in the context of a record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ids, ok := ctx.Value(taskKey{}).(taskIDs); ok {
+		if ids.taskId != "" {
+			record.AddAttrs(slog.String("task_id", ids.taskId))
+		}
+		if ids.contextId != "" {
+			record.AddAttrs(slog.String("context_id", ids.contextId))
+		}
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/metrics"
+)
+
+// taskMetrics counts the final states of tasks published by wrapped executors
+type taskMetrics struct{}
+
+func (taskMetrics) OnEvent(e types.Event) {
+	status, ok := e.(*types.TaskStatusUpdateEvent)
+	if !ok || !status.Final {
+		return
+	}
+	metrics.TasksTotal.WithLabelValues(string(status.Status.State)).Inc()
+}
+
+// queueCollector reports the queues held by a QueueManager on every scrape
+type queueCollector struct {
+	queues *QueueManager
+
+	queuesDesc   *prometheus.Desc
+	tapsDesc     *prometheus.Desc
+	stalledDesc  *prometheus.Desc
+	retainedDesc *prometheus.Desc
+	droppedDesc  *prometheus.Desc
+}
+
+func newQueueCollector(queues *QueueManager) *queueCollector {
+	return &queueCollector{
+		queues:       queues,
+		queuesDesc:   prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "", "queues"), "Task queues held, by state (running, finished, idle).", []string{"state"}, nil),
+		tapsDesc:     prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "", "queue_taps"), "Subscribers tapped into task queues.", nil, nil),
+		stalledDesc:  prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "", "queue_stalled_taps"), "Subscribers that stopped reading their queue.", nil, nil),
+		retainedDesc: prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "", "queue_retained_events"), "Events retained for tasks/resubscribe.", nil, nil),
+		droppedDesc:  prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "", "queue_dropped_events"), "Events dropped for slow subscribers of the held queues.", nil, nil),
+	}
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queuesDesc
+	ch <- c.tapsDesc
+	ch <- c.stalledDesc
+	ch <- c.retainedDesc
+	ch <- c.droppedDesc
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	states := map[string]int{"running": 0, "finished": 0, "idle": 0}
+	var taps, stalled, retained, dropped int
+	for _, info := range c.queues.Snapshot() {
+		switch {
+		case info.Running:
+			states["running"]++
+		case info.Finished:
+			states["finished"]++
+		default:
+			states["idle"]++
+		}
+		taps += info.Taps
+		stalled += info.StalledTaps
+		retained += info.Retained
+		dropped += info.Dropped
+	}
+
+	for state, count := range states {
+		ch <- prometheus.MustNewConstMetric(c.queuesDesc, prometheus.GaugeValue, float64(count), state)
+	}
+	ch <- prometheus.MustNewConstMetric(c.tapsDesc, prometheus.GaugeValue, float64(taps))
+	ch <- prometheus.MustNewConstMetric(c.stalledDesc, prometheus.GaugeValue, float64(stalled))
+	ch <- prometheus.MustNewConstMetric(c.retainedDesc, prometheus.GaugeValue, float64(retained))
+	ch <- prometheus.MustNewConstMetric(c.droppedDesc, prometheus.GaugeValue, float64(dropped))
+}
@@ -0,0 +1,138 @@
+// Package metrics defines the Prometheus metrics of the agent
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the names of all agent metrics
+const Namespace = "github_a2a"
+
+// UnknownTool is the tool label of calls to tools that do not exist, the model cannot add label values
+const UnknownTool = "unknown"
+
+var (
+	// TasksTotal counts tasks by the final state they reached
+	TasksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "tasks_total",
+		Help:      "Tasks that reached a final state, by state.",
+	}, []string{"state"})
+
+	// LLMRequestDuration observes chat completion calls by model and outcome
+	LLMRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Latency of chat completion calls.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 4, 8, 16, 32, 64},
+	}, []string{"model", "outcome"})
+
+	// LLMTokensTotal counts the tokens reported in the completion usage by model and kind
+	LLMTokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens used by chat completions, by kind (prompt, completion, cache_hit).",
+	}, []string{"model", "kind"})
+
+	// ToolCallsTotal counts tool calls by tool name and outcome
+	ToolCallsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "tool_calls_total",
+		Help:      "Tool calls requested by the model, by tool and outcome (ok, error, invalid_arguments, unknown_tool).",
+	}, []string{"tool", "outcome"})
+
+	// ToolCallDuration observes tool calls by tool name
+	ToolCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "tool_call_duration_seconds",
+		Help:      "Latency of tool calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"tool"})
+
+	// GitHubRequestsTotal counts GitHub API requests by API and status code
+	GitHubRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "github_requests_total",
+		Help:      "GitHub API requests, by API (rest, graphql) and status code, 0 for transport errors.",
+	}, []string{"api", "code"})
+
+	// GitHubRequestDuration observes GitHub API requests by API
+	GitHubRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "github_request_duration_seconds",
+		Help:      "Latency of GitHub API requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"api"})
+
+	// GitHubRateLimitRemaining is the remaining rate limit reported by the last GitHub response per resource
+	GitHubRateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "github_rate_limit_remaining",
+		Help:      "Remaining GitHub API rate limit from the last response, by resource.",
+	}, []string{"resource"})
+)
+
+// Registry holds the agent metrics together with the Go runtime and process collectors
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		TasksTotal,
+		LLMRequestDuration,
+		LLMTokensTotal,
+		ToolCallsTotal,
+		ToolCallDuration,
+		GitHubRequestsTotal,
+		GitHubRequestDuration,
+		GitHubRateLimitRemaining,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Transport records metrics for the GitHub API requests it sends, a nil Base uses the default transport
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip sends the request through the base transport and records its outcome
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	api := "rest"
+	if strings.HasSuffix(req.URL.Path, "/graphql") {
+		api = "graphql"
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	GitHubRequestDuration.WithLabelValues(api).Observe(time.Since(start).Seconds())
+	if err != nil {
+		GitHubRequestsTotal.WithLabelValues(api, "0").Inc()
+		return resp, err
+	}
+	GitHubRequestsTotal.WithLabelValues(api, strconv.Itoa(resp.StatusCode)).Inc()
+
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		resource := resp.Header.Get("X-RateLimit-Resource")
+		if resource == "" {
+			resource = api
+		}
+		GitHubRateLimitRemaining.WithLabelValues(resource).Set(float64(remaining))
+	}
+	return resp, nil
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestTransport(t *testing.T) {
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/octo/demo":
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+			w.Header().Set("X-RateLimit-Resource", "core")
+		case "/search/repositories":
+			w.Header().Set("X-RateLimit-Remaining", "29")
+			w.Header().Set("X-RateLimit-Resource", "search")
+		case "/graphql":
+			// Without a resource the API names the limit
+			w.Header().Set("X-RateLimit-Remaining", "4990")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer github.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+	for _, path := range []string{"/repos/octo/demo", "/repos/octo/demo", "/search/repositories", "/graphql", "/repos/octo/missing"} {
+		resp, err := client.Get(github.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	failing := &http.Client{Transport: &Transport{Base: failingTransport{}}}
+	if _, err := failing.Get(github.URL + "/repos/octo/demo"); err == nil {
+		t.Fatal("transport error not returned")
+	}
+
+	want := `
+# HELP github_a2a_github_rate_limit_remaining Remaining GitHub API rate limit from the last response, by resource.
+# TYPE github_a2a_github_rate_limit_remaining gauge
+github_a2a_github_rate_limit_remaining{resource="core"} 4999
+github_a2a_github_rate_limit_remaining{resource="graphql"} 4990
+github_a2a_github_rate_limit_remaining{resource="search"} 29
+# HELP github_a2a_github_requests_total GitHub API requests, by API (rest, graphql) and status code, 0 for transport errors.
+# TYPE github_a2a_github_requests_total counter
+github_a2a_github_requests_total{api="graphql",code="200"} 1
+github_a2a_github_requests_total{api="rest",code="0"} 1
+github_a2a_github_requests_total{api="rest",code="200"} 3
+github_a2a_github_requests_total{api="rest",code="404"} 1
+`
+	if err := testutil.CollectAndCompare(Registry, strings.NewReader(want), Namespace+"_github_rate_limit_remaining", Namespace+"_github_requests_total"); err != nil {
+		t.Error(err)
+	}
+	if got := testutil.CollectAndCount(GitHubRequestDuration); got != 2 {
+		t.Errorf("%d request duration series, want one per API", got)
+	}
+}
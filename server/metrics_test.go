@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+func TestQueueCollector(t *testing.T) {
+	q := NewQueueManager(WithIdleTTL(0), WithEventLog(2, time.Minute))
+	defer q.Stop()
+	ctx := context.Background()
+
+	// A running task with a subscriber, a finished one with retained events and an idle queue
+	queue, _ := q.CreateOrTap(ctx, "running")
+	running := q.start("running", queue, func(error) {})
+	q.Tap(ctx, "running")
+	q.publish(running, statusEvent("running", types.WORKING))
+	queue, _ = q.CreateOrTap(ctx, "finished")
+	finished := q.start("finished", queue, func(error) {})
+	for _, state := range []types.TaskState{types.WORKING, types.WORKING, types.COMPLETED} {
+		q.publish(finished, statusEvent("finished", state))
+	}
+	q.finish("finished", finished)
+	q.CreateOrTap(ctx, "idle")
+
+	want := `
+# HELP github_a2a_queue_dropped_events Events dropped for slow subscribers of the held queues.
+# TYPE github_a2a_queue_dropped_events gauge
+github_a2a_queue_dropped_events 0
+# HELP github_a2a_queue_retained_events Events retained for tasks/resubscribe.
+# TYPE github_a2a_queue_retained_events gauge
+github_a2a_queue_retained_events 3
+# HELP github_a2a_queue_stalled_taps Subscribers that stopped reading their queue.
+# TYPE github_a2a_queue_stalled_taps gauge
+github_a2a_queue_stalled_taps 0
+# HELP github_a2a_queue_taps Subscribers tapped into task queues.
+# TYPE github_a2a_queue_taps gauge
+github_a2a_queue_taps 1
+# HELP github_a2a_queues Task queues held, by state (running, finished, idle).
+# TYPE github_a2a_queues gauge
+github_a2a_queues{state="finished"} 1
+github_a2a_queues{state="idle"} 1
+github_a2a_queues{state="running"} 1
+`
+	if err := testutil.CollectAndCompare(newQueueCollector(q), strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,469 @@
+// Package push delivers A2A push notifications for task updates to client webhooks
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// Headers set on every notification request
+const (
+	HeaderTimestamp = "X-A2A-Timestamp"
+	HeaderSignature = "X-A2A-Signature"
+	HeaderEvent     = "X-A2A-Event"
+	HeaderDelivery  = "X-A2A-Delivery"
+)
+
+// Config configures notification delivery
+type Config struct {
+	// SigningSecret signs every payload with HMAC-SHA256 when set
+	SigningSecret string `json:"signing_secret,omitempty"`
+	// AllowedHosts restricts webhook URLs to these hosts when not empty. Loopback, private and link-local
+	// addresses are refused unless their host is listed here.
+	AllowedHosts []string `json:"allowed_hosts,omitempty"`
+	// MaxAttempts bounds how often a delivery is tried
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// InitialBackoff is the wait before the first retry, it doubles up to MaxBackoff
+	InitialBackoff time.Duration `json:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration `json:"max_backoff,omitempty"`
+	// Timeout bounds a single delivery attempt
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// MaxPending bounds the queued deliveries per task, the oldest are dropped first
+	MaxPending int `json:"max_pending,omitempty"`
+	// LogSize is how many delivery records are kept
+	LogSize int `json:"log_size,omitempty"`
+}
+
+// Delivery records one notification and the outcome of its attempts
+type Delivery struct {
+	ID          string     `json:"id"`
+	TaskID      string     `json:"task_id"`
+	URL         string     `json:"url"`
+	Event       string     `json:"event"`
+	Attempts    int        `json:"attempts"`
+	Status      string     `json:"status"`
+	StatusCode  int        `json:"status_code,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+	StatusDropped   = "dropped"
+)
+
+type notification struct {
+	delivery *Delivery
+	config   types.PushNotificationConfig
+	body     []byte
+	// last is set for the notification of a terminal state, no more follow for the task
+	last bool
+}
+
+// Notifier stores push notification configs per task and delivers task events to them.
+// Deliveries of one task are sent in order by a single goroutine per task.
+type Notifier struct {
+	config Config
+	client *http.Client
+	// trusted sends to the allowed hosts, which may be internal
+	trusted *http.Client
+
+	mu       sync.RWMutex
+	configs  map[string]*types.PushNotificationConfig
+	pending  map[string][]*notification
+	log      []*Delivery
+	sequence int
+
+	wg sync.WaitGroup
+}
+
+// NewNotifier creates a notifier. A nil client uses one that refuses to connect to internal addresses of hosts
+// that are not allowed explicitly and does not follow redirects, since webhook URLs are chosen by clients.
+func NewNotifier(config Config, client *http.Client) *Notifier {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxPending <= 0 {
+		config.MaxPending = 100
+	}
+	if config.LogSize <= 0 {
+		config.LogSize = 500
+	}
+	trusted := client
+	if client == nil {
+		client = publicClient()
+		trusted = &http.Client{CheckRedirect: refuseRedirect}
+	}
+	return &Notifier{
+		config:  config,
+		client:  client,
+		trusted: trusted,
+		configs: make(map[string]*types.PushNotificationConfig),
+		pending: make(map[string][]*notification),
+	}
+}
+
+// SetInfo validates and stores the push notification config of a task
+func (n *Notifier) SetInfo(ctx context.Context, taskId string, config *types.PushNotificationConfig) error {
+	if config == nil {
+		return errors.New("push notification config is required")
+	}
+	if err := n.validateURL(config.URL); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	stored := *config
+	n.configs[taskId] = &stored
+	return nil
+}
+
+// GetInfo returns the push notification config of a task, or nil when none is set
+func (n *Notifier) GetInfo(ctx context.Context, taskId string) (*types.PushNotificationConfig, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if config, ok := n.configs[taskId]; ok {
+		stored := *config
+		return &stored, nil
+	}
+	return nil, nil
+}
+
+// Delete removes the push notification config of a task
+func (n *Notifier) Delete(ctx context.Context, taskId string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.configs, taskId)
+	return nil
+}
+
+// SendNotification queues the task snapshot for delivery
+func (n *Notifier) SendNotification(task *types.Task) error {
+	return n.enqueue(task.Id, types.EventTypeTask, task, terminal(task.Status.State))
+}
+
+// OnEvent queues status and artifact updates of tasks that have a push notification config
+func (n *Notifier) OnEvent(e types.Event) {
+	switch e.Type() {
+	case types.EventTypeStatusUpdate, types.EventTypeArtifactUpdate:
+	default:
+		return
+	}
+	status, ok := e.(*types.TaskStatusUpdateEvent)
+	last := ok && terminal(status.Status.State)
+	if err := n.enqueue(e.GetTaskId(), e.Type(), e, last); err != nil {
+		slog.Error("Failed to queue push notification", "task_id", e.GetTaskId(), "error", err)
+	}
+}
+
+// Deliveries returns the recorded deliveries, newest first, optionally only those of one task
+func (n *Notifier) Deliveries(taskId string) []Delivery {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	var deliveries []Delivery
+	for i := len(n.log) - 1; i >= 0; i-- {
+		if taskId == "" || n.log[i].TaskID == taskId {
+			deliveries = append(deliveries, *n.log[i])
+		}
+	}
+	return deliveries
+}
+
+// Wait blocks until queued deliveries finished or the context ends
+func (n *Notifier) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DeliveriesHandler serves the delivery log as JSON, filtered by the task_id query parameter
+func (n *Notifier) DeliveriesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(n.Deliveries(r.URL.Query().Get("task_id"))); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+func (n *Notifier) enqueue(taskId string, eventType string, payload any, last bool) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	config, ok := n.configs[taskId]
+	if !ok {
+		return nil
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s notification: %w", eventType, err)
+	}
+
+	n.sequence++
+	delivery := &Delivery{
+		ID:        fmt.Sprintf("%d-%s", n.sequence, taskId),
+		TaskID:    taskId,
+		URL:       config.URL,
+		Event:     eventType,
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	n.record(delivery)
+
+	queue := append(n.pending[taskId], &notification{delivery: delivery, config: *config, body: body, last: last})
+	if len(queue) > n.config.MaxPending {
+		dropped := queue[0]
+		dropped.delivery.Status = StatusDropped
+		dropped.delivery.Error = "too many pending notifications for task"
+		queue = queue[1:]
+	}
+	_, running := n.pending[taskId]
+	n.pending[taskId] = queue
+	if !running {
+		n.wg.Add(1)
+		go n.drain(taskId)
+	}
+	return nil
+}
+
+// drain delivers the pending notifications of a task in order until none are left
+func (n *Notifier) drain(taskId string) {
+	defer n.wg.Done()
+	for {
+		n.mu.Lock()
+		queue := n.pending[taskId]
+		if len(queue) == 0 {
+			delete(n.pending, taskId)
+			n.mu.Unlock()
+			return
+		}
+		next := queue[0]
+		n.pending[taskId] = queue[1:]
+		n.mu.Unlock()
+
+		n.deliver(next)
+		// The config of a finished task is not needed anymore, keeping it would grow the map forever
+		if next.last {
+			n.Delete(context.Background(), taskId)
+		}
+	}
+}
+
+// terminal reports whether a task in this state never changes again
+func terminal(state types.TaskState) bool {
+	switch state {
+	case types.COMPLETED, types.CANCELED, types.FAILED, types.REJECTED:
+		return true
+	}
+	return false
+}
+
+func (n *Notifier) deliver(notification *notification) {
+	backoff := n.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		statusCode, retry, err := n.attempt(notification)
+
+		n.mu.Lock()
+		delivery := notification.delivery
+		delivery.Attempts = attempt
+		delivery.StatusCode = statusCode
+		if err == nil {
+			now := time.Now()
+			delivery.Status = StatusDelivered
+			delivery.Error = ""
+			delivery.CompletedAt = &now
+			n.mu.Unlock()
+			return
+		}
+		delivery.Error = err.Error()
+		if !retry || attempt >= n.config.MaxAttempts {
+			now := time.Now()
+			delivery.Status = StatusFailed
+			delivery.CompletedAt = &now
+			n.mu.Unlock()
+			slog.Warn("Push notification failed", "task_id", delivery.TaskID, "delivery", delivery.ID, "url", delivery.URL, "attempts", attempt, "error", err)
+			return
+		}
+		n.mu.Unlock()
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > n.config.MaxBackoff {
+			backoff = n.config.MaxBackoff
+		}
+	}
+}
+
+// attempt sends a notification once and reports whether a failure is worth retrying
+func (n *Notifier) attempt(notification *notification) (int, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notification.config.URL, bytes.NewReader(notification.body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, notification.delivery.Event)
+	req.Header.Set(HeaderDelivery, notification.delivery.ID)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if n.config.SigningSecret != "" {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(n.config.SigningSecret, timestamp, notification.body))
+	}
+	if auth := notification.config.Authentication; auth != nil && auth.Credentials != "" {
+		for _, scheme := range auth.Schemes {
+			if strings.EqualFold(scheme, "bearer") {
+				req.Header.Set("Authorization", "Bearer "+auth.Credentials)
+				break
+			}
+		}
+	}
+
+	client := n.client
+	if n.allowed(req.URL.Hostname()) {
+		client = n.trusted
+	}
+	resp, err := client.Do(req)
+	if errors.Is(err, errInternalAddress) {
+		return 0, false, err
+	}
+	if err != nil {
+		return 0, true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return resp.StatusCode, retry, fmt.Errorf("webhook responded with %s", resp.Status)
+}
+
+// record appends a delivery to the log, dropping the oldest entries beyond the log size
+func (n *Notifier) record(delivery *Delivery) {
+	n.log = append(n.log, delivery)
+	if len(n.log) > n.config.LogSize {
+		n.log = n.log[len(n.log)-n.config.LogSize:]
+	}
+}
+
+func (n *Notifier) validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("push notification url must be an absolute http(s) URL, got %q", value)
+	}
+	if n.allowed(u.Hostname()) {
+		return nil
+	}
+	if len(n.config.AllowedHosts) > 0 {
+		return fmt.Errorf("push notification host %s is not allowed", u.Hostname())
+	}
+	// Host names are checked when connecting, once they are resolved
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && internal(addr) {
+		return fmt.Errorf("push notification host %s is an internal address", u.Hostname())
+	}
+	return nil
+}
+
+// allowed reports whether a host is listed in AllowedHosts
+func (n *Notifier) allowed(host string) bool {
+	for _, allowed := range n.config.AllowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+var errInternalAddress = errors.New("connecting to internal addresses is not allowed")
+
+// publicClient returns an HTTP client that only connects to public addresses and does not follow redirects
+func publicClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		// Checking the resolved address rather than the URL also covers names resolving to internal addresses
+		Control: func(network, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if internal(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errInternalAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect on our behalf, past the address check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport, CheckRedirect: refuseRedirect}
+}
+
+// internal reports whether an address belongs to this host or a private network
+func internal(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsUnspecified() || addr.IsMulticast()
+}
+
+// refuseRedirect keeps a webhook from sending the notification on to another host
+func refuseRedirect(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// Sign computes the hex encoded HMAC-SHA256 of "timestamp.body", receivers recompute it to verify a payload
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,161 @@
+package push
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// receiver is a webhook recording the requests it receives
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newReceiver(t *testing.T, handler http.HandlerFunc) *receiver {
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.mu.Unlock()
+		if handler != nil {
+			handler(w, req)
+		}
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// send delivers a completed task to url and returns the recorded delivery
+func send(t *testing.T, n *Notifier, taskID, url string) Delivery {
+	t.Helper()
+	ctx := context.Background()
+	if err := n.SetInfo(ctx, taskID, &types.PushNotificationConfig{URL: url}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.SendNotification(&types.Task{Id: taskID, Status: types.TaskStatus{State: types.COMPLETED}}); err != nil {
+		t.Fatal(err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := n.Wait(waitCtx); err != nil {
+		t.Fatal(err)
+	}
+	return n.Deliveries(taskID)[0]
+}
+
+func TestInternalAddressesAreRefused(t *testing.T) {
+	webhook := newReceiver(t, nil)
+	port := strings.TrimPrefix(webhook.URL, "http://127.0.0.1:")
+	n := NewNotifier(Config{SigningSecret: "secret", MaxAttempts: 1}, nil)
+
+	for _, target := range []string{webhook.URL, "http://[::1]:" + port, "http://10.0.0.1/hook", "http://169.254.169.254/latest/meta-data"} {
+		if err := n.SetInfo(context.Background(), "task", &types.PushNotificationConfig{URL: target}); err == nil {
+			t.Errorf("%s accepted", target)
+		}
+	}
+
+	// A name resolving to an internal address is refused when connecting, without retries
+	delivery := send(t, n, "task-1", "http://localhost:"+port+"/hook")
+	if delivery.Status != StatusFailed || delivery.Attempts != 1 || !strings.Contains(delivery.Error, "internal addresses") {
+		t.Errorf("delivery %+v, want it refused", delivery)
+	}
+	if webhook.count() != 0 {
+		t.Errorf("%d requests reached the internal webhook", webhook.count())
+	}
+}
+
+func TestAllowedHostsMayBeInternal(t *testing.T) {
+	webhook := newReceiver(t, nil)
+	n := NewNotifier(Config{SigningSecret: "secret", AllowedHosts: []string{"127.0.0.1"}}, nil)
+
+	if err := n.SetInfo(context.Background(), "task", &types.PushNotificationConfig{URL: "https://example.com/hook"}); err == nil {
+		t.Error("host outside the allowed hosts accepted")
+	}
+
+	delivery := send(t, n, "task-1", webhook.URL+"/hook")
+	if delivery.Status != StatusDelivered {
+		t.Fatalf("delivery %+v", delivery)
+	}
+	req := webhook.requests[0]
+	timestamp := req.Header.Get(HeaderTimestamp)
+	if req.Header.Get(HeaderSignature) == "" || !strings.HasPrefix(req.Header.Get(HeaderSignature), "sha256=") || timestamp == "" {
+		t.Errorf("unsigned notification, headers %v", req.Header)
+	}
+}
+
+func TestRedirectsAreNotFollowed(t *testing.T) {
+	internal := newReceiver(t, nil)
+	webhook := newReceiver(t, func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, internal.URL+"/admin", http.StatusFound)
+	})
+	host, _ := url.Parse(webhook.URL)
+	n := NewNotifier(Config{SigningSecret: "secret", AllowedHosts: []string{host.Hostname()}, MaxAttempts: 1}, nil)
+
+	// Both test servers listen on 127.0.0.1, the redirect would be followed to the allowed host otherwise
+	delivery := send(t, n, "task-1", webhook.URL+"/hook")
+	if delivery.Status != StatusFailed || delivery.StatusCode != http.StatusFound {
+		t.Errorf("delivery %+v, want the redirect reported as failure", delivery)
+	}
+	if internal.count() != 0 {
+		t.Error("redirect followed")
+	}
+}
+
+func TestConfigRemovedAfterFinalNotification(t *testing.T) {
+	webhook := newReceiver(t, nil)
+	n := NewNotifier(Config{SigningSecret: "secret", AllowedHosts: []string{"127.0.0.1"}}, nil)
+	ctx := context.Background()
+	if err := n.SetInfo(ctx, "task-1", &types.PushNotificationConfig{URL: webhook.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	wait := func() {
+		t.Helper()
+		waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := n.Wait(waitCtx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status := func(state types.TaskState) *types.TaskStatusUpdateEvent {
+		return &types.TaskStatusUpdateEvent{TaskId: "task-1", Kind: "status-update", Status: types.TaskStatus{State: state}, Final: state != types.WORKING}
+	}
+
+	// An interrupted task may continue, its config is kept
+	n.OnEvent(status(types.WORKING))
+	n.OnEvent(status(types.InputRequired))
+	wait()
+	if config, _ := n.GetInfo(ctx, "task-1"); config == nil {
+		t.Fatal("config removed before the task finished")
+	}
+
+	n.OnEvent(status(types.COMPLETED))
+	wait()
+	if config, _ := n.GetInfo(ctx, "task-1"); config != nil {
+		t.Errorf("config %+v kept after the final notification", config)
+	}
+	if webhook.count() != 3 {
+		t.Errorf("%d notifications delivered, want 3", webhook.count())
+	}
+	// Later notifications of the finished task go nowhere
+	n.SendNotification(&types.Task{Id: "task-1", Status: types.TaskStatus{State: types.COMPLETED}})
+	wait()
+	if webhook.count() != 3 {
+		t.Errorf("%d notifications delivered after the config was removed", webhook.count())
+	}
+}
@@ -2,69 +2,731 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/yeeaiclub/a2a-go/sdk/server/event"
+	"github.com/yeeaiclub/a2a-go/sdk/server/execution"
+	"github.com/yeeaiclub/a2a-go/sdk/server/tasks"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
 )
 
+const (
+	defaultQueueCapacity   = 10
+	defaultQueueIdleTTL    = 10 * time.Minute
+	defaultDeliveryTimeout = time.Second
+	defaultEventLogSize    = 1000
+	defaultEventLogTTL     = 10 * time.Minute
+	defaultAbortGrace      = 5 * time.Second
+	// abortRecordTime is the time an abandoned execution gets to record its status after the abort grace
+	abortRecordTime = time.Second
+)
+
+// ErrShuttingDown is the cancellation cause of executions aborted by Drain
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// ErrTaskCanceled is the cancellation cause of executions stopped by tasks/cancel
+var ErrTaskCanceled = errors.New("task canceled by the client")
+
+// QueueOption configures a QueueManager
+type QueueOption func(q *QueueManager)
+
+// WithQueueCapacity sets the buffer size of every queue
+func WithQueueCapacity(capacity uint) QueueOption {
+	return func(q *QueueManager) {
+		q.capacity = capacity
+	}
+}
+
+// WithIdleTTL sets how long a queue may see no events before it is evicted
+func WithIdleTTL(ttl time.Duration) QueueOption {
+	return func(q *QueueManager) {
+		q.idleTTL = ttl
+	}
+}
+
+// WithDeliveryTimeout sets how long a full queue is retried before an event is dropped for it
+func WithDeliveryTimeout(timeout time.Duration) QueueOption {
+	return func(q *QueueManager) {
+		q.deliveryTimeout = timeout
+	}
+}
+
+// WithEventLog sets how many events are retained per task for replay and how long after the execution ended
+func WithEventLog(size int, ttl time.Duration) QueueOption {
+	return func(q *QueueManager) {
+		q.logSize = size
+		q.logTTL = ttl
+	}
+}
+
+// EventListener is told about every event published by a wrapped executor
+type EventListener interface {
+	OnEvent(e types.Event)
+}
+
+// WithAbortGrace sets how long an execution canceled by Drain may take to stop before it is abandoned
+func WithAbortGrace(grace time.Duration) QueueOption {
+	return func(q *QueueManager) {
+		q.abortGrace = grace
+	}
+}
+
+// WithEventListener adds a listener for published events, listeners must not block
+func WithEventListener(listener EventListener) QueueOption {
+	return func(q *QueueManager) {
+		q.listeners = append(q.listeners, listener)
+	}
+}
+
+// taskQueues holds the primary queue of a task, the taps subscribed to it and its running executions.
+// mu guards the fields, the queues themselves are written and closed under the lock of their tap.
+type taskQueues struct {
+	mu           sync.Mutex
+	primary      *tap
+	taps         []*tap
+	runs         map[*taskRun]context.CancelCauseFunc
+	finished     bool
+	createdAt    time.Time
+	lastActivity time.Time
+	finishedAt   time.Time
+	events       int
+	dropped      int
+
+	// log retains the published events for replay, logOffset is the index of its first event
+	log       []types.Event
+	logOffset int
+}
+
+// tap is a queue receiving the events of a task. Every send and the close happen under mu, so the
+// queue is never written after it was closed, and a full queue is retried without blocking the others.
+type tap struct {
+	mu      sync.Mutex
+	queue   *event.Queue
+	stalled bool
+	closed  bool
+}
+
+// taskRun is a running execution of a task. Its events go to its own output queue, the queue the
+// handler consumes for the request, and to the taps of the task.
+type taskRun struct {
+	entry  *taskQueues
+	output *tap
+}
+
+// QueueInfo describes a live queue for debugging
+type QueueInfo struct {
+	TaskID       string    `json:"task_id"`
+	Running      bool      `json:"running"`
+	Finished     bool      `json:"finished"`
+	Taps         int       `json:"taps"`
+	StalledTaps  int       `json:"stalled_taps"`
+	Events       int       `json:"events"`
+	Dropped      int       `json:"dropped"`
+	Retained     int       `json:"retained"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// QueueManager manages per-task event queues.
+// Executors wrapped with Wrap publish to a private source queue which is fanned out
+// to the queue of the request that started the execution and every tap, so each subscriber receives all events.
 type QueueManager struct {
-	queues map[string]*event.Queue
+	queues map[string]*taskQueues
 	mutex  sync.RWMutex
+
+	capacity        uint
+	idleTTL         time.Duration
+	deliveryTimeout time.Duration
+	logSize         int
+	logTTL          time.Duration
+	listeners       []EventListener
+	abortGrace      time.Duration
+	draining        bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
-func NewQueueManager() *QueueManager {
-	return &QueueManager{
-		queues: make(map[string]*event.Queue),
+func NewQueueManager(opts ...QueueOption) *QueueManager {
+	q := &QueueManager{
+		queues:          make(map[string]*taskQueues),
+		capacity:        defaultQueueCapacity,
+		idleTTL:         defaultQueueIdleTTL,
+		deliveryTimeout: defaultDeliveryTimeout,
+		logSize:         defaultEventLogSize,
+		logTTL:          defaultEventLogTTL,
+		abortGrace:      defaultAbortGrace,
+		stop:            make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(q)
 	}
+	if q.idleTTL > 0 || q.logTTL > 0 {
+		go q.janitor()
+	}
+	return q
 }
 
 func (q *QueueManager) Add(ctx context.Context, taskId string, queue *event.Queue) error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
-	
-	q.queues[taskId] = queue
+
+	if _, exists := q.queues[taskId]; exists {
+		return fmt.Errorf("queue already exists for task %s", taskId)
+	}
+	q.queues[taskId] = newTaskQueues(queue)
 	return nil
 }
 
 func (q *QueueManager) Get(ctx context.Context, taskId string) (*event.Queue, error) {
 	q.mutex.RLock()
 	defer q.mutex.RUnlock()
-	
-	queue, exists := q.queues[taskId]
+
+	entry, exists := q.queues[taskId]
 	if !exists {
 		return nil, fmt.Errorf("queue not found for task %s", taskId)
 	}
-	return queue, nil
+	return entry.primary.queue, nil
 }
 
+// Tap creates a child queue that receives every event published after it was created
 func (q *QueueManager) Tap(ctx context.Context, taskId string) (*event.Queue, error) {
 	q.mutex.RLock()
-	defer q.mutex.RUnlock()
-	
-	queue, exists := q.queues[taskId]
+	entry, exists := q.queues[taskId]
+	q.mutex.RUnlock()
 	if !exists {
 		return nil, fmt.Errorf("queue not found for task %s", taskId)
 	}
-	return queue, nil
+	return q.tap(entry), nil
 }
 
+// Close closes the taps and primary queue of a task and forgets it
 func (q *QueueManager) Close(ctx context.Context, taskId string) error {
 	q.mutex.Lock()
-	defer q.mutex.Unlock()
-	
+	entry, exists := q.queues[taskId]
 	delete(q.queues, taskId)
+	q.mutex.Unlock()
+
+	if exists {
+		entry.mu.Lock()
+		entry.closeTaps()
+		entry.primary.close()
+		entry.mu.Unlock()
+	}
 	return nil
 }
 
 func (q *QueueManager) CreateOrTap(ctx context.Context, taskId string) (*event.Queue, error) {
 	q.mutex.Lock()
-	defer q.mutex.Unlock()
-	
-	queue, exists := q.queues[taskId]
+	entry, exists := q.queues[taskId]
+	if !exists || entry.done() {
+		// A finished entry only serves replays, a new message starts a new execution
+		queue := event.NewQueue(q.capacity)
+		q.queues[taskId] = newTaskQueues(queue)
+		q.mutex.Unlock()
+		return queue, nil
+	}
+	q.mutex.Unlock()
+	return q.tap(entry), nil
+}
+
+// Snapshot returns the live queues ordered by creation time
+func (q *QueueManager) Snapshot() []QueueInfo {
+	entries := q.entries()
+	infos := make([]QueueInfo, 0, len(entries))
+	for taskId, entry := range entries {
+		entry.mu.Lock()
+		info := QueueInfo{
+			TaskID:       taskId,
+			Running:      entry.running(),
+			Finished:     entry.finished,
+			Taps:         len(entry.taps),
+			Events:       entry.events,
+			Dropped:      entry.dropped,
+			Retained:     len(entry.log),
+			CreatedAt:    entry.createdAt,
+			LastActivity: entry.lastActivity,
+		}
+		for _, t := range entry.taps {
+			t.mu.Lock()
+			if t.stalled {
+				info.StalledTaps++
+			}
+			t.mu.Unlock()
+		}
+		entry.mu.Unlock()
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt.Before(infos[j].CreatedAt)
+	})
+	return infos
+}
+
+// Stop ends idle eviction
+func (q *QueueManager) Stop() {
+	q.stopOnce.Do(func() {
+		close(q.stop)
+	})
+}
+
+// Draining reports whether Drain was called, new executions are not accepted anymore
+func (q *QueueManager) Draining() bool {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+	return q.draining
+}
+
+// Drain stops accepting executions and waits for the running ones until ctx ends. The remaining
+// executions are canceled with ErrShuttingDown, which records them as canceled, early enough to stop
+// within the abort grace before the deadline of ctx; they are canceled right away and get the whole
+// grace when the deadline is closer. It returns the IDs of the tasks that were canceled.
+func (q *QueueManager) Drain(ctx context.Context) []string {
+	q.mutex.Lock()
+	q.draining = true
+	q.mutex.Unlock()
+
+	waitCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithDeadline(ctx, deadline.Add(-q.abortGrace-abortRecordTime))
+		defer cancel()
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for len(q.running()) > 0 {
+		select {
+		case <-waitCtx.Done():
+			return q.abort(ctx, ticker)
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Cancel stops the running executions of a task with cause, reporting whether one was running
+func (q *QueueManager) Cancel(taskId string, cause error) bool {
+	q.mutex.RLock()
+	entry, exists := q.queues[taskId]
+	q.mutex.RUnlock()
 	if !exists {
-		queue = event.NewQueue(10)
-		q.queues[taskId] = queue
+		return false
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	for _, cancel := range entry.runs {
+		cancel(cause)
+	}
+	return entry.running()
+}
+
+// abort cancels the running executions and waits until they finished or were abandoned, until the
+// deadline of ctx or for the abort grace when that is later
+func (q *QueueManager) abort(ctx context.Context, ticker *time.Ticker) []string {
+	var canceled []string
+	for taskId, entry := range q.running() {
+		entry.mu.Lock()
+		if entry.running() {
+			for _, cancel := range entry.runs {
+				cancel(ErrShuttingDown)
+			}
+			canceled = append(canceled, taskId)
+		}
+		entry.mu.Unlock()
+	}
+	sort.Strings(canceled)
+	slog.Warn("Canceled running tasks", "count", len(canceled), "task_ids", canceled)
+
+	// The executors abandon their execution after the abort grace, the extra time covers recording it
+	wait := q.abortGrace + abortRecordTime
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) > wait {
+		wait = time.Until(deadline)
+	}
+	deadline := time.After(wait)
+	for len(q.running()) > 0 {
+		select {
+		case <-deadline:
+			slog.Warn("Tasks did not stop in time", "count", len(q.running()))
+			return canceled
+		case <-ticker.C:
+		}
+	}
+	return canceled
+}
+
+// running returns the entries with a running execution
+func (q *QueueManager) running() map[string]*taskQueues {
+	running := make(map[string]*taskQueues)
+	for taskId, entry := range q.entries() {
+		entry.mu.Lock()
+		if entry.running() {
+			running[taskId] = entry
+		}
+		entry.mu.Unlock()
+	}
+	return running
+}
+
+// Wrap returns an executor whose events are fanned out to the queue it is given and all taps of the task.
+// The store is used to keep persisting events once the client that started the task goes away.
+func (q *QueueManager) Wrap(executor execution.AgentExecutor, store tasks.TaskStore) execution.AgentExecutor {
+	return &fanOutExecutor{AgentExecutor: executor, manager: q, store: store}
+}
+
+func (q *QueueManager) tap(entry *taskQueues) *event.Queue {
+	queue := event.NewQueue(q.capacity)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.finished {
+		// The execution already finished, nothing more will be published
+		queue.Close()
+		return queue
+	}
+	entry.taps = append(entry.taps, &tap{queue: queue})
+	return queue
+}
+
+// Resubscribe returns the retained events of a task starting at index from and,
+// while the task is still running, a tap that receives every later event.
+// The replay and the tap are taken under one lock so no event is missed or repeated.
+// ok is false when no event log is retained for the task.
+func (q *QueueManager) Resubscribe(taskId string, from int) (replay []types.Event, next int, live *event.Queue, ok bool) {
+	q.mutex.RLock()
+	entry, exists := q.queues[taskId]
+	q.mutex.RUnlock()
+	if !exists {
+		return nil, 0, nil, false
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if from < entry.logOffset {
+		from = entry.logOffset
+	}
+	if start := from - entry.logOffset; start < len(entry.log) {
+		replay = append(replay, entry.log[start:]...)
+	}
+	next = entry.logOffset + len(entry.log)
+	if entry.finished || !entry.running() {
+		return replay, next, nil, true
+	}
+
+	live = event.NewQueue(q.capacity)
+	entry.taps = append(entry.taps, &tap{queue: live})
+	return replay, next, live, true
+}
+
+// Untap stops delivering events to a tap whose subscriber went away
+func (q *QueueManager) Untap(taskId string, queue *event.Queue) {
+	q.mutex.RLock()
+	entry, exists := q.queues[taskId]
+	q.mutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	entry.mu.Lock()
+	t := entry.untap(queue)
+	entry.mu.Unlock()
+	if t != nil {
+		t.close()
+	}
+}
+
+// start registers a running execution of a task publishing to output, which becomes the primary queue
+// when the task is unknown. A follow-up message to a running task is given a tap by CreateOrTap, that tap
+// is taken out of the taps so only this execution writes to it.
+func (q *QueueManager) start(taskId string, output *event.Queue, cancel context.CancelCauseFunc) *taskRun {
+	q.mutex.Lock()
+	entry, exists := q.queues[taskId]
+	if !exists || entry.done() {
+		entry = newTaskQueues(output)
+		q.queues[taskId] = entry
+	}
+	q.mutex.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	run := &taskRun{entry: entry, output: entry.untap(output)}
+	if run.output == nil && entry.primary.queue == output {
+		run.output = entry.primary
+	}
+	if run.output == nil {
+		run.output = &tap{queue: output}
+	}
+	entry.runs[run] = cancel
+	return run
+}
+
+// publish delivers an event to the output of the execution and every tap. The queues are taken under
+// the entry lock, full ones are retried outside of it so a slow subscriber does not block the task.
+func (q *QueueManager) publish(run *taskRun, e types.StreamEvent) {
+	entry := run.entry
+	entry.mu.Lock()
+	entry.events++
+	entry.lastActivity = time.Now()
+	if e.Event != nil && q.logSize > 0 {
+		entry.log = append(entry.log, e.Event)
+		if len(entry.log) > q.logSize {
+			entry.log = entry.log[1:]
+			entry.logOffset++
+		}
+	}
+	targets := append([]*tap{run.output}, entry.taps...)
+	entry.mu.Unlock()
+
+	dropped := 0
+	for _, t := range targets {
+		if !q.deliver(t, e) {
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		entry.mu.Lock()
+		entry.dropped += dropped
+		entry.mu.Unlock()
+	}
+}
+
+// notify passes a published event to the listeners
+func (q *QueueManager) notify(e types.Event) {
+	if e == nil {
+		return
+	}
+	for _, listener := range q.listeners {
+		listener.OnEvent(e)
+	}
+}
+
+// deliver enqueues an event, retrying a full queue until the delivery timeout unless the tap stalled before.
+// A subscriber that stops reading must not slow down the others, its tap is marked stalled and only gets
+// the events that fit from then on. It reports false when the event was dropped.
+func (q *QueueManager) deliver(t *tap, e types.StreamEvent) bool {
+	deadline := time.Now().Add(q.deliveryTimeout)
+	backoff := time.Millisecond
+	for {
+		sent, retry := t.send(e)
+		if sent {
+			return true
+		}
+		if !retry {
+			return t.isClosed()
+		}
+		if time.Now().After(deadline) {
+			t.mu.Lock()
+			t.stalled = true
+			t.mu.Unlock()
+			return false
+		}
+		time.Sleep(backoff)
+		if backoff < 50*time.Millisecond {
+			backoff *= 2
+		}
+	}
+}
+
+func enqueue(queue *event.Queue, e types.StreamEvent) bool {
+	switch e.Type {
+	case types.EventData:
+		return queue.EnqueueEvent(e.Event)
+	case types.EventDone:
+		return queue.EnqueueDone(e.Event)
+	case types.EventError:
+		return queue.EnqueueError(e.Err)
+	}
+	return false
+}
+
+// finish ends an execution and closes its output before the handler does, so no other publisher can
+// still be writing to it then. Once the last execution ended the taps are closed. The entry stays
+// around until the event log TTL passes so late subscribers can replay it.
+func (q *QueueManager) finish(taskId string, run *taskRun) {
+	run.output.close()
+
+	entry := run.entry
+	entry.mu.Lock()
+	delete(entry.runs, run)
+	finished := !entry.running()
+	if finished {
+		entry.finished = true
+		entry.finishedAt = time.Now()
+		entry.closeTaps()
+	}
+	entry.mu.Unlock()
+
+	if finished && q.logTTL <= 0 {
+		q.remove(taskId, entry)
+	}
+}
+
+// detach marks the output of an execution as stalled once its client went away, events are then
+// only delivered to it while they fit
+func (r *taskRun) detach() {
+	r.output.mu.Lock()
+	r.output.stalled = true
+	r.output.mu.Unlock()
+}
+
+// janitor evicts queues that saw no activity for the idle TTL and event logs past their TTL.
+// Queues of running executions are only reported, since their executor still owns them.
+func (q *QueueManager) janitor() {
+	interval := q.idleTTL / 2
+	if q.logTTL > 0 && (interval <= 0 || q.logTTL/2 < interval) {
+		interval = q.logTTL / 2
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		for taskId, entry := range q.entries() {
+			entry.mu.Lock()
+			if entry.finished {
+				expired := now.Sub(entry.finishedAt) >= q.logTTL
+				entry.mu.Unlock()
+				if expired {
+					q.remove(taskId, entry)
+				}
+				continue
+			}
+			idle := now.Sub(entry.lastActivity)
+			if q.idleTTL <= 0 || idle < q.idleTTL {
+				entry.mu.Unlock()
+				continue
+			}
+			if entry.running() {
+				slog.Warn("Queue of running task has had no events, the executor may be stuck", "task_id", taskId, "idle", idle.Round(time.Second))
+				entry.mu.Unlock()
+				continue
+			}
+			slog.Warn("Evicting idle queue that was never closed", "task_id", taskId, "idle", idle.Round(time.Second), "taps", len(entry.taps))
+			entry.closeTaps()
+			entry.primary.close()
+			entry.mu.Unlock()
+			q.remove(taskId, entry)
+		}
+	}
+}
+
+// entries copies the queue map so entries can be locked without holding the manager lock
+func (q *QueueManager) entries() map[string]*taskQueues {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	entries := make(map[string]*taskQueues, len(q.queues))
+	for taskId, entry := range q.queues {
+		entries[taskId] = entry
+	}
+	return entries
+}
+
+// remove forgets a task unless its queues were replaced in the meantime
+func (q *QueueManager) remove(taskId string, entry *taskQueues) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.queues[taskId] == entry {
+		delete(q.queues, taskId)
+	}
+}
+
+func newTaskQueues(primary *event.Queue) *taskQueues {
+	now := time.Now()
+	return &taskQueues{
+		primary:      &tap{queue: primary},
+		runs:         make(map[*taskRun]context.CancelCauseFunc),
+		createdAt:    now,
+		lastActivity: now,
+	}
+}
+
+// done reports whether the execution of the entry ended
+func (t *taskQueues) done() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.finished
+}
+
+// running reports whether an execution of the entry is running, the lock must be held
+func (t *taskQueues) running() bool {
+	return len(t.runs) > 0
+}
+
+// untap removes the tap of queue and returns it, or nil when queue is no tap. The lock must be held.
+func (t *taskQueues) untap(queue *event.Queue) *tap {
+	for i, tap := range t.taps {
+		if tap.queue == queue {
+			t.taps = append(t.taps[:i], t.taps[i+1:]...)
+			return tap
+		}
+	}
+	return nil
+}
+
+func (t *taskQueues) closeTaps() {
+	for _, tap := range t.taps {
+		tap.close()
+	}
+	t.taps = nil
+}
+
+// send enqueues an event unless the tap was closed, retry reports whether a full queue may be retried
+func (t *tap) send(e types.StreamEvent) (sent bool, retry bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return false, false
+	}
+	if enqueue(t.queue, e) {
+		return true, false
+	}
+	return false, !t.stalled
+}
+
+func (t *tap) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.closed {
+		t.closed = true
+		t.queue.Close()
+	}
+}
+
+func (t *tap) isClosed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
+// queuesHandler serves a snapshot of the live queues as JSON
+func queuesHandler(q *QueueManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(q.Snapshot()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 	}
-	return queue, nil
 }
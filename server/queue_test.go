@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/server/event"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+func statusEvent(taskId string, state types.TaskState) types.StreamEvent {
+	final := state != types.WORKING
+	e := types.StreamEvent{Type: types.EventData, Event: &types.TaskStatusUpdateEvent{
+		TaskId: taskId, Kind: "status-update", Final: final, Status: types.TaskStatus{State: state},
+	}}
+	if final {
+		e.Type = types.EventDone
+	}
+	return e
+}
+
+// states reads the queue until it is closed or a final event arrives and returns the states received
+func states(t *testing.T, queue *event.Queue) []types.TaskState {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got []types.TaskState
+	for e := range queue.Subscribe(ctx) {
+		switch e.Type {
+		case types.EventData, types.EventDone:
+			got = append(got, e.Event.(*types.TaskStatusUpdateEvent).Status.State)
+		case types.EventCanceled:
+			t.Fatal("queue neither closed nor finished")
+		}
+	}
+	return got
+}
+
+// queueInfo returns the snapshot of the queue of a task
+func queueInfo(t *testing.T, q *QueueManager, taskId string) QueueInfo {
+	t.Helper()
+	for _, info := range q.Snapshot() {
+		if info.TaskID == taskId {
+			return info
+		}
+	}
+	t.Fatalf("no queue for %s", taskId)
+	return QueueInfo{}
+}
+
+func TestQueueFanOut(t *testing.T) {
+	q := NewQueueManager(WithIdleTTL(0))
+	defer q.Stop()
+	ctx := context.Background()
+
+	primary, _ := q.CreateOrTap(ctx, "task-1")
+	run := q.start("task-1", primary, func(error) {})
+	first, _ := q.CreateOrTap(ctx, "task-1")
+	q.publish(run, statusEvent("task-1", types.WORKING))
+	// A tap only receives the events published after it was created
+	second, _ := q.Tap(ctx, "task-1")
+	q.publish(run, statusEvent("task-1", types.WORKING))
+	q.publish(run, statusEvent("task-1", types.COMPLETED))
+	q.finish("task-1", run)
+
+	for name, test := range map[string]struct {
+		queue *event.Queue
+		want  int
+	}{"primary": {primary, 3}, "first tap": {first, 3}, "second tap": {second, 2}} {
+		got := states(t, test.queue)
+		if len(got) != test.want || got[len(got)-1] != types.COMPLETED {
+			t.Errorf("%s received %v, want %d events ending completed", name, got, test.want)
+		}
+	}
+
+	// Taps of a finished execution are closed right away
+	late, _ := q.Tap(ctx, "task-1")
+	if got := states(t, late); len(got) != 0 {
+		t.Errorf("late tap received %v", got)
+	}
+	if info := queueInfo(t, q, "task-1"); info.Running || !info.Finished || info.Taps != 0 || info.Events != 3 || info.Retained != 3 {
+		t.Errorf("snapshot %+v", info)
+	}
+}
+
+func TestQueueDropsStalledTap(t *testing.T) {
+	q := NewQueueManager(WithIdleTTL(0), WithQueueCapacity(1), WithDeliveryTimeout(time.Second))
+	defer q.Stop()
+	ctx := context.Background()
+
+	primary, _ := q.CreateOrTap(ctx, "task-1")
+	run := q.start("task-1", primary, func(error) {})
+	stalled, _ := q.Tap(ctx, "task-1")
+	reader, _ := q.Tap(ctx, "task-1")
+	var received []types.TaskState
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range reader.Subscribe(ctx) {
+			if e.Event != nil {
+				received = append(received, e.Event.(*types.TaskStatusUpdateEvent).Status.State)
+			}
+		}
+	}()
+	go func() {
+		for range primary.Subscribe(ctx) {
+		}
+	}()
+
+	// The second event does not fit into the stalled tap, publishing retries it without holding the entry
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		q.publish(run, statusEvent("task-1", types.WORKING))
+		q.publish(run, statusEvent("task-1", types.WORKING))
+	}()
+	time.Sleep(50 * time.Millisecond)
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		q.Snapshot()
+		q.Untap("task-1", event.NewQueue(1))
+	}()
+	select {
+	case <-blocked:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("the queue is locked while an event is retried")
+	}
+	<-published
+
+	// Once stalled the tap only gets what fits, the others are not slowed down anymore
+	start := time.Now()
+	for range 5 {
+		q.publish(run, statusEvent("task-1", types.WORKING))
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("publishing to a stalled tap took %s", elapsed)
+	}
+	q.publish(run, statusEvent("task-1", types.COMPLETED))
+	info := queueInfo(t, q, "task-1")
+	if info.StalledTaps != 1 || info.Dropped != 7 {
+		t.Errorf("snapshot %+v, want 1 stalled tap that missed 7 events", info)
+	}
+	q.finish("task-1", run)
+	<-done
+	if len(received) != 8 || received[7] != types.COMPLETED {
+		t.Errorf("reading tap received %v", received)
+	}
+	if got := states(t, stalled); len(got) != 1 {
+		t.Errorf("stalled tap received %v, want the first event only", got)
+	}
+}
+
+func TestQueueUntapWhileRetrying(t *testing.T) {
+	q := NewQueueManager(WithIdleTTL(0), WithQueueCapacity(1), WithDeliveryTimeout(5*time.Second))
+	defer q.Stop()
+	ctx := context.Background()
+
+	primary, _ := q.CreateOrTap(ctx, "task-1")
+	run := q.start("task-1", primary, func(error) {})
+	go func() {
+		for range primary.Subscribe(ctx) {
+		}
+	}()
+	_, _, live, _ := q.Resubscribe("task-1", 0)
+	q.publish(run, statusEvent("task-1", types.WORKING))
+
+	// The subscriber goes away while the next event waits for room in its full queue
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		q.publish(run, statusEvent("task-1", types.WORKING))
+	}()
+	time.Sleep(50 * time.Millisecond)
+	q.Untap("task-1", live)
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publishing kept retrying a removed tap")
+	}
+	if info := queueInfo(t, q, "task-1"); info.Taps != 0 || info.Dropped != 0 {
+		t.Errorf("snapshot %+v", info)
+	}
+	q.finish("task-1", run)
+}
+
+func TestQueueFollowUpExecution(t *testing.T) {
+	q := NewQueueManager(WithIdleTTL(0))
+	defer q.Stop()
+	ctx := context.Background()
+
+	for i := range 50 {
+		taskId := fmt.Sprintf("task-%d", i)
+		primary, _ := q.CreateOrTap(ctx, taskId)
+		first := q.start(taskId, primary, func(error) {})
+		go func() {
+			for range primary.Subscribe(ctx) {
+			}
+		}()
+
+		// A follow-up message to the running task gets a tap of it, which the second execution owns
+		followUp, _ := q.CreateOrTap(ctx, taskId)
+		second := q.start(taskId, followUp, func(error) {})
+		if info := queueInfo(t, q, taskId); info.Taps != 0 {
+			t.Fatalf("snapshot %+v, the queue of the follow-up is still a tap", info)
+		}
+
+		// The handler closes the queue of the second execution once it returned, while the first
+		// one keeps publishing
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				q.publish(first, statusEvent(taskId, types.WORKING))
+			}
+		}()
+		q.publish(second, statusEvent(taskId, types.COMPLETED))
+		q.finish(taskId, second)
+		followUp.Close()
+		wg.Wait()
+
+		if got := states(t, followUp); len(got) != 1 || got[0] != types.COMPLETED {
+			t.Fatalf("follow-up received %v, want only the events of its execution", got)
+		}
+		if !queueInfo(t, q, taskId).Running {
+			t.Fatal("task stopped running with its first execution still going")
+		}
+		q.publish(first, statusEvent(taskId, types.COMPLETED))
+		q.finish(taskId, first)
+	}
+}
+
+func TestQueueEviction(t *testing.T) {
+	q := NewQueueManager(WithIdleTTL(10*time.Millisecond), WithEventLog(10, 10*time.Millisecond))
+	defer q.Stop()
+	ctx := context.Background()
+
+	// A queue that was never closed, one of a finished execution and one of a running execution
+	abandoned, _ := q.CreateOrTap(ctx, "abandoned")
+	finished, _ := q.CreateOrTap(ctx, "finished")
+	run := q.start("finished", finished, func(error) {})
+	q.publish(run, statusEvent("finished", types.COMPLETED))
+	q.finish("finished", run)
+	running, _ := q.CreateOrTap(ctx, "running")
+	q.start("running", running, func(error) {})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(q.Snapshot()) > 1 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	infos := q.Snapshot()
+	if len(infos) != 1 || infos[0].TaskID != "running" {
+		t.Fatalf("queues %+v, want only the running one kept", infos)
+	}
+	if got := states(t, abandoned); len(got) != 0 {
+		t.Errorf("evicted queue received %v", got)
+	}
+	if replay, _, _, ok := q.Resubscribe("finished", 0); ok || replay != nil {
+		t.Error("event log kept past its TTL")
+	}
+}
+
+func TestQueueSnapshot(t *testing.T) {
+	q := NewQueueManager(WithIdleTTL(0), WithEventLog(2, time.Minute))
+	defer q.Stop()
+	ctx := context.Background()
+
+	for _, taskId := range []string{"task-b", "task-a"} {
+		queue, _ := q.CreateOrTap(ctx, taskId)
+		run := q.start(taskId, queue, func(error) {})
+		q.Tap(ctx, taskId)
+		for range 3 {
+			q.publish(run, statusEvent(taskId, types.WORKING))
+		}
+		if taskId == "task-b" {
+			q.publish(run, statusEvent(taskId, types.COMPLETED))
+			q.finish(taskId, run)
+		}
+	}
+
+	infos := q.Snapshot()
+	if len(infos) != 2 || infos[0].TaskID != "task-b" || infos[1].TaskID != "task-a" {
+		t.Fatalf("snapshot %+v, want the queues in creation order", infos)
+	}
+	want := []QueueInfo{
+		{TaskID: "task-b", Finished: true, Events: 4, Retained: 2},
+		{TaskID: "task-a", Running: true, Taps: 1, Events: 3, Retained: 2},
+	}
+	for i, info := range infos {
+		info.CreatedAt, info.LastActivity = time.Time{}, time.Time{}
+		if info != want[i] {
+			t.Errorf("queue %d %+v, want %+v", i, info, want[i])
+		}
+	}
+}
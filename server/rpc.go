@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/yeeaiclub/a2a-go/sdk/server/tasks"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// JSON-RPC error codes of the A2A specification. The a2a-go constants for the A2A specific
+// errors are shifted by one, so the codes are defined here.
+const (
+	codeParseError                   types.ErrorCode = -32700
+	codeInvalidRequest               types.ErrorCode = -32600
+	codeMethodNotFound               types.ErrorCode = -32601
+	codeInvalidParams                types.ErrorCode = -32602
+	codeInternalError                types.ErrorCode = -32603
+	codeTaskNotFound                 types.ErrorCode = -32001
+	codeTaskNotCancelable            types.ErrorCode = -32002
+	codePushNotificationNotSupported types.ErrorCode = -32003
+)
+
+// maxRPCBodyBytes bounds a JSON-RPC request, files are inlined into messages so it is generous
+const maxRPCBodyBytes = 16 << 20
+
+// rpcMethods are the JSON-RPC methods served by the agent
+var rpcMethods = map[string]bool{
+	types.MethodMessageSend:         true,
+	types.MethodMessageStream:       true,
+	types.MethodTasksGet:            true,
+	types.MethodTasksCancel:         true,
+	types.MethodTasksResubscribe:    true,
+	types.MethodPushNotificationGet: true,
+	types.MethodPushNotificationSet: true,
+}
+
+// rpcEnvelope is a JSON-RPC request as sent by the client, keeping the id as it was sent
+type rpcEnvelope struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// rpcGuard validates JSON-RPC requests before the a2a-go server handles them and fixes its responses:
+//   - malformed requests, unknown methods, missing params and unknown tasks are answered with
+//     the JSON-RPC and A2A error codes instead of an internal error, an empty response or a panic
+//   - numeric ids are accepted, the server only supports string ids, and echoed back unchanged
+//   - errors in event streams get the response envelope and the request id
+func rpcGuard(next http.Handler, store tasks.TaskStore, pushEnabled bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRPCBodyBytes))
+		if err != nil {
+			writeRPCError(w, nil, codeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+			return
+		}
+
+		var request rpcEnvelope
+		if err := json.Unmarshal(body, &request); err != nil {
+			writeRPCError(w, nil, codeParseError, fmt.Sprintf("Parse error: %v", err))
+			return
+		}
+		id, ok := requestId(request.Id)
+		if !ok {
+			writeRPCError(w, nil, codeInvalidRequest, "Invalid request: id must be a string, a number or null")
+			return
+		}
+		if request.JSONRPC != types.Version || request.Method == "" {
+			writeRPCError(w, id, codeInvalidRequest, `Invalid request: jsonrpc must be "2.0" and method is required`)
+			return
+		}
+		if !rpcMethods[request.Method] {
+			writeRPCError(w, id, codeMethodNotFound, fmt.Sprintf("Method not found: %s", request.Method))
+			return
+		}
+		if code, message := checkParams(r, store, pushEnabled, request); code != 0 {
+			writeRPCError(w, id, code, message)
+			return
+		}
+
+		// The server decodes the id as a string, a placeholder stands in for any other id
+		if !isString(request.Id) {
+			request.Id = json.RawMessage(`"` + idPlaceholder + `"`)
+			body, err = json.Marshal(request)
+			if err != nil {
+				writeRPCError(w, id, codeInternalError, "Internal error")
+				return
+			}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		writer := &rpcResponseWriter{ResponseWriter: w, id: id}
+		next.ServeHTTP(writer, r)
+		writer.flushLine()
+	})
+}
+
+// idPlaceholder replaces ids that are not strings while the server handles the request
+const idPlaceholder = "a2a-request-id"
+
+// requestId returns the id to echo in responses, null for notifications and requests without an id
+func requestId(raw json.RawMessage) (json.RawMessage, bool) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return json.RawMessage("null"), true
+	}
+	switch trimmed[0] {
+	case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return trimmed, true
+	}
+	return nil, false
+}
+
+func isString(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '"'
+}
+
+// checkParams validates the params of a known method and the task they refer to,
+// returning a zero code when the request can be handled
+func checkParams(r *http.Request, store tasks.TaskStore, pushEnabled bool, request rpcEnvelope) (types.ErrorCode, string) {
+	var taskId string
+	switch request.Method {
+	case types.MethodMessageSend, types.MethodMessageStream:
+		var params types.MessageSendParam
+		if err := decodeParams(request.Params, &params); err != nil {
+			return codeInvalidParams, fmt.Sprintf("Invalid params: %v", err)
+		}
+		if params.Message == nil || len(params.Message.Parts) == 0 {
+			return codeInvalidParams, "Invalid params: message with at least one part is required"
+		}
+		if params.Message.Role != types.User {
+			return codeInvalidParams, fmt.Sprintf("Invalid params: message role must be %q", types.User)
+		}
+		if params.Configuration != nil && params.Configuration.PushNotificationConfig != nil && !pushEnabled {
+			return codePushNotificationNotSupported, "Push notifications are not supported"
+		}
+		return 0, ""
+	case types.MethodPushNotificationSet:
+		var params types.TaskPushNotificationConfig
+		if err := decodeParams(request.Params, &params); err != nil {
+			return codeInvalidParams, fmt.Sprintf("Invalid params: %v", err)
+		}
+		if !pushEnabled {
+			return codePushNotificationNotSupported, "Push notifications are not supported"
+		}
+		if params.Config == nil {
+			return codeInvalidParams, "Invalid params: config is required"
+		}
+		taskId = params.TaskId
+	case types.MethodPushNotificationGet:
+		if !pushEnabled {
+			return codePushNotificationNotSupported, "Push notifications are not supported"
+		}
+		fallthrough
+	default:
+		var params types.TaskIdParams
+		if err := decodeParams(request.Params, &params); err != nil {
+			return codeInvalidParams, fmt.Sprintf("Invalid params: %v", err)
+		}
+		taskId = params.Id
+	}
+
+	if taskId == "" {
+		return codeInvalidParams, "Invalid params: task id is required"
+	}
+	task, err := store.Get(r.Context(), taskId)
+	if err != nil {
+		return codeInternalError, "Internal error"
+	}
+	if task == nil {
+		return codeTaskNotFound, fmt.Sprintf("Task not found: %s", taskId)
+	}
+	if request.Method == types.MethodTasksCancel && finished(task) {
+		return codeTaskNotCancelable, fmt.Sprintf("Task cannot be canceled: %s is %s", taskId, task.Status.State)
+	}
+	return 0, ""
+}
+
+// decodeParams decodes the params object, which every method requires
+func decodeParams(raw json.RawMessage, params any) error {
+	if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return fmt.Errorf("params are required")
+	}
+	return json.Unmarshal(raw, params)
+}
+
+// rpcError is a JSON-RPC error response with the id as the client sent it
+type rpcError struct {
+	JSONRPC string              `json:"jsonrpc"`
+	Id      json.RawMessage     `json:"id"`
+	Error   *types.JSONRPCError `json:"error"`
+}
+
+func writeRPCError(w http.ResponseWriter, id json.RawMessage, code types.ErrorCode, message string) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rpcError{
+		JSONRPC: types.Version,
+		Id:      id,
+		Error:   &types.JSONRPCError{Code: code, Message: message},
+	})
+}
+
+// rpcResponseWriter rewrites the responses of the server line by line, every line is one
+// JSON-RPC response, the single one of a call or an event of a stream
+type rpcResponseWriter struct {
+	http.ResponseWriter
+	id      json.RawMessage
+	pending []byte
+}
+
+func (w *rpcResponseWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			return len(p), nil
+		}
+		line := w.pending[:end+1]
+		w.pending = w.pending[end+1:]
+		if _, err := w.ResponseWriter.Write(w.rewrite(line)); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush writes a pending partial line and flushes the underlying writer, which streams rely on
+func (w *rpcResponseWriter) Flush() {
+	w.flushLine()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *rpcResponseWriter) flushLine() {
+	if len(w.pending) == 0 {
+		return
+	}
+	line := w.pending
+	w.pending = nil
+	_, _ = w.ResponseWriter.Write(w.rewrite(line))
+}
+
+// rewrite restores the request id in a response and wraps bare errors into a response
+func (w *rpcResponseWriter) rewrite(line []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return line
+	}
+	if _, ok := fields["jsonrpc"]; !ok {
+		if _, isError := fields["code"]; !isError {
+			return line
+		}
+		bare := make(map[string]json.RawMessage, len(fields))
+		for key, value := range fields {
+			bare[key] = value
+		}
+		encodedError, _ := json.Marshal(bare)
+		fields = map[string]json.RawMessage{
+			"jsonrpc": json.RawMessage(`"` + types.Version + `"`),
+			"error":   encodedError,
+		}
+	}
+	fields["id"] = w.id
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return line
+	}
+	return append(rewritten, '\n')
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/server/tasks"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/config"
+	"github.com/yeeaiclub/github-a2a/server/push"
+	"github.com/yeeaiclub/github-a2a/server/schedule"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+)
+
+// newScheduler runs the configured digests as tasks of the agent, see runTask
+func newScheduler(cfg config.Config, taskHandler *TaskHandler, taskStore tasks.TaskStore, notifier *push.Notifier, githubOptions []toolset.GitHubOption) (*schedule.Scheduler, error) {
+	var jobs []schedule.Job
+	for _, spec := range cfg.Schedule.Jobs {
+		job := schedule.Job{
+			Name:      spec.Name,
+			Cron:      spec.Cron,
+			Repos:     spec.Repos,
+			Window:    spec.Window,
+			Prompt:    spec.Prompt,
+			OutputDir: spec.OutputDir,
+		}
+		if spec.Timezone != "" {
+			location, err := time.LoadLocation(spec.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("job %q: %w", spec.Name, err)
+			}
+			job.Location = location
+		}
+		if spec.Webhook != "" {
+			job.Webhook = &types.PushNotificationConfig{URL: spec.Webhook}
+			if spec.WebhookToken != "" {
+				job.Webhook.Authentication = &types.PushNotificationAuthenticationInfo{
+					Schemes:     []string{"Bearer"},
+					Credentials: spec.WebhookToken,
+				}
+			}
+		}
+		jobs = append(jobs, job)
+	}
+
+	opts := []schedule.Option{
+		schedule.WithFingerprinter(toolset.NewGitHubToolset(cfg.GitHub.Token, githubOptions...)),
+	}
+	// A nil *push.Notifier must not end up in the interface, the scheduler checks for nil
+	if notifier != nil {
+		opts = append(opts, schedule.WithNotifier(notifier))
+	}
+	return schedule.New(schedule.Config{Jobs: jobs, StatePath: cfg.Schedule.StatePath}, runTask(taskHandler, taskStore), opts...)
+}
@@ -0,0 +1,163 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression with the five fields minute, hour, day of month, month and
+// day of week. Fields take *, numbers, names, lists, ranges and steps such as */15 or MON-FRI.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set for a * day field, when both day fields are restricted a day matches either
+	domAny, dowAny bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	// Day of week 7 is Sunday like 0
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a five field cron expression or one of @yearly, @monthly, @weekly, @daily and @hourly
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, minute hour day-of-month month day-of-week", expr)
+	}
+
+	var c Cron
+	var err error
+	if c.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if c.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if c.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if c.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if c.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return &c, nil
+}
+
+// Next returns the first time after t the expression matches, in the location of t.
+// It returns the zero time when the expression never matches, such as on February 30.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Every schedule that can match does so within four years, leap days included
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parse returns the bit set of the values a field matches
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		span, step := item, 1
+		if before, after, ok := strings.Cut(item, "/"); ok {
+			parsed, err := strconv.Atoi(after)
+			if err != nil || parsed < 1 {
+				return 0, fmt.Errorf("invalid step %q in cron %s field", after, f.name)
+			}
+			span, step = before, parsed
+		}
+
+		low, high := f.min, f.max
+		if span != "*" {
+			from, to, isRange := strings.Cut(span, "-")
+			var err error
+			if low, err = f.value(from); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = f.value(to); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// A start with a step, such as 5/15, runs to the end of the field
+				high = f.max
+			}
+			if high < low {
+				return 0, fmt.Errorf("invalid range %q in cron %s field", span, f.name)
+			}
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(text string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(text, name) {
+			return i + f.min, nil
+		}
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("invalid value %q in cron %s field, want %d-%d", text, f.name, f.min, f.max)
+	}
+	return value, nil
+}
@@ -0,0 +1,185 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/internal/fakeagent"
+)
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	// 2026-03-06 is a Friday
+	from := time.Date(2026, 3, 6, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", from, time.Date(2026, 3, 6, 8, 45, 0, 0, time.UTC)},
+		{"0 8 * * *", from, time.Date(2026, 3, 7, 8, 0, 0, 0, time.UTC)},
+		{"0 8 * * 1-5", from, time.Date(2026, 3, 9, 8, 0, 0, 0, time.UTC)},
+		{"30 9 * * MON,fri", from, time.Date(2026, 3, 6, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", from, time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", from, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"5/20 10 * * *", from, time.Date(2026, 3, 6, 10, 5, 0, 0, time.UTC)},
+		// Both day fields restricted: the 1st of the month or any Sunday
+		{"0 12 1 * sun", from, time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", from, time.Time{}},
+		// Evaluated in the location of the time, across the switch to summer time on March 29
+		{"0 8 * * *", time.Date(2026, 3, 28, 9, 0, 0, 0, berlin), time.Date(2026, 3, 29, 8, 0, 0, 0, berlin)},
+	}
+	for _, test := range tests {
+		cron, err := ParseCron(test.expr)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		if got := cron.Next(test.from); !got.Equal(test.want) {
+			t.Errorf("%s after %s = %s, want %s", test.expr, test.from, got, test.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "* * * * funday"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("%q accepted", expr)
+		}
+	}
+}
+
+// fakeFingerprints fingerprints the repositories it knows
+type fakeFingerprints map[string]string
+
+func (f fakeFingerprints) RepositoryFingerprint(ctx context.Context, repo string) (string, error) {
+	if fingerprint, ok := f[repo]; ok {
+		return fingerprint, nil
+	}
+	return "", errors.New("404 Not Found")
+}
+
+func TestRunSkipsUnchangedRepositories(t *testing.T) {
+	dir := t.TempDir()
+	agent := &fakeagent.Agent{Answer: "## octo/demo\nTwo commits."}
+	fingerprints := fakeFingerprints{"octo/demo": "a1", "octo/docs": "b1"}
+	notifier := &fakeagent.Notifier{}
+	now := time.Date(2026, 3, 6, 8, 0, 0, 0, time.UTC)
+	config := Config{
+		StatePath: filepath.Join(dir, "state.json"),
+		Jobs: []Job{{
+			Name:      "daily",
+			Cron:      "0 8 * * *",
+			Location:  time.UTC,
+			Repos:     []string{"octo/demo", "octo/docs"},
+			Webhook:   &types.PushNotificationConfig{URL: "https://hooks.example.com/digest"},
+			OutputDir: filepath.Join(dir, "digests"),
+		}},
+	}
+	newScheduler := func() *Scheduler {
+		s, err := New(config, agent.Run, WithFingerprinter(fingerprints), WithNotifier(notifier), WithClock(func() time.Time { return now }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	s := newScheduler()
+	ctx := context.Background()
+
+	run, err := s.RunNow(ctx, "daily")
+	if err != nil || run.Error != "" {
+		t.Fatalf("run %+v, %v", run, err)
+	}
+	if run.TaskID != "task-1" || len(run.Repos) != 2 || len(run.Skipped) != 0 {
+		t.Errorf("first run %+v, want both repositories covered", run)
+	}
+	if prompt := agent.Prompts()[0]; !strings.Contains(prompt, "octo/demo, octo/docs") || !strings.Contains(prompt, "2026-03-05 08:00 UTC") {
+		t.Errorf("prompt %q does not name the repositories and the window", prompt)
+	}
+	if config := notifier.Config("task-1"); len(notifier.Sent()) != 1 || config == nil || config.URL != "https://hooks.example.com/digest" {
+		t.Errorf("webhook deliveries %v with config %v", notifier.Sent(), config)
+	}
+	digest, err := os.ReadFile(filepath.Join(dir, "digests", "daily-20260306-0800.md"))
+	if err != nil || !strings.Contains(string(digest), "Two commits.") {
+		t.Errorf("digest file %q: %v", digest, err)
+	}
+
+	// Nothing changed: no task at all
+	now = now.Add(24 * time.Hour)
+	if run, _ = s.RunNow(ctx, "daily"); run.TaskID != "" || len(run.Skipped) != 2 || len(agent.Prompts()) != 1 {
+		t.Errorf("unchanged run %+v after %d prompts, want it skipped", run, len(agent.Prompts()))
+	}
+
+	// The fingerprints survive a restart, only the changed repository is covered
+	fingerprints["octo/docs"] = "b2"
+	now = now.Add(24 * time.Hour)
+	restarted := newScheduler()
+	if last := restarted.Jobs()[0].LastRun; last == nil || last.TaskID != "" {
+		t.Errorf("last run after a restart %+v", last)
+	}
+	run, _ = restarted.RunNow(ctx, "daily")
+	if run.Error != "" || len(run.Repos) != 1 || run.Repos[0] != "octo/docs" || run.Skipped[0] != "octo/demo" {
+		t.Errorf("run %+v, want only octo/docs covered", run)
+	}
+	if prompt := agent.Prompts()[1]; !strings.Contains(prompt, "octo/docs") || strings.Contains(prompt, "octo/demo") {
+		t.Errorf("prompt %q", prompt)
+	}
+}
+
+func TestRunFailureKeepsChanges(t *testing.T) {
+	agent := &fakeagent.Agent{State: types.FAILED}
+	fingerprints := fakeFingerprints{"octo/demo": "a1"}
+	s, err := New(Config{Jobs: []Job{{
+		Name: "daily", Cron: "@daily", Repos: []string{"octo/demo", "octo/unknown"}, OutputDir: t.TempDir(),
+		Prompt: "{{.Job}}: {{range .Repos}}{{.}} {{end}}",
+	}}}, agent.Run, WithFingerprinter(fingerprints))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	run, _ := s.RunNow(ctx, "daily")
+	if run.Error == "" || run.State != types.FAILED {
+		t.Errorf("run %+v, want the failed task reported", run)
+	}
+	// Repositories that cannot be fingerprinted are covered rather than skipped
+	if prompt := agent.Prompts()[0]; prompt != "daily: octo/demo octo/unknown " {
+		t.Errorf("prompt %q", prompt)
+	}
+
+	agent.State = types.COMPLETED
+	if run, _ = s.RunNow(ctx, "daily"); run.Error != "" || len(run.Repos) != 2 {
+		t.Errorf("run after the failure %+v, want the same changes covered again", run)
+	}
+	if run, _ = s.RunNow(ctx, "daily"); len(run.Repos) != 1 || run.Repos[0] != "octo/unknown" {
+		t.Errorf("third run %+v, want only the repository without fingerprint", run)
+	}
+}
+
+func TestNewValidatesJobs(t *testing.T) {
+	run := (&fakeagent.Agent{}).Run
+	tests := map[string]Job{
+		"cron":     {Name: "a", Cron: "daily", Repos: []string{"o/r"}, OutputDir: "out"},
+		"repos":    {Name: "a", Cron: "@daily", OutputDir: "out"},
+		"delivery": {Name: "a", Cron: "@daily", Repos: []string{"o/r"}},
+		"webhook":  {Name: "a", Cron: "@daily", Repos: []string{"o/r"}, Webhook: &types.PushNotificationConfig{URL: "https://example.com"}},
+		"prompt":   {Name: "a", Cron: "@daily", Repos: []string{"o/r"}, OutputDir: "out", Prompt: "{{.Repos"},
+	}
+	for name, job := range tests {
+		if _, err := New(Config{Jobs: []Job{job}}, run); err == nil {
+			t.Errorf("%s: invalid job accepted", name)
+		}
+	}
+	job := Job{Name: "a", Cron: "@daily", Repos: []string{"o/r"}, OutputDir: "out"}
+	if _, err := New(Config{Jobs: []Job{job, job}}, run); err == nil {
+		t.Error("duplicate job names accepted")
+	}
+}
@@ -0,0 +1,519 @@
+// Package schedule runs digest prompts on cron schedules. Every run is an agent task covering the
+// repositories that changed since the last delivered digest, and its answer is delivered to a push
+// notification webhook, to a markdown file or both.
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// DefaultWindow is the time window of a job that sets none
+const DefaultWindow = 24 * time.Hour
+
+// DefaultPrompt asks for a digest of the changed repositories, prompts are text/template
+// templates executed with PromptData
+const DefaultPrompt = `Summarize what changed in the GitHub repositories {{join .Repos ", "}} between {{.Since.Format "2006-01-02 15:04 MST"}} and {{.Until.Format "2006-01-02 15:04 MST"}}. ` +
+	`Cover new commits, opened, merged and closed pull requests, new and closed issues and failing workflow runs. ` +
+	`Group the summary by repository and start each group with the repository name as a heading.`
+
+// Job is a digest prompt run on a cron schedule
+type Job struct {
+	Name string
+	// Cron is a five field cron expression evaluated in Location, the local time zone when nil
+	Cron     string
+	Location *time.Location
+	// Repos are the "owner/repo" names the digest covers
+	Repos []string
+	// Window is how far back a digest looks, DefaultWindow when zero
+	Window time.Duration
+	// Prompt is a text/template executed with PromptData, DefaultPrompt when empty
+	Prompt string
+	// Webhook receives the finished task as a push notification when set
+	Webhook *types.PushNotificationConfig
+	// OutputDir receives the answer as a markdown file when set
+	OutputDir string
+}
+
+// Config configures the scheduler
+type Config struct {
+	Jobs []Job
+	// StatePath keeps the repository fingerprints of the last delivered digests across restarts,
+	// they are kept in memory only when empty
+	StatePath string
+}
+
+// PromptData is what a prompt template is executed with
+type PromptData struct {
+	// Job is the name of the job
+	Job string
+	// Repos are the repositories that changed since the last digest, Skipped the unchanged ones
+	Repos   []string
+	Skipped []string
+	Since   time.Time
+	Until   time.Time
+	Window  time.Duration
+}
+
+// RunFunc runs a message as an agent task and returns the task once it stopped running
+type RunFunc func(ctx context.Context, message *types.Message) (*types.Task, error)
+
+// Fingerprinter summarizes the state of a repository, a digest is skipped for repositories whose
+// fingerprint did not change since the last delivered one
+type Fingerprinter interface {
+	RepositoryFingerprint(ctx context.Context, repoName string) (string, error)
+}
+
+// Notifier delivers a task to the push notification config registered for it
+type Notifier interface {
+	SetInfo(ctx context.Context, taskId string, config *types.PushNotificationConfig) error
+	SendNotification(task *types.Task) error
+}
+
+// Option configures optional Scheduler dependencies
+type Option func(s *Scheduler)
+
+// WithFingerprinter skips unchanged repositories, every run covers all repositories without it
+func WithFingerprinter(fingerprinter Fingerprinter) Option {
+	return func(s *Scheduler) {
+		s.fingerprinter = fingerprinter
+	}
+}
+
+// WithNotifier delivers digests to the webhooks of the jobs, it is required when a job sets one
+func WithNotifier(notifier Notifier) Option {
+	return func(s *Scheduler) {
+		s.notifier = notifier
+	}
+}
+
+// WithClock replaces time.Now, for tests
+func WithClock(now func() time.Time) Option {
+	return func(s *Scheduler) {
+		s.now = now
+	}
+}
+
+// Run records one run of a job
+type Run struct {
+	Job        string          `json:"job"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	TaskID     string          `json:"task_id,omitempty"`
+	State      types.TaskState `json:"state,omitempty"`
+	// Repos are the repositories the digest covered, Skipped the unchanged ones left out
+	Repos   []string `json:"repos,omitempty"`
+	Skipped []string `json:"skipped,omitempty"`
+	// File is the markdown file the digest was written to
+	File  string `json:"file,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// job is a Job with its parsed expression and template
+type job struct {
+	Job
+	cron   *Cron
+	prompt *template.Template
+
+	running bool
+	next    time.Time
+	last    *Run
+}
+
+// Scheduler runs the jobs when they are due. A job is not started again while its last run is
+// still going, and runs missed while the server was down are not made up.
+type Scheduler struct {
+	run           RunFunc
+	fingerprinter Fingerprinter
+	notifier      Notifier
+	now           func() time.Time
+	state         *state
+
+	mu   sync.Mutex
+	jobs []*job
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	loop     sync.WaitGroup
+	runs     sync.WaitGroup
+}
+
+// New validates the jobs and loads the state, Start begins running them
+func New(config Config, run RunFunc, opts ...Option) (*Scheduler, error) {
+	s := &Scheduler{run: run, now: time.Now, stop: make(chan struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var errs []error
+	names := map[string]bool{}
+	for _, spec := range config.Jobs {
+		j, err := s.newJob(spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("job %q: %w", spec.Name, err))
+			continue
+		}
+		if names[j.Name] {
+			errs = append(errs, fmt.Errorf("job %q is defined twice", j.Name))
+			continue
+		}
+		names[j.Name] = true
+		s.jobs = append(s.jobs, j)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	state, err := loadState(config.StatePath)
+	if err != nil {
+		return nil, err
+	}
+	s.state = state
+	for _, j := range s.jobs {
+		j.last = state.lastRun(j.Name)
+	}
+	return s, nil
+}
+
+func (s *Scheduler) newJob(spec Job) (*job, error) {
+	if spec.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	cron, err := ParseCron(spec.Cron)
+	if err != nil {
+		return nil, err
+	}
+	if len(spec.Repos) == 0 {
+		return nil, errors.New("at least one repository is required")
+	}
+	if spec.Webhook == nil && spec.OutputDir == "" {
+		return nil, errors.New("a webhook or an output directory is required")
+	}
+	if spec.Webhook != nil && s.notifier == nil {
+		return nil, errors.New("delivering to a webhook requires push notifications")
+	}
+	if spec.Location == nil {
+		spec.Location = time.Local
+	}
+	if spec.Window <= 0 {
+		spec.Window = DefaultWindow
+	}
+	if spec.Prompt == "" {
+		spec.Prompt = DefaultPrompt
+	}
+	prompt, err := template.New(spec.Name).Funcs(template.FuncMap{"join": strings.Join}).Parse(spec.Prompt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt: %w", err)
+	}
+	return &job{Job: spec, cron: cron, prompt: prompt}, nil
+}
+
+// Start runs the jobs when they are due until Stop
+func (s *Scheduler) Start() {
+	s.loop.Add(1)
+	go func() {
+		defer s.loop.Done()
+		for {
+			now := s.now()
+			wait := time.Minute
+			s.mu.Lock()
+			for _, j := range s.jobs {
+				if j.next.IsZero() {
+					j.next = j.cron.Next(now.In(j.Location))
+				}
+				if !j.next.IsZero() && !j.next.After(now) {
+					s.startLocked(j)
+					j.next = j.cron.Next(now.In(j.Location))
+				}
+				if !j.next.IsZero() && j.next.Sub(now) < wait {
+					wait = j.next.Sub(now)
+				}
+			}
+			s.mu.Unlock()
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-s.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// startLocked starts a run of the job unless one is going
+func (s *Scheduler) startLocked(j *job) {
+	if j.running {
+		slog.Warn("Skipping scheduled digest, the last run is still going", "job", j.Name)
+		return
+	}
+	j.running = true
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		s.execute(context.Background(), j)
+	}()
+}
+
+// Stop stops starting runs, runs that are going continue, see Wait
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	s.loop.Wait()
+}
+
+// Wait blocks until the runs that are going finished or the context ends
+func (s *Scheduler) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunNow runs a job immediately and returns the record of the run
+func (s *Scheduler) RunNow(ctx context.Context, name string) (*Run, error) {
+	s.mu.Lock()
+	var found *job
+	for _, j := range s.jobs {
+		if j.Name == name {
+			found = j
+		}
+	}
+	if found == nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("no job named %q", name)
+	}
+	if found.running {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("job %q is running", name)
+	}
+	found.running = true
+	s.mu.Unlock()
+	s.runs.Add(1)
+	defer s.runs.Done()
+	return s.execute(ctx, found), nil
+}
+
+// execute runs a job and records the run, the job must be marked running
+func (s *Scheduler) execute(ctx context.Context, j *job) *Run {
+	run := &Run{Job: j.Name, StartedAt: s.now()}
+	logger := slog.With("job", j.Name)
+	if err := s.digest(ctx, j, run); err != nil {
+		run.Error = err.Error()
+		logger.Error("Scheduled digest failed", "task_id", run.TaskID, "error", err)
+	} else if run.TaskID == "" {
+		logger.Info("Scheduled digest skipped, no repository changed", "skipped", run.Skipped)
+	} else {
+		logger.Info("Scheduled digest delivered", "task_id", run.TaskID, "repos", run.Repos, "skipped", run.Skipped)
+	}
+	run.FinishedAt = s.now()
+
+	s.mu.Lock()
+	j.running = false
+	j.last = run
+	s.mu.Unlock()
+	s.state.setLastRun(run)
+	if err := s.state.save(); err != nil {
+		logger.Error("Failed to save the schedule state", "error", err)
+	}
+	return run
+}
+
+// digest covers the changed repositories in a task and delivers its answer. The fingerprints are
+// only updated once the digest was delivered, so failed runs cover the same changes again.
+func (s *Scheduler) digest(ctx context.Context, j *job, run *Run) error {
+	fingerprints := map[string]string{}
+	for _, repo := range j.Repos {
+		if s.fingerprinter == nil {
+			run.Repos = append(run.Repos, repo)
+			continue
+		}
+		fingerprint, err := s.fingerprinter.RepositoryFingerprint(ctx, repo)
+		if err != nil {
+			// Without a fingerprint the repository may have changed
+			slog.Warn("Failed to fingerprint repository, including it in the digest", "job", j.Name, "repo", repo, "error", err)
+			run.Repos = append(run.Repos, repo)
+			continue
+		}
+		if fingerprint == s.state.fingerprint(j.Name, repo) {
+			run.Skipped = append(run.Skipped, repo)
+			continue
+		}
+		fingerprints[repo] = fingerprint
+		run.Repos = append(run.Repos, repo)
+	}
+	if len(run.Repos) == 0 {
+		return nil
+	}
+
+	until := run.StartedAt.In(j.Location)
+	var prompt bytes.Buffer
+	err := j.prompt.Execute(&prompt, PromptData{
+		Job:     j.Name,
+		Repos:   run.Repos,
+		Skipped: run.Skipped,
+		Since:   until.Add(-j.Window),
+		Until:   until,
+		Window:  j.Window,
+	})
+	if err != nil {
+		return fmt.Errorf("render prompt: %w", err)
+	}
+
+	task, err := s.run(ctx, &types.Message{
+		Kind:      "message",
+		MessageID: uuid.NewString(),
+		// The digests of a job share a context, so clients find them together
+		ContextID: "schedule-" + j.Name,
+		Role:      types.User,
+		Parts:     []types.Part{&types.TextPart{Kind: "text", Text: prompt.String()}},
+		Metadata:  map[string]any{"schedule": j.Name, "repos": run.Repos},
+	})
+	if task != nil {
+		run.TaskID = task.Id
+		run.State = task.Status.State
+	}
+	if err != nil {
+		return fmt.Errorf("run digest task: %w", err)
+	}
+	if task.Status.State != types.COMPLETED {
+		return fmt.Errorf("digest task ended %s", task.Status.State)
+	}
+
+	if err := s.deliver(ctx, j, task, run); err != nil {
+		return err
+	}
+	s.state.setFingerprints(j.Name, fingerprints)
+	return nil
+}
+
+// deliver sends the finished task to the webhook and writes its answer to the output directory
+func (s *Scheduler) deliver(ctx context.Context, j *job, task *types.Task, run *Run) error {
+	var errs []error
+	if j.Webhook != nil {
+		if err := s.notifier.SetInfo(ctx, task.Id, j.Webhook); err != nil {
+			errs = append(errs, fmt.Errorf("register webhook: %w", err))
+		} else if err := s.notifier.SendNotification(task); err != nil {
+			errs = append(errs, fmt.Errorf("send webhook notification: %w", err))
+		}
+	}
+	if j.OutputDir != "" {
+		path, err := writeDigest(j, task, run)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		run.File = path
+	}
+	return errors.Join(errs...)
+}
+
+// writeDigest writes the answer of a digest task as markdown, named after the job and the start of the run
+func writeDigest(j *job, task *types.Task, run *Run) (string, error) {
+	if err := os.MkdirAll(j.OutputDir, 0o755); err != nil {
+		return "", fmt.Errorf("create output directory: %w", err)
+	}
+	started := run.StartedAt.In(j.Location)
+	path := filepath.Join(j.OutputDir, fmt.Sprintf("%s-%s.md", j.Name, started.Format("20060102-1504")))
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "# %s\n\n", j.Name)
+	fmt.Fprintf(&out, "%s to %s, task %s\n\n", started.Add(-j.Window).Format("2006-01-02 15:04 MST"), started.Format("2006-01-02 15:04 MST"), task.Id)
+	if len(run.Skipped) > 0 {
+		fmt.Fprintf(&out, "Unchanged: %s\n\n", strings.Join(run.Skipped, ", "))
+	}
+	out.WriteString(strings.TrimSpace(answer(task)))
+	out.WriteString("\n")
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("write digest: %w", err)
+	}
+	return path, nil
+}
+
+// answer returns the text of the task's artifacts, or of its status message when it has none
+func answer(task *types.Task) string {
+	var texts []string
+	for _, artifact := range task.Artifacts {
+		for _, part := range artifact.Parts {
+			if text, ok := part.(*types.TextPart); ok {
+				texts = append(texts, text.Text)
+			}
+		}
+	}
+	if len(texts) == 0 && task.Status.Message != nil {
+		for _, part := range task.Status.Message.Parts {
+			if text, ok := part.(*types.TextPart); ok {
+				texts = append(texts, text.Text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// JobStatus describes a job for the debug endpoint
+type JobStatus struct {
+	Name    string    `json:"name"`
+	Cron    string    `json:"cron"`
+	Repos   []string  `json:"repos"`
+	Running bool      `json:"running"`
+	NextRun time.Time `json:"next_run,omitzero"`
+	LastRun *Run      `json:"last_run,omitempty"`
+}
+
+// Jobs returns the status of every job
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		next := j.next
+		if next.IsZero() {
+			next = j.cron.Next(s.now().In(j.Location))
+		}
+		statuses = append(statuses, JobStatus{
+			Name:    j.Name,
+			Cron:    j.Cron,
+			Repos:   j.Repos,
+			Running: j.running,
+			NextRun: next,
+			LastRun: j.last,
+		})
+	}
+	return statuses
+}
+
+// Handler serves the status of the jobs as JSON
+func (s *Scheduler) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(s.Jobs()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
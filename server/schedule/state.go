@@ -0,0 +1,112 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// state holds the fingerprints of the repositories as of the last delivered digest of every job
+// and the last run of every job, saved to a JSON file when it has a path
+type state struct {
+	path string
+
+	mu   sync.Mutex
+	Jobs map[string]*jobState `json:"jobs"`
+}
+
+type jobState struct {
+	Fingerprints map[string]string `json:"fingerprints,omitempty"`
+	LastRun      *Run              `json:"last_run,omitempty"`
+}
+
+// loadState reads the state file, a missing file is an empty state
+func loadState(path string) (*state, error) {
+	s := &state{path: path, Jobs: map[string]*jobState{}}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read schedule state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parse schedule state %s: %w", path, err)
+	}
+	if s.Jobs == nil {
+		s.Jobs = map[string]*jobState{}
+	}
+	return s, nil
+}
+
+func (s *state) job(name string) *jobState {
+	job, ok := s.Jobs[name]
+	if !ok {
+		job = &jobState{}
+		s.Jobs[name] = job
+	}
+	return job
+}
+
+func (s *state) fingerprint(job string, repo string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.job(job).Fingerprints[repo]
+}
+
+func (s *state) setFingerprints(job string, fingerprints map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.job(job)
+	if stored.Fingerprints == nil {
+		stored.Fingerprints = map[string]string{}
+	}
+	for repo, fingerprint := range fingerprints {
+		stored.Fingerprints[repo] = fingerprint
+	}
+}
+
+func (s *state) lastRun(job string) *Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.job(job).LastRun
+}
+
+func (s *state) setLastRun(run *Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.job(run.Job).LastRun = run
+}
+
+// save writes the state file through a temporary file, so a crash never leaves it half written
+func (s *state) save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/config"
+	"github.com/yeeaiclub/github-a2a/server/schedule"
+	"github.com/yeeaiclub/github-a2a/server/toolset/fakellm"
+)
+
+func TestScheduledDigest(t *testing.T) {
+	dir := t.TempDir()
+	agent := startAgent(t, fakellm.New(fakellm.Reply("## octo/demo\nPull request #7 adds docs.")), func(cfg *config.Config) {
+		cfg.Schedule.Jobs = []config.ScheduleJob{{
+			Name: "daily", Cron: "0 8 * * 1-5", Timezone: "UTC", Repos: []string{"octo/demo"}, OutputDir: dir,
+		}}
+	})
+
+	run, err := agent.app.Scheduler.RunNow(context.Background(), "daily")
+	if err != nil || run.Error != "" {
+		t.Fatalf("run %+v, %v", run, err)
+	}
+
+	// The digest is a task of the agent like any other
+	response := agent.call(t, request(1, types.MethodTasksGet, map[string]any{"id": run.TaskID}))
+	task := checkTask(t, response.Result)
+	if task.Status.State != types.COMPLETED || task.ContextId != "schedule-daily" {
+		t.Errorf("digest task %s in context %s", task.Status.State, task.ContextId)
+	}
+	digest, err := os.ReadFile(run.File)
+	if err != nil || !strings.Contains(string(digest), "Pull request #7 adds docs.") {
+		t.Errorf("digest file %q: %v", digest, err)
+	}
+
+	resp, err := http.Get(agent.debug.URL + "/debug/schedules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var jobs []schedule.JobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].LastRun == nil || jobs[0].LastRun.TaskID != run.TaskID || jobs[0].NextRun.IsZero() {
+		t.Errorf("schedules %+v", jobs)
+	}
+	// The jobs and their prompts are only served on the debug listener
+	public, err := http.Get(agent.URL + "/debug/schedules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	public.Body.Close()
+	if public.StatusCode != http.StatusNotFound {
+		t.Errorf("public /debug/schedules: status %d", public.StatusCode)
+	}
+}
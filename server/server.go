@@ -2,56 +2,154 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/yeeaiclub/a2a-go/sdk/server/handler"
-	"github.com/yeeaiclub/a2a-go/sdk/server/tasks"
-	"github.com/yeeaiclub/a2a-go/sdk/types"
-	"github.com/yeeaiclub/github-a2a/server/toolset"
+	"github.com/yeeaiclub/github-a2a/server/config"
+	"github.com/yeeaiclub/github-a2a/server/logging"
+	"github.com/yeeaiclub/github-a2a/server/metrics"
+	"github.com/yeeaiclub/github-a2a/server/tracing"
 )
 
-var AgentCard = types.AgentCard{
-	Description: "An A2A-compliant agent that provides GitHub capabilities",
-	Version:     "1.0.0",
-	DefaultInputModes: []string{
-		"text",
-	},
-	DefaultOutputModes: []string{
-		"text",
-	},
+func main() {
+	// The eval command evaluates the agent on a dataset instead of serving it
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		os.Exit(runEval(os.Args[2:], os.LookupEnv, os.Stdout, os.Stderr))
+	}
+
+	cfg, options, err := config.LoadOS()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
+	if options.PrintConfig {
+		out, err := cfg.Redacted().YAML()
+		if err != nil {
+			fatal("Failed to encode configuration", err)
+		}
+		os.Stdout.Write(out)
+		if err := cfg.Validate(); err != nil {
+			fatal("Invalid configuration", err)
+		}
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		fatal("Invalid configuration", err)
+	}
+	if err := logging.Setup(logging.Config{Level: cfg.Log.Level, Format: cfg.Log.Format}, os.Stderr); err != nil {
+		fatal("Invalid log configuration", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Enabled:     cfg.Tracing.Enabled,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		ServiceName: cfg.Tracing.ServiceName,
+		Version:     cfg.Agent.Version,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
+	app, err := NewApp(cfg)
+	if err != nil {
+		fatal("Failed to build the agent", err)
+	}
+	defer app.Close()
+	metrics.Registry.MustRegister(newQueueCollector(app.Queues))
+
+	httpServer := &http.Server{
+		Addr:         cfg.Server.Addr,
+		Handler:      app.Handler,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 2)
+	go func() {
+		slog.Info("Starting server", "name", app.Card.Name, "version", app.Card.Version, "addr", httpServer.Addr)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	var debugServer *http.Server
+	if cfg.Server.DebugAddr != "" {
+		debugServer = &http.Server{Addr: cfg.Server.DebugAddr, Handler: app.Debug, ReadTimeout: cfg.Server.ReadTimeout}
+		go func() {
+			slog.Info("Starting debug server", "addr", debugServer.Addr)
+			serveErr <- debugServer.ListenAndServe()
+		}()
+	}
+
+	if app.Scheduler != nil {
+		app.Scheduler.Start()
+	}
+
+	select {
+	case err := <-serveErr:
+		slog.Error("Server stopped", "error", err)
+		return
+	case <-ctx.Done():
+		stop()
+	}
+
+	// New messages are rejected from here on, running tasks get the shutdown timeout to finish
+	if app.Scheduler != nil {
+		app.Scheduler.Stop()
+	}
+	slog.Info("Shutting down, waiting for running tasks", "timeout", cfg.Server.ShutdownTimeout)
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	app.Queues.Drain(drainCtx)
+	cancelDrain()
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancelFlush()
+	if err := httpServer.Shutdown(flushCtx); err != nil {
+		slog.Error("Failed to close open connections", "error", err)
+	}
+	if debugServer != nil {
+		debugServer.Close()
+	}
+	if app.Scheduler != nil {
+		if err := app.Scheduler.Wait(flushCtx); err != nil {
+			slog.Warn("Scheduled digests were not delivered", "error", err)
+		}
+	}
+	if app.Webhooks != nil {
+		if err := app.Webhooks.Wait(flushCtx); err != nil {
+			slog.Warn("Webhook tasks did not finish", "error", err)
+		}
+	}
+	if app.Push != nil {
+		if err := app.Push.Wait(flushCtx); err != nil {
+			slog.Warn("Pending push notifications were not delivered", "error", err)
+		}
+	}
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+	slog.Info("Shutdown complete")
 }
 
-var store = tasks.NewInMemoryTaskStore()
+// flushTimeout bounds closing connections and delivering pending push notifications at shutdown
+const flushTimeout = 10 * time.Second
 
-func main() {
-	// Create agent configuration
-	agentConfig := GithubAgent()
-
-	// Get API key from environment
-	apiKey := os.Getenv("DEEPSEEK_API_KEY")
-	if apiKey == "" {
-		log.Fatal("DEEPSEEK_API_KEY environment variable is required")
-	}
-
-	store.Save(context.Background(), &types.Task{Id: "1"})
-
-	defaultHandler := handler.NewDefaultHandler(
-		store,
-		toolset.NewExecutor(store, &AgentCard, agentConfig.Tools, apiKey, agentConfig.SystemPrompt),
-		handler.WithQueueManger(NewQueueManager()),
-	)
-
-	server := handler.NewServer(
-		"/agent_card",
-		"/api",
-		AgentCard,
-		defaultHandler,
-		handler.WithWriteTimeout(1*time.Minute),
-		handler.WithIdleTimeout(1*time.Minute),
-		handler.WithReadTimeout(1*time.Minute),
-	)
-
-	server.Start(8080)
+// fatal logs the error and exits, deferred cleanups do not run
+func fatal(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, "error", err)
+	} else {
+		slog.Error(msg)
+	}
+	os.Exit(1)
 }
@@ -0,0 +1,326 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	metaBucket     = []byte("meta")
+	tasksBucket    = []byte("tasks")
+	contextsBucket = []byte("contexts")
+	updatedBucket  = []byte("updated")
+
+	schemaVersionKey = []byte("schema_version")
+)
+
+// record is the stored form of a task
+type record struct {
+	Task      *types.Task `json:"task"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// Options configures the bolt task store
+type Options struct {
+	// Retention is how long finished tasks are kept, zero keeps them forever
+	Retention time.Duration
+	// PruneInterval is how often finished tasks past retention are removed
+	PruneInterval time.Duration
+	// CompactOnOpen rewrites the database file on open to reclaim freed pages
+	CompactOnOpen bool
+	// Timeout bounds how long Open waits for the file lock
+	Timeout time.Duration
+}
+
+// BoltTaskStore is a tasks.TaskStore persisted in a bbolt database.
+// Tasks are keyed by ID with secondary indexes on context ID and update time.
+type BoltTaskStore struct {
+	db      *bolt.DB
+	options Options
+	now     func() time.Time
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// OpenBolt opens or creates the database at path and migrates it to the current schema
+func OpenBolt(path string, options Options) (*BoltTaskStore, error) {
+	if options.Timeout == 0 {
+		options.Timeout = 5 * time.Second
+	}
+	if options.PruneInterval == 0 {
+		options.PruneInterval = time.Hour
+	}
+
+	if options.CompactOnOpen {
+		if err := compact(path, options.Timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: options.Timeout})
+	if err != nil {
+		return nil, fmt.Errorf("open task store %s: %w", path, err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &BoltTaskStore{
+		db:      db,
+		options: options,
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+	if options.Retention > 0 {
+		s.wg.Add(1)
+		go s.pruneLoop()
+	}
+	return s, nil
+}
+
+// Save creates or replaces a task and updates its indexes
+func (s *BoltTaskStore) Save(ctx context.Context, task *types.Task) error {
+	if task == nil || task.Id == "" {
+		return errors.New("task id is required")
+	}
+	now := s.now().UTC()
+	data, err := json.Marshal(record{Task: task, UpdatedAt: now})
+	if err != nil {
+		return fmt.Errorf("encode task %s: %w", task.Id, err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		tasks := tx.Bucket(tasksBucket)
+		if previous := tasks.Get([]byte(task.Id)); previous != nil {
+			if err := deleteIndexes(tx, task.Id, previous); err != nil {
+				return err
+			}
+		}
+		if err := tasks.Put([]byte(task.Id), data); err != nil {
+			return err
+		}
+		if task.ContextId != "" {
+			if err := tx.Bucket(contextsBucket).Put(indexKey([]byte(task.ContextId), task.Id), nil); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(updatedBucket).Put(indexKey(timeKey(now), task.Id), nil)
+	})
+}
+
+// Get returns the task with the given ID, or nil when it does not exist
+func (s *BoltTaskStore) Get(ctx context.Context, taskID string) (*types.Task, error) {
+	var task *types.Task
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(tasksBucket).Get([]byte(taskID))
+		if data == nil {
+			return nil
+		}
+		rec, err := decodeRecord(data)
+		if err != nil {
+			return fmt.Errorf("decode task %s: %w", taskID, err)
+		}
+		task = rec.Task
+		return nil
+	})
+	return task, err
+}
+
+// Delete removes a task and its index entries
+func (s *BoltTaskStore) Delete(ctx context.Context, taskID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return deleteTask(tx, taskID)
+	})
+}
+
+// ListByContext returns the tasks of a context ordered by task ID
+func (s *BoltTaskStore) ListByContext(ctx context.Context, contextID string) ([]*types.Task, error) {
+	var result []*types.Task
+	err := s.db.View(func(tx *bolt.Tx) error {
+		tasks := tx.Bucket(tasksBucket)
+		prefix := indexKey([]byte(contextID), "")
+		c := tx.Bucket(contextsBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			taskID := string(k[len(prefix):])
+			data := tasks.Get([]byte(taskID))
+			if data == nil {
+				continue
+			}
+			rec, err := decodeRecord(data)
+			if err != nil {
+				return fmt.Errorf("decode task %s: %w", taskID, err)
+			}
+			result = append(result, rec.Task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Prune removes finished tasks last updated before the cutoff and returns how many were removed
+func (s *BoltTaskStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var expired []string
+		tasks := tx.Bucket(tasksBucket)
+		end := timeKey(before.UTC())
+		c := tx.Bucket(updatedBucket).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k[:len(end)], end) < 0; k, _ = c.Next() {
+			taskID := string(k[len(end)+1:])
+			data := tasks.Get([]byte(taskID))
+			if data == nil {
+				continue
+			}
+			rec, err := decodeRecord(data)
+			if err != nil {
+				return fmt.Errorf("decode task %s: %w", taskID, err)
+			}
+			if finished(rec.Task) {
+				expired = append(expired, taskID)
+			}
+		}
+
+		// Deleting while iterating would skip entries, so collect first
+		for _, taskID := range expired {
+			if err := deleteTask(tx, taskID); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+	return removed, err
+}
+
+// Close stops background pruning and closes the database
+func (s *BoltTaskStore) Close() error {
+	close(s.stop)
+	s.wg.Wait()
+	return s.db.Close()
+}
+
+func (s *BoltTaskStore) pruneLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.options.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := s.Prune(context.Background(), s.now().Add(-s.options.Retention))
+		if err != nil {
+			slog.Error("Failed to prune task store", "error", err)
+		} else if removed > 0 {
+			slog.Info("Pruned finished tasks from task store", "removed", removed)
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// finished reports whether a task reached a terminal state and can be pruned
+func finished(task *types.Task) bool {
+	switch task.Status.State {
+	case types.COMPLETED, types.CANCELED, types.FAILED, types.REJECTED:
+		return true
+	}
+	return false
+}
+
+func deleteTask(tx *bolt.Tx, taskID string) error {
+	tasks := tx.Bucket(tasksBucket)
+	data := tasks.Get([]byte(taskID))
+	if data == nil {
+		return nil
+	}
+	if err := deleteIndexes(tx, taskID, data); err != nil {
+		return err
+	}
+	return tasks.Delete([]byte(taskID))
+}
+
+func deleteIndexes(tx *bolt.Tx, taskID string, data []byte) error {
+	rec, err := decodeRecord(data)
+	if err != nil {
+		return fmt.Errorf("decode task %s: %w", taskID, err)
+	}
+	if rec.Task.ContextId != "" {
+		if err := tx.Bucket(contextsBucket).Delete(indexKey([]byte(rec.Task.ContextId), taskID)); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(updatedBucket).Delete(indexKey(timeKey(rec.UpdatedAt), taskID))
+}
+
+func decodeRecord(data []byte) (record, error) {
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, err
+	}
+	if rec.Task == nil {
+		return rec, errors.New("record has no task")
+	}
+	return rec, nil
+}
+
+// indexKey joins an index value and a task ID with a separator that cannot appear in either
+func indexKey(value []byte, taskID string) []byte {
+	key := make([]byte, 0, len(value)+1+len(taskID))
+	key = append(key, value...)
+	key = append(key, 0)
+	return append(key, taskID...)
+}
+
+// timeKey encodes a time so that byte order matches time order
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// compact rewrites the database into a fresh file, dropping pages freed by deletes
+func compact(path string, timeout time.Duration) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	src, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: timeout, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("open task store %s for compaction: %w", path, err)
+	}
+
+	tmp := path + ".compact"
+	dst, err := bolt.Open(tmp, 0o600, &bolt.Options{Timeout: timeout})
+	if err != nil {
+		src.Close()
+		return fmt.Errorf("create compacted task store: %w", err)
+	}
+	err = bolt.Compact(dst, src, 64<<20)
+	src.Close()
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("compact task store: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+func task(id, contextID string, state types.TaskState) *types.Task {
+	return &types.Task{Id: id, ContextId: contextID, Kind: "task", Status: types.TaskStatus{State: state}}
+}
+
+func openBolt(t *testing.T, path string, options Options) *BoltTaskStore {
+	t.Helper()
+	s, err := OpenBolt(path, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func taskIDs(tasks []*types.Task) string {
+	var ids []string
+	for _, task := range tasks {
+		ids = append(ids, task.Id)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestBoltReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	ctx := context.Background()
+
+	s := openBolt(t, path, Options{})
+	if err := s.Save(ctx, task("a", "ctx-1", types.WORKING)); err != nil {
+		t.Fatal(err)
+	}
+	// Saving again replaces the task, also in the context index
+	if err := s.Save(ctx, task("a", "ctx-2", types.COMPLETED)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(ctx, task("b", "ctx-2", types.WORKING)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openBolt(t, path, Options{})
+	defer s.Close()
+	got, err := s.Get(ctx, "a")
+	if err != nil || got == nil || got.Status.State != types.COMPLETED || got.ContextId != "ctx-2" {
+		t.Fatalf("task after reopen %+v, %v", got, err)
+	}
+	if missing, err := s.Get(ctx, "missing"); missing != nil || err != nil {
+		t.Errorf("missing task %+v, %v", missing, err)
+	}
+
+	for contextID, want := range map[string]string{"ctx-1": "", "ctx-2": "a,b", "ctx-3": ""} {
+		tasks, err := s.ListByContext(ctx, contextID)
+		if err != nil || taskIDs(tasks) != want {
+			t.Errorf("tasks of %s %q, %v, want %q", contextID, taskIDs(tasks), err, want)
+		}
+	}
+
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if tasks, _ := s.ListByContext(ctx, "ctx-2"); taskIDs(tasks) != "b" {
+		t.Errorf("tasks of ctx-2 after delete %q", taskIDs(tasks))
+	}
+}
+
+func TestBoltContextLookupIsExact(t *testing.T) {
+	s := openBolt(t, filepath.Join(t.TempDir(), "tasks.db"), Options{})
+	defer s.Close()
+	ctx := context.Background()
+
+	// A context ID that is a prefix of another does not list the tasks of the longer one
+	for _, saved := range []*types.Task{task("a", "ctx", types.WORKING), task("b", "ctx-long", types.WORKING), task("c", "", types.WORKING)} {
+		if err := s.Save(ctx, saved); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tasks, _ := s.ListByContext(ctx, "ctx"); taskIDs(tasks) != "a" {
+		t.Errorf("tasks of ctx %q", taskIDs(tasks))
+	}
+}
+
+func setSchemaVersion(t *testing.T, path string, version uint64) {
+	t.Helper()
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, version)
+		return meta.Put(schemaVersionKey, value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func schemaVersion(t *testing.T, s *BoltTaskStore) uint64 {
+	t.Helper()
+	var version uint64
+	s.db.View(func(tx *bolt.Tx) error {
+		version = binary.BigEndian.Uint64(tx.Bucket(metaBucket).Get(schemaVersionKey))
+		return nil
+	})
+	return version
+}
+
+func TestBoltMigration(t *testing.T) {
+	ctx := context.Background()
+
+	// A database created before the first migration has no buckets yet
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	setSchemaVersion(t, path, 0)
+	s := openBolt(t, path, Options{})
+	if version := schemaVersion(t, s); version != 1 {
+		t.Errorf("schema version %d, want 1", version)
+	}
+	if err := s.Save(ctx, task("a", "ctx-1", types.COMPLETED)); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// Upgrading to a new version applies only the new migration and keeps the tasks
+	applied := 0
+	migrations = append(migrations, migration{version: 2, description: "test", apply: func(tx *bolt.Tx) error {
+		applied++
+		_, err := tx.CreateBucket([]byte("v2"))
+		return err
+	}})
+	defer func() { migrations = migrations[:len(migrations)-1] }()
+
+	s = openBolt(t, path, Options{})
+	if version := schemaVersion(t, s); version != 2 || applied != 1 {
+		t.Errorf("schema version %d after %d migrations, want 2 after 1", version, applied)
+	}
+	if got, _ := s.Get(ctx, "a"); got == nil {
+		t.Error("task lost in migration")
+	}
+	s.Close()
+
+	// Reopening at the latest version migrates nothing
+	s = openBolt(t, path, Options{})
+	s.Close()
+	if applied != 1 {
+		t.Errorf("migration applied %d times", applied)
+	}
+
+	// A database written by a newer server is refused rather than downgraded
+	setSchemaVersion(t, path, 3)
+	if _, err := OpenBolt(path, Options{}); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("newer schema opened: %v", err)
+	}
+}
+
+func TestBoltPruneAndCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	ctx := context.Background()
+	s := openBolt(t, path, Options{})
+
+	now := time.Date(2026, 3, 6, 8, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	s.Save(ctx, task("old-done", "ctx", types.COMPLETED))
+	s.Save(ctx, task("old-failed", "ctx", types.FAILED))
+	s.Save(ctx, task("old-running", "ctx", types.WORKING))
+	now = now.Add(2 * time.Hour)
+	s.Save(ctx, task("new-done", "ctx", types.COMPLETED))
+	// Padding so that compaction has freed pages to reclaim
+	padding := strings.Repeat("x", 4096)
+	for i := range 200 {
+		padded := task(fmt.Sprintf("padding-%03d", i), "padding", types.COMPLETED)
+		padded.Metadata = map[string]any{"padding": padding}
+		s.Save(ctx, padded)
+	}
+
+	// Unfinished tasks are kept however old they are
+	removed, err := s.Prune(ctx, now.Add(-time.Hour))
+	if err != nil || removed != 2 {
+		t.Fatalf("pruned %d, %v, want 2", removed, err)
+	}
+	if tasks, _ := s.ListByContext(ctx, "ctx"); taskIDs(tasks) != "new-done,old-running" {
+		t.Errorf("tasks after prune %q", taskIDs(tasks))
+	}
+	if removed, _ := s.Prune(ctx, now.Add(time.Hour)); removed != 201 {
+		t.Errorf("second prune removed %d, want 201", removed)
+	}
+	s.Close()
+
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = openBolt(t, path, Options{CompactOnOpen: true})
+	defer s.Close()
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() >= before.Size() {
+		t.Errorf("compaction left %d bytes of %d", after.Size(), before.Size())
+	}
+	if got, _ := s.Get(ctx, "old-running"); got == nil || got.Status.State != types.WORKING {
+		t.Errorf("task after compaction %+v", got)
+	}
+	if _, err := os.Stat(path + ".compact"); !os.IsNotExist(err) {
+		t.Errorf("temporary compaction file left: %v", err)
+	}
+}
+
+func TestBoltPruneLoop(t *testing.T) {
+	s := openBolt(t, filepath.Join(t.TempDir(), "tasks.db"), Options{Retention: time.Millisecond, PruneInterval: 10 * time.Millisecond})
+	defer s.Close()
+	ctx := context.Background()
+	s.Save(ctx, task("done", "ctx", types.COMPLETED))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ := s.Get(ctx, "done"); got == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("finished task past retention not pruned")
+}
+
+func TestMemoryContextLookup(t *testing.T) {
+	s := NewMemoryTaskStore()
+	ctx := context.Background()
+	for _, saved := range []*types.Task{task("b", "ctx", types.WORKING), task("a", "ctx", types.COMPLETED), task("c", "ctx-long", types.WORKING)} {
+		if err := s.Save(ctx, saved); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tasks, err := s.ListByContext(ctx, "ctx")
+	if err != nil || taskIDs(tasks) != "a,b" {
+		t.Errorf("tasks of ctx %q, %v", taskIDs(tasks), err)
+	}
+	// The listed tasks are copies
+	tasks[0].Status.State = types.FAILED
+	if got, _ := s.Get(ctx, "a"); got.Status.State != types.COMPLETED {
+		t.Errorf("stored task changed to %s", got.Status.State)
+	}
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// MemoryTaskStore is a tasks.TaskStore kept in memory. Tasks are stored encoded, so every Get
+// returns a private copy: the a2a-go in-memory store hands the same task to every caller, and
+// the handlers of a streaming request and a tasks/cancel request then update it concurrently.
+type MemoryTaskStore struct {
+	mu    sync.RWMutex
+	tasks map[string][]byte
+}
+
+// NewMemoryTaskStore creates an empty in-memory task store
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{tasks: make(map[string][]byte)}
+}
+
+// Save stores a copy of the task
+func (s *MemoryTaskStore) Save(ctx context.Context, task *types.Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("encode task %s: %w", task.Id, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[task.Id] = data
+	return nil
+}
+
+// Get returns a copy of the task with the given ID, or nil when it does not exist
+func (s *MemoryTaskStore) Get(ctx context.Context, taskID string) (*types.Task, error) {
+	s.mu.RLock()
+	data, exists := s.tasks[taskID]
+	s.mu.RUnlock()
+	if !exists {
+		return nil, nil
+	}
+	var task types.Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return nil, fmt.Errorf("decode task %s: %w", taskID, err)
+	}
+	return &task, nil
+}
+
+// Delete removes a task
+func (s *MemoryTaskStore) Delete(ctx context.Context, taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tasks, taskID)
+	return nil
+}
+
+// ListByContext returns copies of the tasks of a context ordered by task ID
+func (s *MemoryTaskStore) ListByContext(ctx context.Context, contextID string) ([]*types.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result []*types.Task
+	for taskID, data := range s.tasks {
+		var task types.Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			return nil, fmt.Errorf("decode task %s: %w", taskID, err)
+		}
+		if task.ContextId == contextID {
+			result = append(result, &task)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result, nil
+}
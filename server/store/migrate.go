@@ -0,0 +1,64 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// migration upgrades the database schema by one version
+type migration struct {
+	version     uint64
+	description string
+	apply       func(tx *bolt.Tx) error
+}
+
+// migrations are applied in order, append new versions and never edit released ones
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create task, context index and update time index buckets",
+		apply: func(tx *bolt.Tx) error {
+			for _, name := range [][]byte{tasksBucket, contextsBucket, updatedBucket} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// migrate brings the database to the latest schema version in a single transaction
+func migrate(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		var current uint64
+		if value := meta.Get(schemaVersionKey); value != nil {
+			current = binary.BigEndian.Uint64(value)
+		}
+		latest := migrations[len(migrations)-1].version
+		if current > latest {
+			return fmt.Errorf("task store schema version %d is newer than supported version %d", current, latest)
+		}
+
+		for _, m := range migrations {
+			if m.version <= current {
+				continue
+			}
+			if err := m.apply(tx); err != nil {
+				return fmt.Errorf("migrate task store to version %d (%s): %w", m.version, m.description, err)
+			}
+			current = m.version
+		}
+
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, current)
+		return meta.Put(schemaVersionKey, value)
+	})
+}
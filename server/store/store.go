@@ -0,0 +1,54 @@
+// Package store provides task stores for the agent server
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/server/tasks"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// Store drivers selectable by configuration
+const (
+	DriverMemory = "memory"
+	DriverBolt   = "bolt"
+)
+
+// Config selects and configures the task store
+type Config struct {
+	Driver        string        `json:"driver"`
+	Path          string        `json:"path,omitempty"`
+	Retention     time.Duration `json:"retention,omitempty"`
+	CompactOnOpen bool          `json:"compact_on_open,omitempty"`
+}
+
+// TaskStore is a tasks.TaskStore that also looks up the tasks of a context
+type TaskStore interface {
+	tasks.TaskStore
+	// ListByContext returns the tasks of a context ordered by task ID
+	ListByContext(ctx context.Context, contextID string) ([]*types.Task, error)
+}
+
+// Open creates the configured task store, the returned close function releases it
+func Open(config Config) (TaskStore, func() error, error) {
+	switch config.Driver {
+	case "", DriverMemory:
+		return NewMemoryTaskStore(), func() error { return nil }, nil
+	case DriverBolt:
+		if config.Path == "" {
+			return nil, nil, fmt.Errorf("task store path is required for the %s driver", DriverBolt)
+		}
+		s, err := OpenBolt(config.Path, Options{
+			Retention:     config.Retention,
+			CompactOnOpen: config.CompactOnOpen,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown task store driver %q, expected %s or %s", config.Driver, DriverMemory, DriverBolt)
+	}
+}
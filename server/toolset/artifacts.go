@@ -0,0 +1,114 @@
+package toolset
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	itypes "github.com/yeeaiclub/github-a2a/types"
+)
+
+// DataMimeType is the output mode of data artifacts
+const DataMimeType = "application/json"
+
+// dataArtifact converts a successful tool result into the part of a data artifact.
+// It returns nil for results that are not data artifacts or that carry an error.
+func dataArtifact(result interface{}) *types.DataPart {
+	artifact, ok := result.(itypes.DataArtifact)
+	if !ok || !artifact.Succeeded() {
+		return nil
+	}
+	encoded, err := json.Marshal(artifact)
+	if err != nil {
+		return nil
+	}
+	var data map[string]any
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil
+	}
+	return &types.DataPart{
+		Kind: "data",
+		Data: data,
+		Metadata: map[string]any{
+			"schema":   artifact.ArtifactSchema(),
+			"mimeType": DataMimeType,
+		},
+	}
+}
+
+// ArtifactSchemas returns the JSON schema of every data artifact keyed by schema name
+func ArtifactSchemas() map[string]map[string]any {
+	schemas := make(map[string]map[string]any)
+	for _, artifact := range itypes.DataArtifacts() {
+		schema := jsonSchema(reflect.TypeOf(artifact))
+		schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+		schema["$id"] = artifact.ArtifactSchema()
+		schemas[artifact.ArtifactSchema()] = schema
+	}
+	return schemas
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonSchema describes how encoding/json encodes a type. Fields tagged omitempty are optional,
+// embedded structs are flattened like encoding/json does.
+func jsonSchema(t reflect.Type) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		addStructFields(t, properties, &required)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]any{}
+}
+
+func addStructFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addStructFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = jsonSchema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package toolset
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cohesion-org/deepseek-go"
+)
+
+const (
+	defaultMaxContextTokens    = 56000
+	defaultMaxToolResultTokens = 4000
+	defaultCompletionReserve   = 4000
+	// messageOverheadTokens approximates the role and framing tokens of every message
+	messageOverheadTokens = 4
+	// charsPerToken is the initial estimate, it is corrected with the usage the API reports
+	charsPerToken = 3.5
+)
+
+// ContextBudget bounds the tokens the tool loop sends to the model
+type ContextBudget struct {
+	// MaxContextTokens is the context window of the model
+	MaxContextTokens int
+	// MaxToolResultTokens bounds a single tool result, larger results are truncated
+	MaxToolResultTokens int
+	// CompletionReserve is kept free in the window for the answer
+	CompletionReserve int
+}
+
+// withDefaults fills unset limits with the defaults
+func (b ContextBudget) withDefaults() ContextBudget {
+	if b.MaxContextTokens <= 0 {
+		b.MaxContextTokens = defaultMaxContextTokens
+	}
+	if b.MaxToolResultTokens <= 0 {
+		b.MaxToolResultTokens = defaultMaxToolResultTokens
+	}
+	if b.CompletionReserve <= 0 {
+		b.CompletionReserve = defaultCompletionReserve
+	}
+	return b
+}
+
+// promptLimit is how many tokens the messages may take
+func (b ContextBudget) promptLimit() int {
+	return b.MaxContextTokens - b.CompletionReserve
+}
+
+// tokenCounter estimates token counts from the text length, calibrated with the prompt tokens
+// the API reports for the messages it was sent
+type tokenCounter struct {
+	charsPerToken float64
+}
+
+func newTokenCounter() *tokenCounter {
+	return &tokenCounter{charsPerToken: charsPerToken}
+}
+
+// text estimates the tokens of a string
+func (c *tokenCounter) text(s string) int {
+	if s == "" {
+		return 0
+	}
+	return int(float64(utf8.RuneCountInString(s))/c.charsPerToken) + 1
+}
+
+// message estimates the tokens of a message including its tool calls
+func (c *tokenCounter) message(message deepseek.ChatCompletionMessage) int {
+	tokens := messageOverheadTokens + c.text(message.Content)
+	for _, call := range message.ToolCalls {
+		tokens += messageOverheadTokens + c.text(call.Function.Name) + c.text(call.Function.Arguments)
+	}
+	return tokens
+}
+
+// messages estimates the tokens of a conversation
+func (c *tokenCounter) messages(messages []deepseek.ChatCompletionMessage) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += c.message(message)
+	}
+	return tokens
+}
+
+// calibrate adjusts the estimate to the prompt tokens the API counted for the given messages
+func (c *tokenCounter) calibrate(messages []deepseek.ChatCompletionMessage, promptTokens int) {
+	if promptTokens <= 0 {
+		return
+	}
+	chars := 0
+	for _, message := range messages {
+		chars += utf8.RuneCountInString(message.Content)
+		for _, call := range message.ToolCalls {
+			chars += utf8.RuneCountInString(call.Function.Name) + utf8.RuneCountInString(call.Function.Arguments)
+		}
+	}
+	overhead := messageOverheadTokens * len(messages)
+	if promptTokens <= overhead || chars == 0 {
+		return
+	}
+	ratio := float64(chars) / float64(promptTokens-overhead)
+	// Tool definitions are part of the prompt too, keep the estimate in a sane range
+	if ratio < 1 {
+		ratio = 1
+	}
+	if ratio > 6 {
+		ratio = 6
+	}
+	c.charsPerToken = ratio
+}
+
+// truncateResult shrinks a tool result to maxTokens. JSON results keep their shape: the longest
+// lists are shortened and the omitted items counted in an "omitted" field, anything else is cut.
+func (c *tokenCounter) truncateResult(result string, maxTokens int) string {
+	if c.text(result) <= maxTokens {
+		return result
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal([]byte(result), &object); err == nil {
+		omitted := map[string]int{}
+		for {
+			longest := findLongestList(object, "", nil)
+			if longest == nil || len(longest.items) <= 1 {
+				break
+			}
+			keep := len(longest.items) / 2
+			omitted[longest.path] += len(longest.items) - keep
+			longest.set(longest.items[:keep])
+			object["truncated"] = true
+			object["omitted"] = omitted
+			encoded, err := json.Marshal(object)
+			if err != nil {
+				break
+			}
+			if c.text(string(encoded)) <= maxTokens {
+				return string(encoded)
+			}
+		}
+	}
+
+	// The note counts against the limit too, it is measured with the widest count it can hold
+	runes := []rune(result)
+	maxChars := int(float64(maxTokens-1)*c.charsPerToken) - utf8.RuneCountInString(truncationNote(len(runes), len(runes)))
+	if maxChars < 0 {
+		maxChars = 0
+	}
+	return string(runes[:maxChars]) + truncationNote(len(runes)-maxChars, len(runes))
+}
+
+// truncationNote tells the model how much of a result was cut
+func truncationNote(omitted, total int) string {
+	return fmt.Sprintf("\n[truncated, %d of %d characters omitted to fit the context window]", omitted, total)
+}
+
+// jsonList is a list inside a decoded JSON value that can be replaced in place
+type jsonList struct {
+	path  string
+	items []any
+	set   func(items []any)
+}
+
+// findLongestList returns the list with the most items in a decoded JSON value, or nil when it has none
+func findLongestList(value any, path string, set func(items []any)) *jsonList {
+	var longest *jsonList
+	consider := func(candidate *jsonList) {
+		if candidate != nil && (longest == nil || len(candidate.items) > len(longest.items)) {
+			longest = candidate
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, child := range typed {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			consider(findLongestList(child, childPath, func(items []any) { typed[key] = items }))
+		}
+	case []any:
+		if set != nil {
+			consider(&jsonList{path: path, items: typed, set: set})
+		}
+		for i, child := range typed {
+			consider(findLongestList(child, fmt.Sprintf("%s[%d]", path, i), func(items []any) { typed[i] = items }))
+		}
+	}
+	return longest
+}
+
+// fitWindow keeps the conversation within the prompt limit. The system prompt and the user request
+// are always kept, the oldest tool turns are replaced by a note listing the calls they made, and
+// the tool results of the remaining turns are shortened when that is not enough.
+func (c *tokenCounter) fitWindow(messages []deepseek.ChatCompletionMessage, budget ContextBudget) ([]deepseek.ChatCompletionMessage, int) {
+	limit := budget.promptLimit()
+	if c.messages(messages) <= limit || len(messages) <= 2 {
+		return messages, 0
+	}
+
+	head := messages[:2]
+	turns := splitTurns(messages[2:])
+	evicted := 0
+	var calls []string
+	// Keep at least the latest turn, the model needs its tool results to continue
+	for len(turns) > 1 && c.messages(head)+c.turnsTokens(turns)+c.text(summaryNote(calls)) > limit {
+		calls = append(calls, describeTurn(turns[0])...)
+		turns = turns[1:]
+		evicted++
+	}
+
+	fitted := append([]deepseek.ChatCompletionMessage{}, head...)
+	if evicted > 0 {
+		fitted = append(fitted, deepseek.ChatCompletionMessage{
+			Role:    deepseek.ChatMessageRoleSystem,
+			Content: summaryNote(calls),
+		})
+	}
+	for _, turn := range turns {
+		fitted = append(fitted, turn...)
+	}
+
+	// Still too large, shorten the tool results that are left evenly
+	if over := c.messages(fitted) - limit; over > 0 {
+		var results []int
+		for i, message := range fitted {
+			if message.Role == deepseek.ChatMessageRoleTool {
+				results = append(results, i)
+			}
+		}
+		if len(results) > 0 {
+			available := limit - (c.messages(fitted) - c.resultsTokens(fitted, results))
+			share := available/len(results) - messageOverheadTokens
+			if share < 50 {
+				share = 50
+			}
+			for _, i := range results {
+				fitted[i].Content = c.truncateResult(fitted[i].Content, share)
+			}
+		}
+	}
+	return fitted, evicted
+}
+
+func (c *tokenCounter) turnsTokens(turns [][]deepseek.ChatCompletionMessage) int {
+	tokens := 0
+	for _, turn := range turns {
+		tokens += c.messages(turn)
+	}
+	return tokens
+}
+
+func (c *tokenCounter) resultsTokens(messages []deepseek.ChatCompletionMessage, indexes []int) int {
+	tokens := 0
+	for _, i := range indexes {
+		tokens += c.message(messages[i])
+	}
+	return tokens
+}
+
+// splitTurns groups an assistant message with the tool results that answer its calls,
+// so a turn is always evicted as a whole
+func splitTurns(messages []deepseek.ChatCompletionMessage) [][]deepseek.ChatCompletionMessage {
+	var turns [][]deepseek.ChatCompletionMessage
+	for _, message := range messages {
+		if message.Role == deepseek.ChatMessageRoleTool && len(turns) > 0 {
+			turns[len(turns)-1] = append(turns[len(turns)-1], message)
+			continue
+		}
+		turns = append(turns, []deepseek.ChatCompletionMessage{message})
+	}
+	return turns
+}
+
+// describeTurn lists the tool calls of a turn with the size and status of their results
+func describeTurn(turn []deepseek.ChatCompletionMessage) []string {
+	results := map[string]deepseek.ChatCompletionMessage{}
+	for _, message := range turn[1:] {
+		results[message.ToolCallID] = message
+	}
+
+	var calls []string
+	for _, call := range turn[0].ToolCalls {
+		result := results[call.ID]
+		status := "ok"
+		var parsed struct {
+			Status string `json:"status"`
+		}
+		if json.Unmarshal([]byte(result.Content), &parsed) == nil && parsed.Status != "" {
+			status = parsed.Status
+		}
+		calls = append(calls, fmt.Sprintf("%s(%s) returned %s, %d characters",
+			call.Function.Name, argumentsOrEmpty(call.Function.Arguments), status, len(result.Content)))
+	}
+	return calls
+}
+
+// summaryNote tells the model which earlier calls were removed from the conversation
+func summaryNote(calls []string) string {
+	if len(calls) == 0 {
+		return ""
+	}
+	return "Earlier tool calls were removed to fit the context window, call a tool again if you need its result:\n- " +
+		strings.Join(calls, "\n- ")
+}
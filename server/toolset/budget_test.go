@@ -0,0 +1,178 @@
+package toolset
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cohesion-org/deepseek-go"
+)
+
+// exactCounter counts a token per character, plus one per text
+func exactCounter() *tokenCounter {
+	return &tokenCounter{charsPerToken: 1}
+}
+
+func numbers(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprint(i)
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func TestTruncateResult(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	tests := map[string]struct {
+		result    string
+		maxTokens int
+		want      string
+	}{
+		"fits": {`{"items":[1,2,3]}`, 18, `{"items":[1,2,3]}`},
+		// The longest list is halved until the result fits
+		"list": {
+			`{"items":` + numbers(30) + `,"name":"repo"}`, 80,
+			`{"items":[0,1,2,3,4,5,6],"name":"repo","omitted":{"items":23},"truncated":true}`,
+		},
+		"nested list": {
+			`{"data":{"nodes":[` + numbers(40) + `,[0]]},"labels":[0,1,2]}`, 102,
+			`{"data":{"nodes":[[0,1,2,3,4],[0]]},"labels":[0,1,2],"omitted":{"data.nodes[0]":35},"truncated":true}`,
+		},
+		"longest first": {
+			`{"a":` + numbers(30) + `,"b":` + numbers(12) + `}`, 111,
+			`{"a":` + numbers(15) + `,"b":` + numbers(12) + `,"omitted":{"a":15},"truncated":true}`,
+		},
+		// Results without a list to shorten are cut, the note fits in the limit too
+		"text": {long, 100, strings.Repeat("x", 30) + "\n[truncated, 270 of 300 characters omitted to fit the context window]"},
+		"string field": {
+			`{"body":"` + long + `"}`, 100,
+			`{"body":"` + strings.Repeat("x", 21) + "\n[truncated, 281 of 311 characters omitted to fit the context window]",
+		},
+		"array":     {numbers(100), 100, "[0,1,2,3,4,5,6,7,8,9,10,11,12,\n[truncated, 261 of 291 characters omitted to fit the context window]"},
+		"note only": {long, 10, "\n[truncated, 300 of 300 characters omitted to fit the context window]"},
+	}
+	for name, test := range tests {
+		if got := exactCounter().truncateResult(test.result, test.maxTokens); got != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", name, got, test.want)
+		}
+	}
+}
+
+func message(role, content string) deepseek.ChatCompletionMessage {
+	return deepseek.ChatCompletionMessage{Role: role, Content: content}
+}
+
+// turn is an assistant message calling a tool for every result, followed by the results
+func turn(id string, results ...string) []deepseek.ChatCompletionMessage {
+	call := deepseek.ChatCompletionMessage{Role: deepseek.ChatMessageRoleAssistant}
+	var messages []deepseek.ChatCompletionMessage
+	for i, result := range results {
+		callID := fmt.Sprintf("%s-%d", id, i)
+		call.ToolCalls = append(call.ToolCalls, deepseek.ToolCall{ID: callID, Type: "function", Function: deepseek.ToolCallFunction{
+			Name: "list_issues", Arguments: fmt.Sprintf(`{"page":%d}`, i+1),
+		}})
+		messages = append(messages, deepseek.ChatCompletionMessage{Role: deepseek.ChatMessageRoleTool, Content: result, ToolCallID: callID})
+	}
+	return append([]deepseek.ChatCompletionMessage{call}, messages...)
+}
+
+func conversation(turns ...[]deepseek.ChatCompletionMessage) []deepseek.ChatCompletionMessage {
+	messages := []deepseek.ChatCompletionMessage{
+		message(deepseek.ChatMessageRoleSystem, "You are a GitHub agent"),
+		message(deepseek.ChatMessageRoleUser, "Summarize the open issues"),
+	}
+	for _, turn := range turns {
+		messages = append(messages, turn...)
+	}
+	return messages
+}
+
+func contents(messages []deepseek.ChatCompletionMessage) []string {
+	var got []string
+	for _, message := range messages {
+		got = append(got, message.Role+": "+message.Content)
+	}
+	return got
+}
+
+func TestFitWindow(t *testing.T) {
+	result := strings.Repeat("r", 200)
+	failed := `{"status":"error","message":"` + strings.Repeat("e", 100) + `"}`
+	budget := func(limit int) ContextBudget {
+		return ContextBudget{MaxContextTokens: limit + 100, CompletionReserve: 100}
+	}
+	tests := map[string]struct {
+		messages []deepseek.ChatCompletionMessage
+		budget   ContextBudget
+		evicted  int
+		want     []string
+	}{
+		"fits": {
+			conversation(turn("a", result), turn("b", result)), budget(1000), 0,
+			contents(conversation(turn("a", result), turn("b", result))),
+		},
+		// The system prompt and the request are kept even when they alone are too large
+		"request only": {conversation(), budget(10), 0, contents(conversation())},
+		// Whole turns are evicted oldest first and listed in a note
+		"evicted turns": {
+			conversation(turn("a", result, failed), turn("b", result), turn("c", result)), budget(600), 2,
+			append(contents(conversation()[:2]),
+				"system: Earlier tool calls were removed to fit the context window, call a tool again if you need its result:\n"+
+					`- list_issues({"page":1}) returned ok, 200 characters`+"\n"+
+					`- list_issues({"page":2}) returned error, 131 characters`+"\n"+
+					`- list_issues({"page":1}) returned ok, 200 characters`,
+				"assistant: ", "tool: "+result),
+		},
+		// The last turn is kept and its results are shortened evenly
+		"last turn": {
+			conversation(turn("a", result, result)), budget(300), 0,
+			append(contents(conversation()),
+				"assistant: ",
+				"tool: "+strings.Repeat("r", 18)+"\n[truncated, 182 of 200 characters omitted to fit the context window]",
+				"tool: "+strings.Repeat("r", 18)+"\n[truncated, 182 of 200 characters omitted to fit the context window]"),
+		},
+	}
+	for name, test := range tests {
+		counter := exactCounter()
+		fitted, evicted := counter.fitWindow(test.messages, test.budget)
+		if tokens := counter.messages(fitted); name != "request only" && tokens > test.budget.promptLimit() {
+			t.Errorf("%s: %d tokens left, over the limit of %d", name, tokens, test.budget.promptLimit())
+		}
+		if evicted != test.evicted {
+			t.Errorf("%s: evicted %d turns, want %d", name, evicted, test.evicted)
+		}
+		if got := strings.Join(contents(fitted), "\n---\n"); got != strings.Join(test.want, "\n---\n") {
+			t.Errorf("%s: got\n%s\nwant\n%s", name, got, strings.Join(test.want, "\n---\n"))
+		}
+	}
+
+	// Evicting never splits a turn from its results
+	fitted, _ := exactCounter().fitWindow(conversation(turn("a", result), turn("b", result, result), turn("c", result)), budget(600))
+	for i, message := range fitted {
+		if message.Role == deepseek.ChatMessageRoleTool && fitted[i-1].Role != deepseek.ChatMessageRoleAssistant && fitted[i-1].Role != deepseek.ChatMessageRoleTool {
+			t.Errorf("tool result %d follows a %s message", i, fitted[i-1].Role)
+		}
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	// 100 characters in one message, whose overhead is 4 tokens
+	messages := []deepseek.ChatCompletionMessage{message(deepseek.ChatMessageRoleUser, strings.Repeat("x", 100))}
+	tests := map[string]struct {
+		promptTokens int
+		want         float64
+	}{
+		"no usage":      {0, charsPerToken},
+		"only overhead": {4, charsPerToken},
+		"measured":      {54, 2},
+		"upper bound":   {14, 6},
+		"lower bound":   {1004, 1},
+	}
+	for name, test := range tests {
+		counter := newTokenCounter()
+		counter.calibrate(messages, test.promptTokens)
+		if counter.charsPerToken != test.want {
+			t.Errorf("%s: %v characters per token, want %v", name, counter.charsPerToken, test.want)
+		}
+	}
+}
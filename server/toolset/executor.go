@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/cohesion-org/deepseek-go"
 
@@ -13,32 +16,131 @@ import (
 	"github.com/yeeaiclub/a2a-go/sdk/server/tasks"
 	"github.com/yeeaiclub/a2a-go/sdk/server/tasks/updater"
 	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/logging"
+	"github.com/yeeaiclub/github-a2a/server/metrics"
+	"github.com/yeeaiclub/github-a2a/server/tracing"
 	itypes "github.com/yeeaiclub/github-a2a/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ChatClient requests chat completions, *deepseek.Client implements it and tests substitute a scripted model
+type ChatClient interface {
+	CreateChatCompletion(ctx context.Context, request *deepseek.ChatCompletionRequest) (*deepseek.ChatCompletionResponse, error)
+}
+
 type DeepSeekExecutor struct {
 	store        tasks.TaskStore
 	card         *types.AgentCard
 	tools        map[string]itypes.Function
 	apiKey       string
 	systemPrompt string
-	client       *deepseek.Client
+	client       ChatClient
+	baseURL      string
 	model        string
+	// maxIterations bounds the model round trips of a single request
+	maxIterations int
+	// maxRepairAttempts bounds how often the model may retry a tool with invalid arguments
+	maxRepairAttempts int
+	// budget bounds the tokens sent to the model per completion
+	budget ContextBudget
+	// inputLimits bounds the files and data of a message
+	inputLimits InputLimits
+	// httpClient downloads file parts given by URL
+	httpClient *http.Client
+}
+
+// ExecutorOption configures optional DeepSeekExecutor settings
+type ExecutorOption func(e *DeepSeekExecutor)
+
+// WithMaxRepairAttempts sets how many invalid calls of the same tool are answered with
+// validation feedback before the tool is withdrawn for the rest of the request
+func WithMaxRepairAttempts(attempts int) ExecutorOption {
+	return func(e *DeepSeekExecutor) {
+		e.maxRepairAttempts = attempts
+	}
+}
+
+// WithModel sets the chat model used for completions
+func WithModel(model string) ExecutorOption {
+	return func(e *DeepSeekExecutor) {
+		e.model = model
+	}
+}
+
+// WithMaxIterations sets how many completions a single request may take
+func WithMaxIterations(iterations int) ExecutorOption {
+	return func(e *DeepSeekExecutor) {
+		e.maxIterations = iterations
+	}
+}
+
+// WithContextBudget sets the token limits of the conversation, unset limits keep their defaults
+func WithContextBudget(budget ContextBudget) ExecutorOption {
+	return func(e *DeepSeekExecutor) {
+		e.budget = budget
+	}
+}
+
+// WithInputLimits sets the limits of the files and data a message may carry, unset limits keep their defaults
+func WithInputLimits(limits InputLimits) ExecutorOption {
+	return func(e *DeepSeekExecutor) {
+		e.inputLimits = limits
+	}
+}
+
+// WithChatClient replaces the DeepSeek client, the API key and base URL are ignored then
+func WithChatClient(client ChatClient) ExecutorOption {
+	return func(e *DeepSeekExecutor) {
+		e.client = client
+	}
+}
+
+// WithBaseURL points the client at a DeepSeek compatible API
+func WithBaseURL(baseURL string) ExecutorOption {
+	return func(e *DeepSeekExecutor) {
+		e.baseURL = baseURL
+	}
 }
 
-func NewExecutor(store tasks.TaskStore, card *types.AgentCard, tools map[string]itypes.Function, apiKey string, systemPrompt string) *DeepSeekExecutor {
-	client := deepseek.NewClient(apiKey)
-	log.Printf("Initializing DeepSeekExecutor")
+func NewExecutor(store tasks.TaskStore, card *types.AgentCard, tools map[string]itypes.Function, apiKey string, systemPrompt string, opts ...ExecutorOption) *DeepSeekExecutor {
+	slog.Debug("Initializing DeepSeek executor")
+
+	executor := &DeepSeekExecutor{
+		store:             store,
+		card:              card,
+		tools:             tools,
+		apiKey:            apiKey,
+		systemPrompt:      systemPrompt,
+		model:             "deepseek-chat",
+		maxIterations:     10,
+		maxRepairAttempts: 2,
+	}
+	for _, opt := range opts {
+		opt(executor)
+	}
+	executor.budget = executor.budget.withDefaults()
+	executor.inputLimits = executor.inputLimits.withDefaults()
+	if executor.httpClient == nil {
+		executor.httpClient = &http.Client{Timeout: fileFetchTimeout}
+	}
 
-	return &DeepSeekExecutor{
-		store:        store,
-		card:         card,
-		tools:        tools,
-		apiKey:       apiKey,
-		systemPrompt: systemPrompt,
-		client:       client,
-		model:        "deepseek-chat",
+	if executor.client != nil {
+		return executor
+	}
+	var clientOptions []deepseek.Option
+	if executor.baseURL != "" {
+		clientOptions = append(clientOptions, deepseek.WithBaseURL(executor.baseURL))
 	}
+	client, err := deepseek.NewClientWithOptions(apiKey, clientOptions...)
+	if err != nil {
+		slog.Error("Failed to create DeepSeek client", "error", err)
+		// A nil *deepseek.Client must not end up in the interface, processRequest checks for nil
+		return executor
+	}
+	executor.client = client
+	return executor
 }
 
 func (e *DeepSeekExecutor) Execute(ctx context.Context, requestContext *execution.RequestContext, queue *event.Queue) error {
@@ -48,63 +150,92 @@ func (e *DeepSeekExecutor) Execute(ctx context.Context, requestContext *executio
 	}
 	u.StartWork()
 
-	messageText := ""
-	for _, msg := range requestContext.Params.Message.Parts {
-		if msg.GetKind() == "text" {
-			part := msg.(*types.TextPart)
-			messageText += part.Text
-		}
+	input, err := readInput(ctx, requestContext.Params.Message.Parts, e.inputLimits, e.httpClient)
+	if err == nil {
+		err = e.checkInputBudget(input)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Rejected message", "error", err)
+		u.Reject(updater.WithMessage(u.NewAgentMessage([]types.Part{
+			&types.TextPart{Kind: "text", Text: fmt.Sprintf("The message was rejected: %v", err)},
+		})))
+		return nil
 	}
 
-	return e.processRequest(ctx, messageText, u)
+	return e.processRequest(ctx, input, u)
 }
 
+// checkInputBudget rejects requests that would leave too little of the context window for tool results
+func (e *DeepSeekExecutor) checkInputBudget(input Input) error {
+	counter := newTokenCounter()
+	tokens := counter.text(e.systemPrompt) + counter.text(input.Text)
+	if limit := e.budget.promptLimit() / 2; tokens > limit {
+		return fmt.Errorf("the message takes about %d tokens, at most %d fit the context window of the model", tokens, limit)
+	}
+	return nil
+}
+
+// Cancel records a task as canceled. It is only called for tasks without a running execution,
+// the server stops running executions through their context.
 func (e *DeepSeekExecutor) Cancel(ctx context.Context, requestContext *execution.RequestContext, queue *event.Queue) error {
-	return fmt.Errorf("cancel operation not supported")
+	u := updater.NewTaskUpdater(queue, requestContext.TaskId, requestContext.ContextId)
+	u.UpdateStatus(types.CANCELED, updater.WithMessage(u.NewAgentMessage([]types.Part{
+		&types.TextPart{Kind: "text", Text: "Task canceled by the client"},
+	})))
+	return nil
 }
 
-func (e *DeepSeekExecutor) processRequest(ctx context.Context, messageText string, taskUpdater *updater.TaskUpdater) error {
+func (e *DeepSeekExecutor) processRequest(ctx context.Context, input Input, taskUpdater *updater.TaskUpdater) error {
 	if e.client == nil {
-		log.Printf("ERROR: DeepSeekExecutor client is nil!")
+		slog.ErrorContext(ctx, "DeepSeek executor has no client")
 		return fmt.Errorf("DeepSeekExecutor client is nil")
 	}
 
-	log.Printf("Processing request with message: %s", messageText)
+	slog.InfoContext(ctx, "Processing request", logging.KeyMessage, input.Text, "parameters", len(input.Parameters))
 
 	messages := []deepseek.ChatCompletionMessage{
 		{Role: deepseek.ChatMessageRoleSystem, Content: e.systemPrompt},
-		{Role: deepseek.ChatMessageRoleUser, Content: messageText},
+		{Role: deepseek.ChatMessageRoleUser, Content: input.Text},
 	}
 
-	var tools []deepseek.Tool
-	for _, function := range e.tools {
-		tools = append(tools, deepseek.Tool{
-			Type:     "function",
-			Function: function.FunctionDefinition(),
-		})
-	}
+	tools := e.toolDefinitions(nil)
 
-	log.Printf("Created %d tools for the request", len(tools))
+	slog.DebugContext(ctx, "Offering tools", "tools", len(tools))
 
-	maxIterations := 10
+	maxIterations := e.maxIterations
 	iteration := 0
+	// answered is set once the model stopped calling tools, the task then has its final state
+	answered := false
+	// failures counts invalid calls per tool name across the whole request
+	failures := make(map[string]int)
+	// messages keeps the whole conversation, each completion is sent the part that fits the budget
+	counter := newTokenCounter()
 
 	for iteration < maxIterations {
 		iteration += 1
-		log.Printf("Making API call iteration %d/%d", iteration, maxIterations)
-
-		response, err := e.client.CreateChatCompletion(ctx, &deepseek.ChatCompletionRequest{
-			Model:    e.model,
-			Messages: messages,
-			Tools:    tools,
-		})
+		slog.DebugContext(ctx, "Requesting completion", "iteration", iteration, "max_iterations", maxIterations)
 
+		iterationCtx, iterationSpan := tracing.Tracer().Start(ctx, "llm.iteration",
+			trace.WithAttributes(attribute.Int("llm.iteration", iteration)))
+		window, evicted := counter.fitWindow(messages, e.budget)
+		if evicted > 0 {
+			slog.InfoContext(ctx, "Evicted earlier tool turns to fit the context window",
+				"evicted_turns", evicted, "estimated_tokens", counter.messages(window), "limit", e.budget.promptLimit())
+		}
+		iterationSpan.SetAttributes(
+			attribute.Int("llm.estimated_prompt_tokens", counter.messages(window)),
+			attribute.Int("llm.evicted_turns", evicted),
+		)
+		response, err := e.complete(iterationCtx, window, tools)
 		if err != nil {
-			log.Printf("Error in API call: %v", err)
+			slog.ErrorContext(ctx, "Chat completion failed", "iteration", iteration, "error", err)
+			tracing.End(iterationSpan, err)
 			return err
 		}
 
-		log.Printf("API call successful, got %d choices", len(response.Choices))
+		slog.DebugContext(ctx, "Chat completion succeeded", "choices", len(response.Choices),
+			"prompt_tokens", response.Usage.PromptTokens, "estimated_tokens", counter.messages(window))
+		counter.calibrate(window, response.Usage.PromptTokens)
 
 		message := response.Choices[0].Message
 		messages = append(messages, deepseek.ChatCompletionMessage{
@@ -114,59 +245,58 @@ func (e *DeepSeekExecutor) processRequest(ctx context.Context, messageText strin
 		})
 
 		if len(message.ToolCalls) == 0 {
-			if message.Content != "" {
-				log.Println("Assistant response:", message.Content)
-				agentParts := []types.Part{
-					&types.TextPart{Kind: "text", Text: message.Content},
-				}
-				taskUpdater.AddArtifact(agentParts)
-				taskUpdater.Complete()
+			answered = true
+			if message.Content == "" {
+				slog.WarnContext(ctx, "Assistant answered without content", "iteration", iteration)
+				taskUpdater.Failed(updater.WithMessage(taskUpdater.NewAgentMessage([]types.Part{
+					&types.TextPart{Kind: "text", Text: "Sorry, the model returned an empty answer."},
+				})))
+				iterationSpan.End()
+				break
+			}
+			slog.DebugContext(ctx, "Assistant answered", logging.KeyContent, message.Content)
+			agentParts := []types.Part{
+				&types.TextPart{Kind: "text", Text: message.Content},
 			}
+			taskUpdater.AddArtifact(agentParts)
+			taskUpdater.Complete()
+			iterationSpan.End()
 			break
 		}
 
-		log.Printf("Processing %d tool calls", len(message.ToolCalls))
+		slog.DebugContext(ctx, "Processing tool calls", "tool_calls", len(message.ToolCalls))
 
 		for _, tool := range message.ToolCalls {
-			name := tool.Function.Name
-			args := tool.Function.Arguments
-			if function, ok := e.tools[name]; ok {
-				var arg map[string]interface{}
-				err = json.Unmarshal([]byte(args), &arg)
-				if err != nil {
-					log.Printf("Error parsing function arguments: %v", err)
-					continue
-				}
-
-				res := function.Call(arg)
-				// Serialize the result to JSON string
-				resultJSON, err := json.Marshal(res)
-				log.Printf("Result JSON: %s", string(resultJSON))
-				if err != nil {
-					resultJSON = []byte(fmt.Sprintf(`{"error": "Failed to serialize result: %v"}`, err))
-				}
-
-				messages = append(messages, deepseek.ChatCompletionMessage{
-					Role:       deepseek.ChatMessageRoleTool,
-					ToolCallID: tool.ID,
-					Content:    string(resultJSON),
-				})
-			} else {
-				messages = append(messages, deepseek.ChatCompletionMessage{
-					Role:       deepseek.ChatMessageRoleTool,
-					ToolCallID: tool.ID,
-					Content:    fmt.Sprintf(`{"error": "Method %s not found on tool instance"}`, name),
-				})
+			result, data := e.callTool(iterationCtx, tool, input.Parameters, failures)
+			// The client gets the complete result as structured data, the model a version that fits its window
+			if part := dataArtifact(data); part != nil {
+				taskUpdater.AddArtifact([]types.Part{part}, updater.WithName(tool.Function.Name), updater.WithMetadata(map[string]any{
+					"schema":     part.Metadata["schema"],
+					"tool":       tool.Function.Name,
+					"toolCallId": tool.ID,
+				}))
+			}
+			if truncated := counter.truncateResult(result, e.budget.MaxToolResultTokens); len(truncated) < len(result) {
+				slog.InfoContext(ctx, "Truncated oversized tool result", "tool", tool.Function.Name,
+					"characters", len(result), "kept", len(truncated))
+				result = truncated
 			}
+			messages = append(messages, deepseek.ChatCompletionMessage{
+				Role:       deepseek.ChatMessageRoleTool,
+				ToolCallID: tool.ID,
+				Content:    result,
+			})
 		}
+		tools = e.toolDefinitions(failures)
 
 		agentMessage := taskUpdater.NewAgentMessage([]types.Part{
 			&types.TextPart{Kind: "text", Text: "Processing tool calls..."},
 		})
 		taskUpdater.StartWork(updater.WithMessage(agentMessage))
+		iterationSpan.End()
 	}
 
-	if iteration >= maxIterations {
+	if !answered {
 		parts := []types.Part{&types.TextPart{Kind: "text", Text: "Sorry, the request has exceeded the maximum number of iterations."}}
 		taskUpdater.Complete(updater.WithMessage(&types.Message{
 			Parts: parts,
@@ -174,3 +304,165 @@ func (e *DeepSeekExecutor) processRequest(ctx context.Context, messageText strin
 	}
 	return nil
 }
+
+// toolDefinitions returns the tools offered to the model, leaving out tools whose repair attempts are exhausted
+func (e *DeepSeekExecutor) toolDefinitions(failures map[string]int) []deepseek.Tool {
+	var tools []deepseek.Tool
+	for name, function := range e.tools {
+		if failures[name] > e.maxRepairAttempts {
+			continue
+		}
+		tools = append(tools, deepseek.Tool{
+			Type:     "function",
+			Function: function.FunctionDefinition(),
+		})
+	}
+	return tools
+}
+
+// callTool executes a single tool call and always returns the content of the matching tool message,
+// along with the result of the tool when it ran. Arguments the model left out are taken from the
+// message parameters. Argument errors are returned to the model in a structured form so it can
+// correct the call.
+func (e *DeepSeekExecutor) callTool(ctx context.Context, tool deepseek.ToolCall, parameters map[string]any, failures map[string]int) (string, interface{}) {
+	name := tool.Function.Name
+	ctx, span := tracing.Tracer().Start(ctx, "tool.call", trace.WithAttributes(
+		attribute.String("tool.name", name),
+		attribute.String("tool.call_id", tool.ID),
+	))
+	defer span.End()
+
+	function, ok := e.tools[name]
+	if !ok {
+		metrics.ToolCallsTotal.WithLabelValues(metrics.UnknownTool, "unknown_tool").Inc()
+		span.SetAttributes(attribute.String("tool.outcome", "unknown_tool"))
+		return toolErrorJSON(&ToolError{
+			Status:  "error",
+			Message: fmt.Sprintf("Tool %s does not exist, use one of the provided tools", name),
+		}), nil
+	}
+
+	if failures[name] > e.maxRepairAttempts {
+		metrics.ToolCallsTotal.WithLabelValues(name, "invalid_arguments").Inc()
+		span.SetAttributes(attribute.String("tool.outcome", "invalid_arguments"))
+		return toolErrorJSON(&ToolError{
+			Status:  "error",
+			Message: fmt.Sprintf("Tool %s is no longer available for this request after repeated invalid arguments", name),
+		}), nil
+	}
+
+	var res interface{}
+	var arg map[string]interface{}
+	if err := json.Unmarshal([]byte(argumentsOrEmpty(tool.Function.Arguments)), &arg); err != nil {
+		slog.WarnContext(ctx, "Invalid tool arguments", "tool", name, logging.KeyArguments, tool.Function.Arguments, "error", err)
+		res = NewValidationError(name, []ValidationError{
+			{Message: fmt.Sprintf("arguments must be a JSON object: %v", err)},
+		})
+	} else {
+		if applied := applyParameters(function, arg, parameters); len(applied) > 0 {
+			slog.DebugContext(ctx, "Applied message parameters", "tool", name, "parameters", applied)
+		}
+		start := time.Now()
+		res = function.Call(ctx, arg)
+		metrics.ToolCallDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	}
+
+	if toolErr, ok := res.(*ToolError); ok && len(toolErr.Errors) > 0 {
+		metrics.ToolCallsTotal.WithLabelValues(name, "invalid_arguments").Inc()
+		span.SetAttributes(attribute.String("tool.outcome", "invalid_arguments"))
+		failures[name]++
+		if failures[name] > e.maxRepairAttempts {
+			toolErr.Message = fmt.Sprintf("Invalid arguments for %s after %d attempts, the tool is withdrawn for this request; explain the problem to the user instead",
+				name, failures[name])
+		}
+		return toolErrorJSON(toolErr), nil
+	}
+
+	// Serialize the result to JSON string
+	resultJSON, err := json.Marshal(res)
+	slog.DebugContext(ctx, "Tool returned", "tool", name, logging.KeyResult, string(resultJSON))
+	outcome := toolOutcome(resultJSON, err)
+	metrics.ToolCallsTotal.WithLabelValues(name, outcome).Inc()
+	span.SetAttributes(attribute.String("tool.outcome", outcome))
+	if outcome == "error" {
+		span.SetStatus(codes.Error, "tool returned an error")
+	}
+	if err != nil {
+		return toolErrorJSON(&ToolError{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to serialize result: %v", err),
+		}), nil
+	}
+	return string(resultJSON), res
+}
+
+// toolOutcome reads the status every tool response carries, ok or error
+func toolOutcome(resultJSON []byte, err error) string {
+	if err != nil {
+		return "error"
+	}
+	var result struct {
+		Status string `json:"status"`
+	}
+	if json.Unmarshal(resultJSON, &result) == nil && result.Status == "error" {
+		return "error"
+	}
+	return "ok"
+}
+
+// complete requests a chat completion, recording its latency and token usage as metrics and a span
+func (e *DeepSeekExecutor) complete(ctx context.Context, messages []deepseek.ChatCompletionMessage, tools []deepseek.Tool) (*deepseek.ChatCompletionResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "llm.chat_completion", trace.WithAttributes(
+		attribute.String("gen_ai.system", "deepseek"),
+		attribute.String("gen_ai.request.model", e.model),
+		attribute.Int("llm.messages", len(messages)),
+		attribute.Int("llm.tools", len(tools)),
+	))
+
+	start := time.Now()
+	response, err := e.client.CreateChatCompletion(ctx, &deepseek.ChatCompletionRequest{
+		Model:    e.model,
+		Messages: messages,
+		Tools:    tools,
+	})
+	outcome := "ok"
+	if err == nil && len(response.Choices) == 0 {
+		err = fmt.Errorf("chat completion returned no choices")
+	}
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.LLMRequestDuration.WithLabelValues(e.model, outcome).Observe(time.Since(start).Seconds())
+	if response != nil {
+		usage := response.Usage
+		metrics.LLMTokensTotal.WithLabelValues(e.model, "prompt").Add(float64(usage.PromptTokens))
+		metrics.LLMTokensTotal.WithLabelValues(e.model, "completion").Add(float64(usage.CompletionTokens))
+		metrics.LLMTokensTotal.WithLabelValues(e.model, "cache_hit").Add(float64(usage.PromptCacheHitTokens))
+		span.SetAttributes(
+			attribute.String("gen_ai.response.model", response.Model),
+			attribute.Int("gen_ai.usage.input_tokens", usage.PromptTokens),
+			attribute.Int("gen_ai.usage.output_tokens", usage.CompletionTokens),
+		)
+		if len(response.Choices) > 0 {
+			span.SetAttributes(attribute.String("gen_ai.response.finish_reason", response.Choices[0].FinishReason))
+		}
+	}
+	tracing.End(span, err)
+	return response, err
+}
+
+// argumentsOrEmpty treats a missing argument string as an empty JSON object
+func argumentsOrEmpty(arguments string) string {
+	if strings.TrimSpace(arguments) == "" {
+		return "{}"
+	}
+	return arguments
+}
+
+func toolErrorJSON(toolErr *ToolError) string {
+	resultJSON, err := json.Marshal(toolErr)
+	if err != nil {
+		return fmt.Sprintf(`{"status": "error", "message": %q}`, toolErr.Message)
+	}
+	return string(resultJSON)
+}
@@ -0,0 +1,358 @@
+package toolset_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cohesion-org/deepseek-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/metrics"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+	"github.com/yeeaiclub/github-a2a/server/toolset/fakellm"
+	itypes "github.com/yeeaiclub/github-a2a/types"
+)
+
+type listReposArgs struct {
+	Owner string `json:"owner" description:"Owner of the repositories" required:"true"`
+	Limit int    `json:"limit" description:"Maximum number of repositories" default:"10" minimum:"1" maximum:"100"`
+}
+
+// repoTool is a fake list_repos tool recording the arguments it was called with
+type repoTool struct {
+	mu    sync.Mutex
+	calls []listReposArgs
+}
+
+func (r *repoTool) function() itypes.Function {
+	return toolset.NewTypedTool("list_repos", "List the repositories of an owner",
+		func(ctx context.Context, args listReposArgs) interface{} {
+			r.mu.Lock()
+			r.calls = append(r.calls, args)
+			r.mu.Unlock()
+			if args.Owner == "missing" {
+				return &itypes.RepositoryResponse{GitHubResponse: itypes.GitHubResponse{
+					Status: "error", Message: "owner not found",
+				}}
+			}
+			count := 1
+			return &itypes.RepositoryResponse{
+				GitHubResponse: itypes.GitHubResponse{Status: "success", Message: "found 1 repository", Count: &count},
+				Data:           []itypes.GitHubRepository{{Name: "demo", FullName: args.Owner + "/demo", Stars: 42}},
+			}
+		})
+}
+
+func (r *repoTool) tools() map[string]itypes.Function {
+	return map[string]itypes.Function{"list_repos": r.function()}
+}
+
+// lastMessage returns the newest message of a request
+func lastMessage(request *deepseek.ChatCompletionRequest) deepseek.ChatCompletionMessage {
+	return request.Messages[len(request.Messages)-1]
+}
+
+func TestExecuteAnswersWithoutTools(t *testing.T) {
+	llm := fakellm.New(
+		fakellm.Reply("Hello from the agent").Expecting(func(request *deepseek.ChatCompletionRequest) error {
+			if len(request.Messages) != 2 || request.Messages[0].Role != deepseek.ChatMessageRoleSystem {
+				return fmt.Errorf("want system prompt and user message, got %d messages", len(request.Messages))
+			}
+			if got := lastMessage(request).Content; got != "hi" {
+				return fmt.Errorf("user message = %q", got)
+			}
+			return nil
+		}),
+	)
+
+	result := execute(t, newExecutor(llm, (&repoTool{}).tools()), text("hi"))
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	assertStates(t, result.states(), types.SUBMITTED, types.WORKING, types.COMPLETED)
+	result.final(t)
+	if got := result.answer(); got != "Hello from the agent" {
+		t.Errorf("answer = %q", got)
+	}
+	if llm.Remaining() != 0 {
+		t.Errorf("%d scripted steps were not used", llm.Remaining())
+	}
+}
+
+func TestExecuteCallsToolAndEmitsDataArtifact(t *testing.T) {
+	tool := &repoTool{}
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("list_repos", map[string]any{"owner": "octo"})).
+			Expecting(func(request *deepseek.ChatCompletionRequest) error {
+				if len(request.Tools) != 1 || request.Tools[0].Function.Name != "list_repos" {
+					return fmt.Errorf("tools offered = %v", request.Tools)
+				}
+				return nil
+			}),
+		fakellm.Reply("octo owns demo").Expecting(func(request *deepseek.ChatCompletionRequest) error {
+			result := lastMessage(request)
+			if result.Role != deepseek.ChatMessageRoleTool || result.ToolCallID != "call_1" {
+				return fmt.Errorf("last message is %s for %q, want the tool result", result.Role, result.ToolCallID)
+			}
+			if !strings.Contains(result.Content, "octo/demo") {
+				return fmt.Errorf("tool result = %s", result.Content)
+			}
+			return nil
+		}),
+	)
+
+	result := execute(t, newExecutor(llm, tool.tools()), text("what does octo own?"))
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	assertStates(t, result.states(), types.SUBMITTED, types.WORKING, types.WORKING, types.COMPLETED)
+	if got := messageText(result.statuses[2].Status.Message); got != "Processing tool calls..." {
+		t.Errorf("working message = %q", got)
+	}
+	if len(tool.calls) != 1 || tool.calls[0].Owner != "octo" || tool.calls[0].Limit != 10 {
+		t.Errorf("tool calls = %+v, want owner octo with the default limit", tool.calls)
+	}
+	if got := result.answer(); got != "octo owns demo" {
+		t.Errorf("answer = %q", got)
+	}
+
+	data := result.dataArtifacts()
+	if len(data) != 1 {
+		t.Fatalf("%d data artifacts, want 1", len(data))
+	}
+	if data[0].Name != "list_repos" || data[0].Metadata["schema"] != itypes.SchemaRepositories {
+		t.Errorf("data artifact %q with metadata %v", data[0].Name, data[0].Metadata)
+	}
+	part := data[0].Parts[0].(*types.DataPart)
+	encoded, _ := json.Marshal(part.Data)
+	var decoded itypes.RepositoryResponse
+	if err := json.Unmarshal(encoded, &decoded); err != nil || len(decoded.Data) != 1 || decoded.Data[0].Stars != 42 {
+		t.Errorf("data part %s does not decode into the response: %v", encoded, err)
+	}
+}
+
+func TestExecuteSkipsDataArtifactForFailedTool(t *testing.T) {
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("list_repos", map[string]any{"owner": "missing"})),
+		fakellm.Reply("that owner does not exist"),
+	)
+
+	result := execute(t, newExecutor(llm, (&repoTool{}).tools()), text("list missing"))
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	if data := result.dataArtifacts(); len(data) != 0 {
+		t.Errorf("%d data artifacts for a failed tool call", len(data))
+	}
+	if content := lastMessage(&llm.Requests()[1]).Content; !strings.Contains(content, "owner not found") {
+		t.Errorf("tool result = %s", content)
+	}
+}
+
+func TestExecuteReturnsValidationErrorsAndWithdrawsTool(t *testing.T) {
+	tool := &repoTool{}
+	invalid := fakellm.ToolCall("list_repos", map[string]any{"limit": 500})
+	llm := fakellm.New(
+		fakellm.CallTools(invalid),
+		fakellm.CallTools(invalid).Expecting(func(request *deepseek.ChatCompletionRequest) error {
+			var feedback toolset.ToolError
+			if err := json.Unmarshal([]byte(lastMessage(request).Content), &feedback); err != nil {
+				return err
+			}
+			fields := map[string]bool{}
+			for _, e := range feedback.Errors {
+				fields[e.Field] = true
+			}
+			if feedback.Status != "error" || !fields["owner"] || !fields["limit"] {
+				return fmt.Errorf("feedback = %+v, want errors for owner and limit", feedback)
+			}
+			return nil
+		}),
+		fakellm.Reply("I could not list the repositories").Expecting(func(request *deepseek.ChatCompletionRequest) error {
+			if len(request.Tools) != 0 {
+				return fmt.Errorf("%d tools still offered after repeated invalid calls", len(request.Tools))
+			}
+			return nil
+		}),
+	)
+
+	result := execute(t, newExecutor(llm, tool.tools(), toolset.WithMaxRepairAttempts(1)), text("list repos"))
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	if len(tool.calls) != 0 {
+		t.Errorf("tool handler ran with invalid arguments: %+v", tool.calls)
+	}
+	if result.final(t).Status.State != types.COMPLETED {
+		t.Errorf("final state = %s", result.final(t).Status.State)
+	}
+}
+
+func TestExecuteReportsUnknownTool(t *testing.T) {
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("delete_everything", map[string]any{})),
+		fakellm.Reply("done").Expecting(func(request *deepseek.ChatCompletionRequest) error {
+			if content := lastMessage(request).Content; !strings.Contains(content, "does not exist") {
+				return fmt.Errorf("tool result = %s", content)
+			}
+			return nil
+		}),
+	)
+
+	unknown := metrics.ToolCallsTotal.WithLabelValues(metrics.UnknownTool, "unknown_tool")
+	before := testutil.ToFloat64(unknown)
+	result := execute(t, newExecutor(llm, (&repoTool{}).tools()), text("go"))
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	assertStates(t, result.states(), types.SUBMITTED, types.WORKING, types.WORKING, types.COMPLETED)
+
+	// The name the model made up is not used as a label value
+	if got := testutil.ToFloat64(unknown) - before; got != 1 {
+		t.Errorf("%v unknown tool calls counted, want 1", got)
+	}
+	if metrics.ToolCallsTotal.DeleteLabelValues("delete_everything", "unknown_tool") {
+		t.Error("calls counted under the made up tool name")
+	}
+}
+
+func TestExecuteStopsAfterMaxIterations(t *testing.T) {
+	call := fakellm.ToolCall("list_repos", map[string]any{"owner": "octo"})
+	llm := fakellm.New(fakellm.CallTools(call), fakellm.CallTools(call))
+
+	result := execute(t, newExecutor(llm, (&repoTool{}).tools(), toolset.WithMaxIterations(2)), text("loop"))
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	final := result.final(t)
+	if final.Status.State != types.COMPLETED || !strings.Contains(messageText(final.Status.Message), "maximum number of iterations") {
+		t.Errorf("final status %s with message %q", final.Status.State, messageText(final.Status.Message))
+	}
+	if len(llm.Requests()) != 2 {
+		t.Errorf("%d completions, want 2", len(llm.Requests()))
+	}
+}
+
+func TestExecuteReturnsModelError(t *testing.T) {
+	llm := fakellm.New(fakellm.Fail(errors.New("rate limited")))
+
+	result := execute(t, newExecutor(llm, (&repoTool{}).tools()), text("hi"))
+	if result.err == nil || !strings.Contains(result.err.Error(), "rate limited") {
+		t.Fatalf("Execute error = %v, want the model error", result.err)
+	}
+	assertStates(t, result.states(), types.SUBMITTED, types.WORKING)
+}
+
+func TestExecuteRejectsBinaryFile(t *testing.T) {
+	llm := fakellm.New()
+	file := &types.FilePart{Kind: "file", File: &types.FileWithBytes{
+		FileBase: types.FileBase{Name: "logo.png", MimeType: "image/png"},
+		Bytes:    base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G', 0, 0}),
+	}}
+
+	result := execute(t, newExecutor(llm, (&repoTool{}).tools()), text("what is this?"), file)
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	final := result.final(t)
+	if final.Status.State != types.REJECTED || !strings.Contains(messageText(final.Status.Message), "logo.png is not a text file") {
+		t.Errorf("final status %s with message %q", final.Status.State, messageText(final.Status.Message))
+	}
+	if len(llm.Requests()) != 0 {
+		t.Errorf("rejected message reached the model")
+	}
+}
+
+func TestExecuteAppliesFilesAndParameters(t *testing.T) {
+	tool := &repoTool{}
+	csv := "owner\nocto\n"
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("list_repos", map[string]any{})).
+			Expecting(func(request *deepseek.ChatCompletionRequest) error {
+				content := lastMessage(request).Content
+				if !strings.Contains(content, "Attached file owners.csv") || !strings.Contains(content, csv) {
+					return fmt.Errorf("user message does not include the file: %s", content)
+				}
+				return nil
+			}),
+		fakellm.Reply("done"),
+	)
+	file := &types.FilePart{Kind: "file", File: &types.FileWithBytes{
+		FileBase: types.FileBase{Name: "owners.csv", MimeType: "text/csv"},
+		Bytes:    base64.StdEncoding.EncodeToString([]byte(csv)),
+	}}
+	data := &types.DataPart{Kind: "data", Data: map[string]any{"owner": "octo", "limit": 3}}
+
+	result := execute(t, newExecutor(llm, tool.tools()), text("list the repos"), file, data)
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	if len(tool.calls) != 1 || tool.calls[0].Owner != "octo" || tool.calls[0].Limit != 3 {
+		t.Errorf("tool calls = %+v, want the arguments of the data part", tool.calls)
+	}
+}
+
+func TestExecuteOverHTTP(t *testing.T) {
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("list_repos", map[string]any{"owner": "octo"})),
+		fakellm.Reply("octo owns demo"),
+	)
+	server := httptest.NewServer(llm)
+	defer server.Close()
+
+	executor := toolset.NewExecutor(nil, nil, (&repoTool{}).tools(), "test-key", "You are a test agent.",
+		toolset.WithBaseURL(server.URL+"/"))
+	result := execute(t, executor, text("what does octo own?"))
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	if got := result.answer(); got != "octo owns demo" {
+		t.Errorf("answer = %q", got)
+	}
+	requests := llm.Requests()
+	if len(requests) != 2 || requests[0].Model != "deepseek-chat" {
+		t.Fatalf("requests = %+v", requests)
+	}
+	if last := lastMessage(&requests[1]); last.Role != deepseek.ChatMessageRoleTool || !strings.Contains(last.Content, "octo/demo") {
+		t.Errorf("tool result sent over HTTP = %+v", last)
+	}
+}
+
+func TestExecuteAnswersOnLastIteration(t *testing.T) {
+	call := fakellm.ToolCall("list_repos", map[string]any{"owner": "octo"})
+	llm := fakellm.New(fakellm.CallTools(call), fakellm.Reply("octo has one repository"))
+
+	result := execute(t, newExecutor(llm, (&repoTool{}).tools(), toolset.WithMaxIterations(2)), text("list"))
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	assertStates(t, result.states(), types.SUBMITTED, types.WORKING, types.WORKING, types.COMPLETED)
+	final := result.final(t)
+	if final.Status.Message != nil {
+		t.Errorf("answer overwritten by %q", messageText(final.Status.Message))
+	}
+	if got := result.answer(); got != "octo has one repository" {
+		t.Errorf("answer = %q", got)
+	}
+}
+
+func TestExecuteFailsOnEmptyAnswer(t *testing.T) {
+	llm := fakellm.New(fakellm.Reply(""))
+
+	result := execute(t, newExecutor(llm, (&repoTool{}).tools()), text("hi"))
+	if result.err != nil {
+		t.Fatalf("Execute: %v", result.err)
+	}
+	assertStates(t, result.states(), types.SUBMITTED, types.WORKING, types.FAILED)
+	final := result.final(t)
+	if !strings.Contains(messageText(final.Status.Message), "empty answer") {
+		t.Errorf("final status %s with message %q", final.Status.State, messageText(final.Status.Message))
+	}
+}
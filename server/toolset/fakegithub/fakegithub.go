@@ -0,0 +1,431 @@
+// Package fakegithub provides a local GitHub API for tests. It serves fixture data on the REST
+// routes the toolset uses, paginates like GitHub with Link headers, and can be told to fail routes.
+// GraphQL queries are answered by a handler the test installs.
+package fakegithub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+const (
+	defaultPerPage = 30
+	maxPerPage     = 100
+)
+
+// Fixtures is the data served by the fake API. Repository keyed maps use "owner/repo".
+type Fixtures struct {
+	// Users are served on /users/{user}, the first one is the authenticated user
+	Users []*github.User
+	// Repos belong to the user named by their Owner.Login
+	Repos   []*github.Repository
+	Commits map[string][]*github.RepositoryCommit
+	Issues  map[string][]*github.Issue
+	Pulls   map[string][]*github.PullRequest
+	Runs    map[string][]*github.WorkflowRun
+	Alerts  map[string][]*github.DependabotAlert
+}
+
+// GraphQLRequest is a query sent to the GraphQL endpoint
+type GraphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// GraphQLHandler answers a query with the data field, or with errors in the GraphQL format
+type GraphQLHandler func(request GraphQLRequest) (data any, errs []map[string]any)
+
+// Server is a running fake GitHub API, point the toolset at URL with toolset.WithGitHubBaseURL
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	fixtures Fixtures
+	graphql  GraphQLHandler
+	failures map[string]failure
+	requests []string
+	headers  []http.Header
+}
+
+type failure struct {
+	status  int
+	message string
+}
+
+// New starts a fake API serving the fixtures, close it when done
+func New(fixtures Fixtures) *Server {
+	s := &Server{fixtures: fixtures, failures: map[string]failure{}}
+	mux := http.NewServeMux()
+	s.route(mux, "GET /user", s.authenticatedUser)
+	s.route(mux, "GET /users/{user}", s.user)
+	s.route(mux, "GET /user/repos", s.userRepos)
+	s.route(mux, "GET /users/{user}/repos", s.userRepos)
+	s.route(mux, "GET /search/repositories", s.searchRepos)
+	s.route(mux, "GET /repos/{owner}/{repo}/commits", s.commits)
+	s.route(mux, "GET /repos/{owner}/{repo}/issues", s.issues)
+	s.route(mux, "GET /repos/{owner}/{repo}/pulls", s.pulls)
+	s.route(mux, "GET /repos/{owner}/{repo}/actions/runs", s.runs)
+	s.route(mux, "GET /repos/{owner}/{repo}/dependabot/alerts", s.alerts)
+	s.route(mux, "POST /graphql", s.graphQL)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		s.record(r)
+		writeError(w, http.StatusNotFound, "Not Found")
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Fail makes a route answer with status and message until Reset, the route is a pattern
+// registered by New such as "GET /repos/{owner}/{repo}/commits"
+func (s *Server) Fail(route string, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[route] = failure{status: status, message: message}
+}
+
+// Reset removes the failures set with Fail
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = map[string]failure{}
+}
+
+// HandleGraphQL installs the handler answering GraphQL queries
+func (s *Server) HandleGraphQL(handler GraphQLHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.graphql = handler
+}
+
+// Requests returns the method, path and query of every request received so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Headers returns the headers of every request received so far, in the order of Requests
+func (s *Server) Headers() []http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]http.Header(nil), s.headers...)
+}
+
+func (s *Server) route(mux *http.ServeMux, pattern string, handler http.HandlerFunc) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		s.record(r)
+		s.mu.Lock()
+		fail, failing := s.failures[pattern]
+		s.mu.Unlock()
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Header().Set("X-RateLimit-Resource", "core")
+		if failing {
+			writeError(w, fail.status, fail.message)
+			return
+		}
+		handler(w, r)
+	})
+}
+
+func (s *Server) record(r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+	s.headers = append(s.headers, r.Header.Clone())
+}
+
+func (s *Server) authenticatedUser(w http.ResponseWriter, r *http.Request) {
+	if len(s.fixtures.Users) == 0 {
+		writeError(w, http.StatusUnauthorized, "Requires authentication")
+		return
+	}
+	writeJSON(w, s.fixtures.Users[0])
+}
+
+func (s *Server) user(w http.ResponseWriter, r *http.Request) {
+	for _, user := range s.fixtures.Users {
+		if strings.EqualFold(user.GetLogin(), r.PathValue("user")) {
+			writeJSON(w, user)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Not Found")
+}
+
+func (s *Server) userRepos(w http.ResponseWriter, r *http.Request) {
+	login := r.PathValue("user")
+	if login == "" && len(s.fixtures.Users) > 0 {
+		login = s.fixtures.Users[0].GetLogin()
+	}
+	var repos []*github.Repository
+	for _, repo := range s.fixtures.Repos {
+		if strings.EqualFold(repo.GetOwner().GetLogin(), login) {
+			repos = append(repos, repo)
+		}
+	}
+	sort.SliceStable(repos, func(i, j int) bool {
+		return repos[i].GetUpdatedAt().After(repos[j].GetUpdatedAt().Time)
+	})
+	writePage(w, r, repos)
+}
+
+// searchRepos matches the words of the query against names and descriptions and
+// supports the pushed:>=YYYY-MM-DD qualifier
+func (s *Server) searchRepos(w http.ResponseWriter, r *http.Request) {
+	var words []string
+	var pushedSince time.Time
+	for _, term := range strings.Fields(r.URL.Query().Get("q")) {
+		if value, ok := strings.CutPrefix(term, "pushed:>="); ok {
+			since, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+				return
+			}
+			pushedSince = since
+			continue
+		}
+		words = append(words, strings.ToLower(term))
+	}
+
+	var items []*github.Repository
+	for _, repo := range s.fixtures.Repos {
+		text := strings.ToLower(repo.GetFullName() + " " + repo.GetDescription())
+		matches := !repo.GetPushedAt().Before(pushedSince)
+		for _, word := range words {
+			matches = matches && strings.Contains(text, word)
+		}
+		if matches {
+			items = append(items, repo)
+		}
+	}
+	if r.URL.Query().Get("sort") == "stars" {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].GetStargazersCount() > items[j].GetStargazersCount() })
+	} else {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].GetUpdatedAt().After(items[j].GetUpdatedAt().Time) })
+	}
+
+	page, ok := paginate(w, r, items)
+	if !ok {
+		return
+	}
+	total := len(items)
+	writeJSON(w, &github.RepositoriesSearchResult{Total: &total, Repositories: page})
+}
+
+func (s *Server) commits(w http.ResponseWriter, r *http.Request) {
+	commits, ok := repoItems(w, r, s.fixtures.Commits)
+	if !ok {
+		return
+	}
+	if value := r.URL.Query().Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+			return
+		}
+		var recent []*github.RepositoryCommit
+		for _, commit := range commits {
+			if !commit.GetCommit().GetAuthor().GetDate().Before(since) {
+				recent = append(recent, commit)
+			}
+		}
+		commits = recent
+	}
+	writePage(w, r, commits)
+}
+
+func (s *Server) issues(w http.ResponseWriter, r *http.Request) {
+	issues, ok := repoItems(w, r, s.fixtures.Issues)
+	if !ok {
+		return
+	}
+	writePage(w, r, filterState(issues, r.URL.Query().Get("state"), (*github.Issue).GetState))
+}
+
+func (s *Server) pulls(w http.ResponseWriter, r *http.Request) {
+	pulls, ok := repoItems(w, r, s.fixtures.Pulls)
+	if !ok {
+		return
+	}
+	writePage(w, r, filterState(pulls, r.URL.Query().Get("state"), (*github.PullRequest).GetState))
+}
+
+func (s *Server) runs(w http.ResponseWriter, r *http.Request) {
+	runs, ok := repoItems(w, r, s.fixtures.Runs)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	var filtered []*github.WorkflowRun
+	for _, run := range runs {
+		if branch := query.Get("branch"); branch != "" && run.GetHeadBranch() != branch {
+			continue
+		}
+		if status := query.Get("status"); status != "" && run.GetStatus() != status && run.GetConclusion() != status {
+			continue
+		}
+		filtered = append(filtered, run)
+	}
+	page, ok := paginate(w, r, filtered)
+	if !ok {
+		return
+	}
+	total := len(filtered)
+	writeJSON(w, &github.WorkflowRuns{TotalCount: &total, WorkflowRuns: page})
+}
+
+func (s *Server) alerts(w http.ResponseWriter, r *http.Request) {
+	alerts, ok := repoItems(w, r, s.fixtures.Alerts)
+	if !ok {
+		return
+	}
+	alerts = filterState(alerts, r.URL.Query().Get("state"), (*github.DependabotAlert).GetState)
+	if severity := r.URL.Query().Get("severity"); severity != "" {
+		var filtered []*github.DependabotAlert
+		for _, alert := range alerts {
+			if alert.GetSecurityAdvisory().GetSeverity() == severity {
+				filtered = append(filtered, alert)
+			}
+		}
+		alerts = filtered
+	}
+	writePage(w, r, alerts)
+}
+
+func (s *Server) graphQL(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	handler := s.graphql
+	s.mu.Unlock()
+	if handler == nil {
+		writeError(w, http.StatusNotImplemented, "No GraphQL handler installed")
+		return
+	}
+	var request GraphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	data, errs := handler(request)
+	response := map[string]any{"data": data}
+	if len(errs) > 0 {
+		response["errors"] = errs
+	}
+	writeJSON(w, response)
+}
+
+// repoItems returns the fixture items of the repository in the path, answering 404 for unknown repositories
+func repoItems[T any](w http.ResponseWriter, r *http.Request, items map[string][]T) ([]T, bool) {
+	key := r.PathValue("owner") + "/" + r.PathValue("repo")
+	for name, repoItems := range items {
+		if strings.EqualFold(name, key) {
+			return repoItems, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "Not Found")
+	return nil, false
+}
+
+// filterState keeps the items in state, open when empty and every item for all
+func filterState[T any](items []T, state string, get func(T) string) []T {
+	if state == "" {
+		state = "open"
+	}
+	if state == "all" {
+		return items
+	}
+	var filtered []T
+	for _, item := range items {
+		if get(item) == state {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// writePage answers with the requested page of items
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, ok := paginate(w, r, items)
+	if !ok {
+		return
+	}
+	writeJSON(w, page)
+}
+
+// paginate selects the page and per_page window of items and sets the Link header like GitHub does
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T) ([]T, bool) {
+	query := r.URL.Query()
+	page, perPage := 1, defaultPerPage
+	var err error
+	if value := query.Get("page"); value != "" {
+		if page, err = strconv.Atoi(value); err != nil || page < 1 {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+			return nil, false
+		}
+	}
+	if value := query.Get("per_page"); value != "" {
+		if perPage, err = strconv.Atoi(value); err != nil || perPage < 1 {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+			return nil, false
+		}
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	lastPage := (len(items) + perPage - 1) / perPage
+	var links []string
+	link := func(target int, rel string) {
+		u := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path}
+		values := r.URL.Query()
+		values.Set("page", strconv.Itoa(target))
+		u.RawQuery = values.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel))
+	}
+	if page < lastPage {
+		link(page+1, "next")
+		link(lastPage, "last")
+	}
+	if page > 1 {
+		link(1, "first")
+		link(page-1, "prev")
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	start := (page - 1) * perPage
+	if start >= len(items) {
+		return []T{}, true
+	}
+	end := start + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end], true
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError answers in the format of GitHub API errors
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":           message,
+		"documentation_url": "https://docs.github.com/rest",
+	})
+}
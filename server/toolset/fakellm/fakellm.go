@@ -0,0 +1,195 @@
+// Package fakellm provides a deterministic chat model for tests. A script of steps answers the
+// completion requests in order, either in process as a toolset.ChatClient or over HTTP as an
+// OpenAI compatible endpoint the real DeepSeek client can be pointed at.
+package fakellm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/cohesion-org/deepseek-go"
+)
+
+// Model is the model name reported in responses
+const Model = "fake-model"
+
+// ErrScriptExhausted is returned for requests after the last step
+var ErrScriptExhausted = errors.New("fakellm: no scripted step left")
+
+// Step answers a single completion request
+type Step struct {
+	// Expect checks the request before it is answered, an error fails the completion
+	Expect func(request *deepseek.ChatCompletionRequest) error
+	// Message is the assistant message returned
+	Message deepseek.Message
+	// Err fails the completion instead of answering
+	Err error
+}
+
+// Reply answers with a final assistant message
+func Reply(content string) Step {
+	return Step{Message: deepseek.Message{Role: deepseek.ChatMessageRoleAssistant, Content: content}}
+}
+
+// CallTools answers with tool calls, their IDs are assigned in order when empty
+func CallTools(calls ...deepseek.ToolCall) Step {
+	return Step{Message: deepseek.Message{Role: deepseek.ChatMessageRoleAssistant, ToolCalls: calls}}
+}
+
+// Fail answers with an error
+func Fail(err error) Step {
+	return Step{Err: err}
+}
+
+// ToolCall creates a call of the named tool, arguments are encoded as JSON unless they are a string
+func ToolCall(name string, arguments any) deepseek.ToolCall {
+	encoded, ok := arguments.(string)
+	if !ok {
+		raw, err := json.Marshal(arguments)
+		if err != nil {
+			panic(fmt.Sprintf("fakellm: encode arguments of %s: %v", name, err))
+		}
+		encoded = string(raw)
+	}
+	return deepseek.ToolCall{
+		Type:     "function",
+		Function: deepseek.ToolCallFunction{Name: name, Arguments: encoded},
+	}
+}
+
+// Expecting returns the step with a check of the request it answers
+func (s Step) Expecting(expect func(request *deepseek.ChatCompletionRequest) error) Step {
+	s.Expect = expect
+	return s
+}
+
+// LLM answers completion requests with its script
+type LLM struct {
+	mu       sync.Mutex
+	steps    []Step
+	requests []deepseek.ChatCompletionRequest
+	calls    int
+}
+
+// New creates a model answering with the steps in order
+func New(steps ...Step) *LLM {
+	return &LLM{steps: steps}
+}
+
+// CreateChatCompletion answers with the next step of the script
+func (l *LLM) CreateChatCompletion(ctx context.Context, request *deepseek.ChatCompletionRequest) (*deepseek.ChatCompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, *request)
+	index := len(l.requests) - 1
+	if index >= len(l.steps) {
+		return nil, fmt.Errorf("%w, request %d", ErrScriptExhausted, index+1)
+	}
+
+	step := l.steps[index]
+	if step.Expect != nil {
+		if err := step.Expect(request); err != nil {
+			return nil, fmt.Errorf("fakellm: request %d: %w", index+1, err)
+		}
+	}
+	if step.Err != nil {
+		return nil, step.Err
+	}
+
+	message := step.Message
+	message.ToolCalls = append([]deepseek.ToolCall(nil), message.ToolCalls...)
+	for i := range message.ToolCalls {
+		if message.ToolCalls[i].ID == "" {
+			l.calls++
+			message.ToolCalls[i].ID = fmt.Sprintf("call_%d", l.calls)
+		}
+		message.ToolCalls[i].Index = i
+	}
+	finishReason := "stop"
+	if len(message.ToolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+
+	promptTokens := estimateTokens(request.Messages)
+	completionTokens := utf8.RuneCountInString(message.Content)/4 + 1
+	return &deepseek.ChatCompletionResponse{
+		ID:      fmt.Sprintf("fake-%d", index+1),
+		Object:  "chat.completion",
+		Model:   Model,
+		Choices: []deepseek.Choice{{Index: 0, Message: message, FinishReason: finishReason}},
+		Usage: deepseek.Usage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		},
+	}, nil
+}
+
+// Requests returns the requests received so far
+func (l *LLM) Requests() []deepseek.ChatCompletionRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]deepseek.ChatCompletionRequest(nil), l.requests...)
+}
+
+// Remaining returns how many steps have not been used
+func (l *LLM) Remaining() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.requests) >= len(l.steps) {
+		return 0
+	}
+	return len(l.steps) - len(l.requests)
+}
+
+// ServeHTTP answers OpenAI compatible chat completion requests, for use with httptest.NewServer
+// and the base URL of the DeepSeek client
+func (l *LLM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	var request deepseek.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := l.CreateChatCompletion(r.Context(), &request)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeError answers with an error in the format of the OpenAI API
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": message, "type": "fakellm_error"},
+	})
+}
+
+// estimateTokens counts four characters per token, close enough for the budget calibration
+func estimateTokens(messages []deepseek.ChatCompletionMessage) int {
+	chars := 0
+	for _, message := range messages {
+		chars += utf8.RuneCountInString(message.Content)
+		for _, call := range message.ToolCalls {
+			chars += utf8.RuneCountInString(call.Function.Name) + utf8.RuneCountInString(call.Function.Arguments)
+		}
+	}
+	return chars/4 + 4*len(messages)
+}
@@ -0,0 +1,54 @@
+package toolset
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// RepositoryFingerprint summarizes the state of a repository as its latest commit and its most recently
+// updated issue or pull request. The fingerprint changes with every push, new or updated issue and pull
+// request, and comment, while stars and forks leave it alone.
+func (g *GitHubToolset) RepositoryFingerprint(ctx context.Context, repoName string) (string, error) {
+	owner, repo, ok := splitRepoName(repoName)
+	if !ok {
+		return "", fmt.Errorf("repository name %q must be in format 'owner/repo'", repoName)
+	}
+
+	var head string
+	commits, _, err := g.client.Repositories.ListCommits(ctx, owner, repo, &github.CommitsListOptions{
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	var apiErr *github.ErrorResponse
+	switch {
+	// An empty repository has no commits yet
+	case errors.As(err, &apiErr) && apiErr.Response.StatusCode == http.StatusConflict:
+	case err != nil:
+		return "", fmt.Errorf("list commits of %s: %w", repoName, err)
+	case len(commits) > 0:
+		head = commits[0].GetSHA()
+	}
+
+	var updated string
+	issues, _, err := g.client.Issues.ListByRepo(ctx, owner, repo, &github.IssueListByRepoOptions{
+		State:       "all",
+		Sort:        "updated",
+		Direction:   "desc",
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return "", fmt.Errorf("list issues of %s: %w", repoName, err)
+	}
+	if len(issues) > 0 {
+		updated = fmt.Sprintf("%d@%s", issues[0].GetNumber(), issues[0].GetUpdatedAt().Format(time.RFC3339))
+	}
+
+	sum := sha256.Sum256([]byte(head + "\n" + updated))
+	return hex.EncodeToString(sum[:8]), nil
+}
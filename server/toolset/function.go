@@ -1,159 +1,154 @@
 package toolset
 
 import (
-	"fmt"
+	"context"
 
-	"github.com/cohesion-org/deepseek-go"
+	"github.com/yeeaiclub/github-a2a/types"
 )
 
-type GetUserRepositoriesTool struct {
-	toolset *GitHubToolset
-}
-
-func (t *GetUserRepositoriesTool) FunctionDefinition() deepseek.Function {
-	return deepseek.Function{
-		Name:        "get_user_repositories",
-		Description: "Get user's repository list with filtering by recent update time",
-		Parameters: &deepseek.FunctionParameters{
-			Type: "object",
-			Properties: map[string]interface{}{
-				"username": map[string]interface{}{
-					"type":        "string",
-					"description": "GitHub username, if not provided will get repositories of the current authenticated user",
-				},
-				"days": map[string]interface{}{
-					"type":        "integer",
-					"description": "Filter repositories updated within how many days, default is 30 days",
-				},
-				"limit": map[string]interface{}{
-					"type":        "integer",
-					"description": "Limit the number of returned results, default is 10",
-				},
-			},
-		},
-	}
+type getUserRepositoriesArgs struct {
+	Username string `json:"username" description:"GitHub username, if not provided will get repositories of the current authenticated user"`
+	Days     int    `json:"days" description:"Filter repositories updated within how many days" default:"30" minimum:"1"`
+	Limit    int    `json:"limit" description:"Limit the number of returned results" default:"10" minimum:"1" maximum:"100"`
 }
 
-func (t *GetUserRepositoriesTool) Call(args map[string]interface{}) interface{} {
-	var username *string
-	var days *int
-	var limit *int
+func newGetUserRepositoriesTool(g *GitHubToolset) types.Function {
+	return NewTypedTool("get_user_repositories",
+		"Get user's repository list with filtering by recent update time",
+		func(ctx context.Context, args getUserRepositoriesArgs) interface{} {
+			return g.GetUserRepositories(ctx, optional(args.Username), &args.Days, &args.Limit)
+		})
+}
 
-	if val, ok := args["username"].(string); ok && val != "" {
-		username = &val
-	}
-	if val, ok := args["days"].(float64); ok {
-		intVal := int(val)
-		days = &intVal
-	}
-	if val, ok := args["limit"].(float64); ok {
-		intVal := int(val)
-		limit = &intVal
-	}
+type getRecentCommitsArgs struct {
+	RepoName string `json:"repoName" description:"Repository name in format 'owner/repo', e.g. 'microsoft/vscode'" required:"true"`
+	Days     int    `json:"days" description:"Get commits within how many days" default:"7" minimum:"1"`
+	Limit    int    `json:"limit" description:"Limit the number of returned results" default:"10" minimum:"1" maximum:"100"`
+}
 
-	result := t.toolset.GetUserRepositories(username, days, limit)
-	fmt.Printf("GetUserRepositories result: %+v\n", result)
-	return result
-}
-
-type GetRecentCommitsTool struct {
-	toolset *GitHubToolset
-}
-
-func (t *GetRecentCommitsTool) FunctionDefinition() deepseek.Function {
-	return deepseek.Function{
-		Name:        "get_recent_commits",
-		Description: "Get recent commit records for a specific repository",
-		Parameters: &deepseek.FunctionParameters{
-			Type: "object",
-			Properties: map[string]interface{}{
-				"repoName": map[string]interface{}{
-					"type":        "string",
-					"description": "Repository name in format 'owner/repo', e.g. 'microsoft/vscode'",
-				},
-				"days": map[string]interface{}{
-					"type":        "integer",
-					"description": "Get commits within how many days, default is 7 days",
-				},
-				"limit": map[string]interface{}{
-					"type":        "integer",
-					"description": "Limit the number of returned results, default is 10",
-				},
-			},
-			Required: []string{"repoName"},
-		},
-	}
+func newGetRecentCommitsTool(g *GitHubToolset) types.Function {
+	return NewTypedTool("get_recent_commits",
+		"Get recent commit records for a specific repository",
+		func(ctx context.Context, args getRecentCommitsArgs) interface{} {
+			return g.GetRecentCommits(ctx, args.RepoName, &args.Days, &args.Limit)
+		})
 }
 
-func (t *GetRecentCommitsTool) Call(args map[string]interface{}) interface{} {
-	repoName, ok := args["repoName"].(string)
-	if !ok {
-		return map[string]string{"error": "repoName is required"}
-	}
+type searchRepositoriesArgs struct {
+	Query string `json:"query" description:"Search keywords, e.g. 'machine learning' or 'react'" required:"true"`
+	Sort  string `json:"sort" description:"Sort method" enum:"stars,forks,updated" default:"updated"`
+	Limit int    `json:"limit" description:"Limit the number of returned results" default:"10" minimum:"1" maximum:"100"`
+}
 
-	var days *int
-	var limit *int
+func newSearchRepositoriesTool(g *GitHubToolset) types.Function {
+	return NewTypedTool("search_repositories",
+		"Search repositories with recent activity",
+		func(ctx context.Context, args searchRepositoriesArgs) interface{} {
+			return g.SearchRepositories(ctx, args.Query, &args.Sort, &args.Limit)
+		})
+}
 
-	if val, ok := args["days"].(float64); ok {
-		intVal := int(val)
-		days = &intVal
-	}
-	if val, ok := args["limit"].(float64); ok {
-		intVal := int(val)
-		limit = &intVal
-	}
+type getRepositoryOverviewsArgs struct {
+	Owner string `json:"owner" description:"GitHub user or organization login, if not provided will get repositories of the current authenticated user"`
+	Limit int    `json:"limit" description:"Limit the number of returned results" default:"10" minimum:"1" maximum:"100"`
+}
 
-	result := t.toolset.GetRecentCommits(repoName, days, limit)
-	return result
-}
-
-type SearchRepositoriesTool struct {
-	toolset *GitHubToolset
-}
-
-func (t *SearchRepositoriesTool) FunctionDefinition() deepseek.Function {
-	return deepseek.Function{
-		Name:        "search_repositories",
-		Description: "Search repositories with recent activity",
-		Parameters: &deepseek.FunctionParameters{
-			Type: "object",
-			Properties: map[string]interface{}{
-				"query": map[string]interface{}{
-					"type":        "string",
-					"description": "Search keywords, e.g. 'machine learning' or 'react'",
-				},
-				"sort": map[string]interface{}{
-					"type":        "string",
-					"description": "Sort method, options: 'stars', 'forks', 'updated', default is 'updated'",
-				},
-				"limit": map[string]interface{}{
-					"type":        "integer",
-					"description": "Limit the number of returned results, default is 10",
-				},
-			},
-			Required: []string{"query"},
-		},
-	}
+func newGetRepositoryOverviewsTool(g *GitHubToolset) types.Function {
+	return NewTypedTool("get_repository_overviews",
+		"Get an overview of a user's or organization's repositories including the latest commit, open pull requests and open issues counts",
+		func(ctx context.Context, args getRepositoryOverviewsArgs) interface{} {
+			return g.GetRepositoryOverviews(ctx, optional(args.Owner), &args.Limit)
+		})
 }
 
-func (t *SearchRepositoriesTool) Call(args map[string]interface{}) interface{} {
-	query, ok := args["query"].(string)
-	if !ok {
-		return map[string]string{"error": "query is required"}
-	}
+type getPullRequestReviewsArgs struct {
+	RepoName string `json:"repoName" description:"Repository name in format 'owner/repo', e.g. 'microsoft/vscode'" required:"true"`
+	Number   int    `json:"number" description:"Pull request number" required:"true" minimum:"1"`
+}
 
-	var sort *string
-	var limit *int
+func newGetPullRequestReviewsTool(g *GitHubToolset) types.Function {
+	return NewTypedTool("get_pull_request_reviews",
+		"Get the review state of a pull request including review decision, requested reviewers and submitted reviews",
+		func(ctx context.Context, args getPullRequestReviewsArgs) interface{} {
+			return g.GetPullRequestReviews(ctx, args.RepoName, args.Number)
+		})
+}
 
-	if val, ok := args["sort"].(string); ok {
-		sort = &val
-	}
-	if val, ok := args["limit"].(float64); ok {
-		intVal := int(val)
-		limit = &intVal
-	}
+type getDiscussionsArgs struct {
+	RepoName string `json:"repoName" description:"Repository name in format 'owner/repo', e.g. 'microsoft/vscode'" required:"true"`
+	Limit    int    `json:"limit" description:"Limit the number of returned results" default:"10" minimum:"1" maximum:"100"`
+}
 
-	result := t.toolset.SearchRepositories(query, sort, limit)
-	fmt.Printf("SearchRepositories result: %+v\n", result)
-	return result
+func newGetDiscussionsTool(g *GitHubToolset) types.Function {
+	return NewTypedTool("get_discussions",
+		"Get the most recently updated discussions of a repository",
+		func(ctx context.Context, args getDiscussionsArgs) interface{} {
+			return g.GetDiscussions(ctx, args.RepoName, &args.Limit)
+		})
+}
+
+type listIssuesArgs struct {
+	RepoName string `json:"repoName" description:"Repository name in format 'owner/repo', e.g. 'microsoft/vscode'" required:"true"`
+	State    string `json:"state" description:"Issue state" enum:"open,closed,all" default:"open"`
+	Limit    int    `json:"limit" description:"Limit the number of returned results" default:"10" minimum:"1" maximum:"100"`
+}
+
+func newListIssuesTool(g *GitHubToolset) types.Function {
+	return NewTypedTool("list_issues",
+		"List recently updated issues of a repository, pull requests are excluded",
+		func(ctx context.Context, args listIssuesArgs) interface{} {
+			return g.ListIssues(ctx, args.RepoName, &args.State, &args.Limit)
+		})
+}
+
+type listPullRequestsArgs struct {
+	RepoName string `json:"repoName" description:"Repository name in format 'owner/repo', e.g. 'microsoft/vscode'" required:"true"`
+	State    string `json:"state" description:"Pull request state" enum:"open,closed,all" default:"open"`
+	Limit    int    `json:"limit" description:"Limit the number of returned results" default:"10" minimum:"1" maximum:"100"`
+}
+
+func newListPullRequestsTool(g *GitHubToolset) types.Function {
+	return NewTypedTool("list_pull_requests",
+		"List recently updated pull requests of a repository",
+		func(ctx context.Context, args listPullRequestsArgs) interface{} {
+			return g.ListPullRequests(ctx, args.RepoName, &args.State, &args.Limit)
+		})
+}
+
+type listWorkflowRunsArgs struct {
+	RepoName string `json:"repoName" description:"Repository name in format 'owner/repo', e.g. 'microsoft/vscode'" required:"true"`
+	Branch   string `json:"branch" description:"Only return runs for this branch"`
+	Status   string `json:"status" description:"Only return runs with this status or conclusion" enum:"queued,in_progress,completed,success,failure,cancelled"`
+	Limit    int    `json:"limit" description:"Limit the number of returned results" default:"10" minimum:"1" maximum:"100"`
+}
+
+func newListWorkflowRunsTool(g *GitHubToolset) types.Function {
+	return NewTypedTool("list_workflow_runs",
+		"List recent GitHub Actions workflow runs of a repository",
+		func(ctx context.Context, args listWorkflowRunsArgs) interface{} {
+			return g.ListWorkflowRuns(ctx, args.RepoName, optional(args.Branch), optional(args.Status), &args.Limit)
+		})
+}
+
+type listSecurityAlertsArgs struct {
+	RepoName string `json:"repoName" description:"Repository name in format 'owner/repo', e.g. 'microsoft/vscode'" required:"true"`
+	State    string `json:"state" description:"Alert state" enum:"open,fixed,dismissed,auto_dismissed" default:"open"`
+	Severity string `json:"severity" description:"Only return alerts with this severity" enum:"low,medium,high,critical"`
+	Limit    int    `json:"limit" description:"Limit the number of returned results" default:"10" minimum:"1" maximum:"100"`
+}
+
+func newListSecurityAlertsTool(g *GitHubToolset) types.Function {
+	return NewTypedTool("list_security_alerts",
+		"List Dependabot security alerts of a repository, requires a token with security alert access",
+		func(ctx context.Context, args listSecurityAlertsArgs) interface{} {
+			return g.ListSecurityAlerts(ctx, args.RepoName, &args.State, optional(args.Severity), &args.Limit)
+		})
+}
+
+// optional returns nil for an empty string argument
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
 }
@@ -0,0 +1,295 @@
+package toolset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/yeeaiclub/github-a2a/types"
+)
+
+// ListIssues lists issues of a repository, pull requests are left out
+func (g *GitHubToolset) ListIssues(ctx context.Context, repoName string, state *string, limit *int) types.IssueResponse {
+	// Set default values
+	if state == nil {
+		defaultState := "open"
+		state = &defaultState
+	}
+	if limit == nil {
+		defaultLimit := 10
+		limit = &defaultLimit
+	}
+
+	owner, repo, ok := splitRepoName(repoName)
+	if !ok {
+		return types.IssueResponse{GitHubResponse: errInvalidRepoName()}
+	}
+
+	opt := &github.IssueListByRepoOptions{
+		State:     *state,
+		Sort:      "updated",
+		Direction: "desc",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var issues []types.GitHubIssue
+	for len(issues) < *limit {
+		page, resp, err := g.client.Issues.ListByRepo(ctx, owner, repo, opt)
+		if err != nil {
+			return types.IssueResponse{GitHubResponse: errorResponse("Failed to list issues", err)}
+		}
+
+		for _, issue := range page {
+			if len(issues) >= *limit {
+				break
+			}
+			// The issues endpoint also returns pull requests
+			if issue.IsPullRequest() {
+				continue
+			}
+
+			githubIssue := types.GitHubIssue{
+				Number:    issue.GetNumber(),
+				Title:     issue.GetTitle(),
+				State:     issue.GetState(),
+				Author:    issue.GetUser().GetLogin(),
+				Comments:  issue.GetComments(),
+				URL:       issue.GetHTMLURL(),
+				CreatedAt: issue.GetCreatedAt().Time,
+				UpdatedAt: issue.GetUpdatedAt().Time,
+			}
+			for _, label := range issue.Labels {
+				githubIssue.Labels = append(githubIssue.Labels, label.GetName())
+			}
+			issues = append(issues, githubIssue)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	count := len(issues)
+	message := fmt.Sprintf("Successfully retrieved %d %s issues for repository %s", count, *state, repoName)
+	return types.IssueResponse{
+		GitHubResponse: types.GitHubResponse{
+			Status:  "success",
+			Message: message,
+			Count:   &count,
+		},
+		Data: issues,
+	}
+}
+
+// ListPullRequests lists pull requests of a repository
+func (g *GitHubToolset) ListPullRequests(ctx context.Context, repoName string, state *string, limit *int) types.PullRequestResponse {
+	// Set default values
+	if state == nil {
+		defaultState := "open"
+		state = &defaultState
+	}
+	if limit == nil {
+		defaultLimit := 10
+		limit = &defaultLimit
+	}
+
+	owner, repo, ok := splitRepoName(repoName)
+	if !ok {
+		return types.PullRequestResponse{GitHubResponse: errInvalidRepoName()}
+	}
+
+	opt := &github.PullRequestListOptions{
+		State:     *state,
+		Sort:      "updated",
+		Direction: "desc",
+		ListOptions: github.ListOptions{
+			PerPage: *limit,
+		},
+	}
+
+	pulls, _, err := g.client.PullRequests.List(ctx, owner, repo, opt)
+	if err != nil {
+		return types.PullRequestResponse{GitHubResponse: errorResponse("Failed to list pull requests", err)}
+	}
+
+	var pullRequests []types.GitHubPullRequest
+	for _, pull := range pulls {
+		if len(pullRequests) >= *limit {
+			break
+		}
+		pullRequests = append(pullRequests, types.GitHubPullRequest{
+			Number:    pull.GetNumber(),
+			Title:     pull.GetTitle(),
+			State:     pull.GetState(),
+			Draft:     pull.GetDraft(),
+			Author:    pull.GetUser().GetLogin(),
+			Head:      pull.GetHead().GetRef(),
+			Base:      pull.GetBase().GetRef(),
+			URL:       pull.GetHTMLURL(),
+			CreatedAt: pull.GetCreatedAt().Time,
+			UpdatedAt: pull.GetUpdatedAt().Time,
+		})
+	}
+
+	count := len(pullRequests)
+	message := fmt.Sprintf("Successfully retrieved %d %s pull requests for repository %s", count, *state, repoName)
+	return types.PullRequestResponse{
+		GitHubResponse: types.GitHubResponse{
+			Status:  "success",
+			Message: message,
+			Count:   &count,
+		},
+		Data: pullRequests,
+	}
+}
+
+// ListWorkflowRuns lists recent GitHub Actions workflow runs of a repository
+func (g *GitHubToolset) ListWorkflowRuns(ctx context.Context, repoName string, branch *string, status *string, limit *int) types.WorkflowRunResponse {
+	// Set default values
+	if limit == nil {
+		defaultLimit := 10
+		limit = &defaultLimit
+	}
+
+	owner, repo, ok := splitRepoName(repoName)
+	if !ok {
+		return types.WorkflowRunResponse{GitHubResponse: errInvalidRepoName()}
+	}
+
+	opt := &github.ListWorkflowRunsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: *limit,
+		},
+	}
+	if branch != nil {
+		opt.Branch = *branch
+	}
+	if status != nil {
+		opt.Status = *status
+	}
+
+	result, _, err := g.client.Actions.ListRepositoryWorkflowRuns(ctx, owner, repo, opt)
+	if err != nil {
+		return types.WorkflowRunResponse{GitHubResponse: errorResponse("Failed to list workflow runs", err)}
+	}
+
+	var runs []types.GitHubWorkflowRun
+	for _, run := range result.WorkflowRuns {
+		if len(runs) >= *limit {
+			break
+		}
+		runs = append(runs, types.GitHubWorkflowRun{
+			ID:         run.GetID(),
+			Name:       run.GetName(),
+			Title:      run.GetDisplayTitle(),
+			Branch:     run.GetHeadBranch(),
+			Event:      run.GetEvent(),
+			Status:     run.GetStatus(),
+			Conclusion: run.Conclusion,
+			HeadSHA:    shortSHA(run.GetHeadSHA()),
+			URL:        run.GetHTMLURL(),
+			CreatedAt:  run.GetCreatedAt().Time,
+		})
+	}
+
+	count := len(runs)
+	message := fmt.Sprintf("Successfully retrieved %d workflow runs for repository %s", count, repoName)
+	return types.WorkflowRunResponse{
+		GitHubResponse: types.GitHubResponse{
+			Status:  "success",
+			Message: message,
+			Count:   &count,
+		},
+		Data: runs,
+	}
+}
+
+// ListSecurityAlerts lists Dependabot alerts of a repository
+func (g *GitHubToolset) ListSecurityAlerts(ctx context.Context, repoName string, state *string, severity *string, limit *int) types.SecurityAlertResponse {
+	// Set default values
+	if state == nil {
+		defaultState := "open"
+		state = &defaultState
+	}
+	if limit == nil {
+		defaultLimit := 10
+		limit = &defaultLimit
+	}
+
+	owner, repo, ok := splitRepoName(repoName)
+	if !ok {
+		return types.SecurityAlertResponse{GitHubResponse: errInvalidRepoName()}
+	}
+
+	opt := &github.ListAlertsOptions{
+		State:    state,
+		Severity: severity,
+		ListOptions: github.ListOptions{
+			PerPage: *limit,
+		},
+	}
+
+	alerts, _, err := g.client.Dependabot.ListRepoAlerts(ctx, owner, repo, opt)
+	if err != nil {
+		return types.SecurityAlertResponse{GitHubResponse: errorResponse("Failed to list security alerts", err)}
+	}
+
+	var securityAlerts []types.GitHubSecurityAlert
+	for _, alert := range alerts {
+		if len(securityAlerts) >= *limit {
+			break
+		}
+		advisory := alert.GetSecurityAdvisory()
+		vulnerability := alert.GetSecurityVulnerability()
+		securityAlert := types.GitHubSecurityAlert{
+			Number:    alert.GetNumber(),
+			State:     alert.GetState(),
+			Severity:  advisory.GetSeverity(),
+			Package:   vulnerability.GetPackage().GetName(),
+			Ecosystem: vulnerability.GetPackage().GetEcosystem(),
+			Summary:   advisory.GetSummary(),
+			GHSAID:    advisory.GetGHSAID(),
+			CVEID:     advisory.CVEID,
+			URL:       alert.GetHTMLURL(),
+			CreatedAt: alert.GetCreatedAt().Time,
+		}
+		if patched := vulnerability.GetFirstPatchedVersion(); patched != nil {
+			securityAlert.PatchedIn = patched.Identifier
+		}
+		securityAlerts = append(securityAlerts, securityAlert)
+	}
+
+	count := len(securityAlerts)
+	message := fmt.Sprintf("Successfully retrieved %d %s security alerts for repository %s", count, *state, repoName)
+	return types.SecurityAlertResponse{
+		GitHubResponse: types.GitHubResponse{
+			Status:  "success",
+			Message: message,
+			Count:   &count,
+		},
+		Data: securityAlerts,
+	}
+}
+
+// errorResponse builds the common error response for a failed REST call
+func errorResponse(prefix string, err error) types.GitHubResponse {
+	errorMsg := fmt.Sprintf("%s: %v", prefix, err)
+	return types.GitHubResponse{
+		Status:       "error",
+		Message:      errorMsg,
+		ErrorMessage: &errorMsg,
+	}
+}
+
+// errInvalidRepoName is returned when a repository name is not in format 'owner/repo'
+func errInvalidRepoName() types.GitHubResponse {
+	errorMsg := "Repository name must be in format 'owner/repo'"
+	return types.GitHubResponse{
+		Status:       "error",
+		Message:      errorMsg,
+		ErrorMessage: &errorMsg,
+	}
+}
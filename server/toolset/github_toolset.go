@@ -3,46 +3,123 @@ package toolset
 import (
 	"context"
 	"fmt"
-	"os"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v62/github"
+	"github.com/yeeaiclub/github-a2a/server/metrics"
 	"github.com/yeeaiclub/github-a2a/types"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/oauth2"
 )
 
 // GitHubToolset provides GitHub API tools for querying repositories and recent updates
 type GitHubToolset struct {
-	client *github.Client
+	client  *github.Client
+	graphql *GraphQLClient
+	// baseURL is the REST API root, the public API when empty
+	baseURL string
+	// transport sends the API requests, http.DefaultTransport when nil
+	transport http.RoundTripper
 }
 
-// NewGitHubToolset creates a new GitHub toolset instance
-func NewGitHubToolset() *GitHubToolset {
+// GitHubOption configures optional GitHubToolset settings
+type GitHubOption func(g *GitHubToolset)
+
+// WithGitHubBaseURL points the REST and GraphQL clients at another API root, such as
+// https://github.example.com/api/v3/ for GitHub Enterprise or a local test server
+func WithGitHubBaseURL(baseURL string) GitHubOption {
+	return func(g *GitHubToolset) {
+		g.baseURL = baseURL
+	}
+}
+
+// WithGitHubTransport sends the API requests through transport, for recording and replaying them in tests
+func WithGitHubTransport(transport http.RoundTripper) GitHubOption {
+	return func(g *GitHubToolset) {
+		g.transport = transport
+	}
+}
+
+// NewGitHubToolset creates a new GitHub toolset instance, an empty token uses unauthenticated access
+func NewGitHubToolset(githubToken string, opts ...GitHubOption) *GitHubToolset {
 	toolset := &GitHubToolset{}
-	toolset.initClient()
+	for _, opt := range opts {
+		opt(toolset)
+	}
+	toolset.initClient(githubToken)
 	return toolset
 }
 
 // initClient initializes the GitHub client with authentication
-func (g *GitHubToolset) initClient() {
-	githubToken := os.Getenv("GITHUB_TOKEN")
+func (g *GitHubToolset) initClient(githubToken string) {
+	base := g.transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
 	if githubToken != "" {
 		// Use authenticated client
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: githubToken},
 		)
-		tc := oauth2.NewClient(context.Background(), ts)
+		tc := &http.Client{Transport: instrument(&oauth2.Transport{Source: ts, Base: base})}
 		g.client = github.NewClient(tc)
+		g.graphql = NewGraphQLClient(tc)
 	} else {
 		// Use unauthenticated client (limited rate)
-		fmt.Println("Warning: No GITHUB_TOKEN found, using unauthenticated access (limited rate)")
-		g.client = github.NewClient(nil)
+		slog.Warn("No GitHub token configured, using unauthenticated access with a low rate limit")
+		g.client = github.NewClient(&http.Client{Transport: instrument(base)})
+	}
+
+	if g.baseURL == "" {
+		return
+	}
+	restURL, graphQLURL, err := apiURLs(g.baseURL)
+	if err != nil {
+		slog.Error("Invalid GitHub API URL, using the public API", "url", g.baseURL, "error", err)
+		return
+	}
+	g.client.BaseURL = restURL
+	if g.graphql != nil {
+		g.graphql.endpoint = graphQLURL
+	}
+}
+
+// apiURLs derives the REST root and the GraphQL endpoint from an API root. GitHub Enterprise serves
+// REST under /api/v3/ and GraphQL at /api/graphql, other roots serve GraphQL at graphql below the root.
+func apiURLs(baseURL string) (*url.URL, string, error) {
+	restURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, "", err
 	}
+	if restURL.Scheme == "" || restURL.Host == "" {
+		return nil, "", fmt.Errorf("GitHub API URL %q must be absolute", baseURL)
+	}
+	if !strings.HasSuffix(restURL.Path, "/") {
+		restURL.Path += "/"
+	}
+	graphQLURL := *restURL
+	if strings.HasSuffix(restURL.Path, "/api/v3/") {
+		graphQLURL.Path = strings.TrimSuffix(restURL.Path, "v3/") + "graphql"
+	} else {
+		graphQLURL.Path = restURL.Path + "graphql"
+	}
+	return restURL, graphQLURL.String(), nil
+}
+
+// instrument wraps a transport so every GitHub API request is measured and traced
+func instrument(base http.RoundTripper) http.RoundTripper {
+	traced := otelhttp.NewTransport(base, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return "GitHub " + r.Method
+	}))
+	return &metrics.Transport{Base: traced}
 }
 
 // GetUserRepositories gets user's repositories with recent updates
-func (g *GitHubToolset) GetUserRepositories(username *string, days *int, limit *int) types.RepositoryResponse {
+func (g *GitHubToolset) GetUserRepositories(ctx context.Context, username *string, days *int, limit *int) types.RepositoryResponse {
 	// Set default values
 	if days == nil {
 		defaultDays := 30
@@ -59,10 +136,10 @@ func (g *GitHubToolset) GetUserRepositories(username *string, days *int, limit *
 
 	if username != nil && *username != "" {
 		// Get specific user
-		user, _, err = g.client.Users.Get(context.Background(), *username)
+		user, _, err = g.client.Users.Get(ctx, *username)
 	} else {
 		// Get authenticated user
-		user, _, err = g.client.Users.Get(context.Background(), "")
+		user, _, err = g.client.Users.Get(ctx, "")
 	}
 
 	if err != nil {
@@ -87,7 +164,7 @@ func (g *GitHubToolset) GetUserRepositories(username *string, days *int, limit *
 
 	var allRepos []*github.Repository
 	for {
-		repos, resp, err := g.client.Repositories.List(context.Background(), *user.Login, opt)
+		repos, resp, err := g.client.Repositories.List(ctx, *user.Login, opt)
 		if err != nil {
 			errorMsg := fmt.Sprintf("Failed to get repositories: %v", err)
 			return types.RepositoryResponse{
@@ -151,7 +228,7 @@ func (g *GitHubToolset) GetUserRepositories(username *string, days *int, limit *
 }
 
 // GetRecentCommits gets recent commits for a repository
-func (g *GitHubToolset) GetRecentCommits(repoName string, days *int, limit *int) types.CommitResponse {
+func (g *GitHubToolset) GetRecentCommits(ctx context.Context, repoName string, days *int, limit *int) types.CommitResponse {
 	// Set default values
 	if days == nil {
 		defaultDays := 7
@@ -186,7 +263,7 @@ func (g *GitHubToolset) GetRecentCommits(repoName string, days *int, limit *int)
 		},
 	}
 
-	commits, _, err := g.client.Repositories.ListCommits(context.Background(), owner, repo, opt)
+	commits, _, err := g.client.Repositories.ListCommits(ctx, owner, repo, opt)
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to get commits: %v", err)
 		return types.CommitResponse{
@@ -235,7 +312,7 @@ func (g *GitHubToolset) GetRecentCommits(repoName string, days *int, limit *int)
 }
 
 // SearchRepositories searches for repositories with recent activity
-func (g *GitHubToolset) SearchRepositories(query string, sort *string, limit *int) types.RepositoryResponse {
+func (g *GitHubToolset) SearchRepositories(ctx context.Context, query string, sort *string, limit *int) types.RepositoryResponse {
 	// Set default values
 	if sort == nil {
 		defaultSort := "updated"
@@ -259,7 +336,7 @@ func (g *GitHubToolset) SearchRepositories(query string, sort *string, limit *in
 		},
 	}
 
-	result, _, err := g.client.Search.Repositories(context.Background(), searchQuery, opt)
+	result, _, err := g.client.Search.Repositories(ctx, searchQuery, opt)
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to search repositories: %v", err)
 		return types.RepositoryResponse{
@@ -312,11 +389,195 @@ func (g *GitHubToolset) SearchRepositories(query string, sort *string, limit *in
 	}
 }
 
-// GetTools returns the available tools for OpenAI function calling
-func (g *GitHubToolset) GetTools() map[string]types.Function {
-	return map[string]types.Function{
-		"get_user_repositories": &GetUserRepositoriesTool{g},
-		"get_recent_commits":    &GetRecentCommitsTool{g},
-		"search_repositories":   &SearchRepositoriesTool{g},
+// GetRepositoryOverviews gets repositories with their latest commit and open pull request counts via GraphQL
+func (g *GitHubToolset) GetRepositoryOverviews(ctx context.Context, owner *string, limit *int) types.RepositoryOverviewResponse {
+	// Set default values
+	if limit == nil {
+		defaultLimit := 10
+		limit = &defaultLimit
+	}
+	if g.graphql == nil {
+		return types.RepositoryOverviewResponse{GitHubResponse: errGraphQLUnauthenticated()}
+	}
+
+	login := ""
+	if owner != nil {
+		login = *owner
+	}
+
+	overviews, truncated, err := g.graphql.RepositoryOverviews(ctx, login, *limit)
+	if err != nil {
+		return types.RepositoryOverviewResponse{GitHubResponse: graphQLErrorResponse("get repository overviews", err)}
+	}
+
+	count := len(overviews)
+	message := fmt.Sprintf("Successfully retrieved %d repository overviews", count)
+	if truncated {
+		message += " (stopped early to preserve the GraphQL rate limit)"
+	}
+	return types.RepositoryOverviewResponse{
+		GitHubResponse: types.GitHubResponse{
+			Status:  "success",
+			Message: message,
+			Count:   &count,
+		},
+		Data: overviews,
+	}
+}
+
+// GetPullRequestReviews gets the review state of a pull request via GraphQL
+func (g *GitHubToolset) GetPullRequestReviews(ctx context.Context, repoName string, number int) types.PullRequestReviewResponse {
+	owner, repo, ok := splitRepoName(repoName)
+	if !ok {
+		return types.PullRequestReviewResponse{GitHubResponse: errInvalidRepoName()}
+	}
+	if g.graphql == nil {
+		return types.PullRequestReviewResponse{GitHubResponse: errGraphQLUnauthenticated()}
 	}
+
+	state, err := g.graphql.PullRequestReviewState(ctx, owner, repo, number)
+	if err != nil {
+		return types.PullRequestReviewResponse{GitHubResponse: graphQLErrorResponse("get pull request reviews", err)}
+	}
+
+	count := len(state.Reviews)
+	message := fmt.Sprintf("Successfully retrieved %d reviews for pull request %s#%d", count, repoName, number)
+	return types.PullRequestReviewResponse{
+		GitHubResponse: types.GitHubResponse{
+			Status:  "success",
+			Message: message,
+			Count:   &count,
+		},
+		Data: state,
+	}
+}
+
+// GetDiscussions gets the most recently updated discussions of a repository via GraphQL
+func (g *GitHubToolset) GetDiscussions(ctx context.Context, repoName string, limit *int) types.DiscussionResponse {
+	// Set default values
+	if limit == nil {
+		defaultLimit := 10
+		limit = &defaultLimit
+	}
+
+	owner, repo, ok := splitRepoName(repoName)
+	if !ok {
+		return types.DiscussionResponse{GitHubResponse: errInvalidRepoName()}
+	}
+	if g.graphql == nil {
+		return types.DiscussionResponse{GitHubResponse: errGraphQLUnauthenticated()}
+	}
+
+	discussions, truncated, err := g.graphql.Discussions(ctx, owner, repo, *limit)
+	if err != nil {
+		return types.DiscussionResponse{GitHubResponse: graphQLErrorResponse("get discussions", err)}
+	}
+
+	count := len(discussions)
+	message := fmt.Sprintf("Successfully retrieved %d discussions for repository %s", count, repoName)
+	if truncated {
+		message += " (stopped early to preserve the GraphQL rate limit)"
+	}
+	return types.DiscussionResponse{
+		GitHubResponse: types.GitHubResponse{
+			Status:  "success",
+			Message: message,
+			Count:   &count,
+		},
+		Data: discussions,
+	}
+}
+
+// splitRepoName splits a repository name in format 'owner/repo'
+func splitRepoName(repoName string) (string, string, bool) {
+	parts := strings.Split(repoName, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// errGraphQLUnauthenticated is returned by GraphQL backed operations without a token
+func errGraphQLUnauthenticated() types.GitHubResponse {
+	errorMsg := "The GitHub GraphQL API requires authentication, set GITHUB_TOKEN to use this tool"
+	return types.GitHubResponse{
+		Status:       "error",
+		Message:      errorMsg,
+		ErrorMessage: &errorMsg,
+	}
+}
+
+// Register adds the GitHub tools to the registry grouped by skill
+func (g *GitHubToolset) Register(registry *Registry) {
+	registry.Register(Skill{
+		ID:          SkillRepos,
+		Name:        "Repositories",
+		Description: "Find repositories, list recently updated ones and summarize their latest commits",
+		Tags:        []string{"github", "repositories", "commits"},
+		Examples: []string{
+			"Which of my repositories were updated in the last week?",
+			"Show recent commits for repository 'facebook/react'",
+			"Find popular Go repositories about machine learning",
+		},
+	},
+		newGetUserRepositoriesTool(g),
+		newGetRecentCommitsTool(g),
+		newSearchRepositoriesTool(g),
+		newGetRepositoryOverviewsTool(g),
+	)
+	registry.Register(Skill{
+		ID:          SkillIssues,
+		Name:        "Issues and discussions",
+		Description: "List issues and discussions of a repository",
+		Tags:        []string{"github", "issues", "discussions"},
+		Examples: []string{
+			"What are the open issues in 'golang/go' labeled as release-blocker?",
+			"Summarize the latest discussions in 'vercel/next.js'",
+		},
+	},
+		newListIssuesTool(g),
+		newGetDiscussionsTool(g),
+	)
+	registry.Register(Skill{
+		ID:          SkillPullRequests,
+		Name:        "Pull requests",
+		Description: "List pull requests and report their review state",
+		Tags:        []string{"github", "pull-requests", "code-review"},
+		Examples: []string{
+			"Which pull requests are open in 'kubernetes/kubernetes'?",
+			"Who still needs to review pull request 123 in 'facebook/react'?",
+		},
+	},
+		newListPullRequestsTool(g),
+		newGetPullRequestReviewsTool(g),
+	)
+	registry.Register(Skill{
+		ID:          SkillCI,
+		Name:        "Continuous integration",
+		Description: "Report GitHub Actions workflow runs and failures",
+		Tags:        []string{"github", "actions", "ci"},
+		Examples: []string{
+			"Did the CI on the main branch of 'golang/go' fail recently?",
+		},
+	},
+		newListWorkflowRunsTool(g),
+	)
+	registry.Register(Skill{
+		ID:          SkillSecurity,
+		Name:        "Security",
+		Description: "List Dependabot security alerts of a repository",
+		Tags:        []string{"github", "security", "dependabot"},
+		Examples: []string{
+			"Are there any critical security alerts open in my repository 'octo/app'?",
+		},
+	},
+		newListSecurityAlertsTool(g),
+	)
+}
+
+// GetTools returns all available tools for OpenAI function calling
+func (g *GitHubToolset) GetTools() map[string]types.Function {
+	registry := NewRegistry(RegistryConfig{})
+	g.Register(registry)
+	return registry.Tools()
 }
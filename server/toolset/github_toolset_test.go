@@ -0,0 +1,406 @@
+package toolset_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+	"github.com/yeeaiclub/github-a2a/server/toolset/fakegithub"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func stamp(t time.Time) *github.Timestamp { return &github.Timestamp{Time: t} }
+
+// daysAgo is a time relative to now, fixtures use it so the time filters of the tools keep working
+func daysAgo(days int) time.Time {
+	return time.Now().Add(-time.Duration(days) * 24 * time.Hour).UTC().Truncate(time.Second)
+}
+
+func repository(owner string, name string, updated time.Time, stars int) *github.Repository {
+	return &github.Repository{
+		Name:            ptr(name),
+		FullName:        ptr(owner + "/" + name),
+		Owner:           &github.User{Login: ptr(owner)},
+		Description:     ptr("The " + name + " project"),
+		HTMLURL:         ptr("https://github.com/" + owner + "/" + name),
+		UpdatedAt:       stamp(updated),
+		PushedAt:        stamp(updated),
+		StargazersCount: ptr(stars),
+		ForksCount:      ptr(stars / 10),
+	}
+}
+
+func commit(sha string, message string, author string, date time.Time) *github.RepositoryCommit {
+	return &github.RepositoryCommit{
+		SHA:     ptr(sha),
+		HTMLURL: ptr("https://github.com/octo/demo/commit/" + sha),
+		Commit: &github.Commit{
+			Message: ptr(message),
+			Author:  &github.CommitAuthor{Name: ptr(author), Date: stamp(date)},
+		},
+	}
+}
+
+// fixtures has 120 repositories of octo, 105 updated within the last 30 days, so listing them takes two pages
+func fixtures() fakegithub.Fixtures {
+	f := fakegithub.Fixtures{
+		Users: []*github.User{{Login: ptr("octo")}, {Login: ptr("hubot")}},
+		Commits: map[string][]*github.RepositoryCommit{
+			"octo/demo": {
+				commit("1111111111111111", "Add feature\n\nLong description", "Mona", daysAgo(1)),
+				commit("2222222222222222", "Fix bug", "Hubot", daysAgo(3)),
+				commit("3333333333333333", "Old change", "Mona", daysAgo(40)),
+			},
+		},
+		Issues: map[string][]*github.Issue{"octo/demo": {}},
+		Pulls: map[string][]*github.PullRequest{
+			"octo/demo": {
+				{Number: ptr(7), Title: ptr("Add docs"), State: ptr("open"), Draft: ptr(true),
+					User: &github.User{Login: ptr("mona")}, Head: &github.PullRequestBranch{Ref: ptr("docs")},
+					Base: &github.PullRequestBranch{Ref: ptr("main")}, CreatedAt: stamp(daysAgo(2)), UpdatedAt: stamp(daysAgo(1))},
+				{Number: ptr(6), Title: ptr("Merged change"), State: ptr("closed"),
+					User: &github.User{Login: ptr("hubot")}, CreatedAt: stamp(daysAgo(9)), UpdatedAt: stamp(daysAgo(8))},
+			},
+		},
+		Runs: map[string][]*github.WorkflowRun{
+			"octo/demo": {
+				{ID: ptr(int64(2)), Name: ptr("CI"), HeadBranch: ptr("main"), Event: ptr("push"), Status: ptr("completed"),
+					Conclusion: ptr("failure"), HeadSHA: ptr("abcdef0123456789"), CreatedAt: stamp(daysAgo(1))},
+				{ID: ptr(int64(1)), Name: ptr("CI"), HeadBranch: ptr("docs"), Event: ptr("pull_request"), Status: ptr("in_progress"),
+					HeadSHA: ptr("0123456789abcdef"), CreatedAt: stamp(daysAgo(2))},
+			},
+		},
+		Alerts: map[string][]*github.DependabotAlert{
+			"octo/demo": {
+				{Number: ptr(3), State: ptr("open"), CreatedAt: stamp(daysAgo(5)),
+					SecurityAdvisory: &github.DependabotSecurityAdvisory{Severity: ptr("high"), Summary: ptr("Prototype pollution"),
+						GHSAID: ptr("GHSA-aaaa-bbbb-cccc"), CVEID: ptr("CVE-2024-0001")},
+					SecurityVulnerability: &github.AdvisoryVulnerability{
+						Package:             &github.VulnerabilityPackage{Name: ptr("lodash"), Ecosystem: ptr("npm")},
+						FirstPatchedVersion: &github.FirstPatchedVersion{Identifier: ptr("4.17.21")},
+					}},
+				{Number: ptr(2), State: ptr("open"), CreatedAt: stamp(daysAgo(6)),
+					SecurityAdvisory: &github.DependabotSecurityAdvisory{Severity: ptr("low"), Summary: ptr("ReDoS")}},
+			},
+		},
+	}
+	for i := 0; i < 120; i++ {
+		updated := daysAgo(i / 4)
+		if i >= 105 {
+			updated = daysAgo(60)
+		}
+		f.Repos = append(f.Repos, repository("octo", fmt.Sprintf("repo-%03d", i), updated, i))
+	}
+	f.Repos = append(f.Repos, repository("hubot", "demo", daysAgo(2), 5000))
+
+	// The issues endpoint returns pull requests too, 150 items take two pages of 100
+	for i := 150; i > 0; i-- {
+		issue := &github.Issue{
+			Number: ptr(i), Title: ptr(fmt.Sprintf("Issue %d", i)), State: ptr("open"),
+			HTMLURL: ptr(fmt.Sprintf("https://github.com/octo/demo/issues/%d", i)),
+			User:    &github.User{Login: ptr("mona")}, Comments: ptr(i % 5),
+			Labels:    []*github.Label{{Name: ptr("bug")}},
+			CreatedAt: stamp(daysAgo(i)), UpdatedAt: stamp(daysAgo(i)),
+		}
+		if i%10 == 0 {
+			issue.PullRequestLinks = &github.PullRequestLinks{URL: ptr("https://api.github.com/repos/octo/demo/pulls/" + fmt.Sprint(i))}
+		}
+		f.Issues["octo/demo"] = append(f.Issues["octo/demo"], issue)
+	}
+	return f
+}
+
+// newFakeToolset starts a fake GitHub API with the fixtures and a toolset using it
+func newFakeToolset(t *testing.T) (*toolset.GitHubToolset, *fakegithub.Server) {
+	t.Helper()
+	server := fakegithub.New(fixtures())
+	t.Cleanup(server.Close)
+	return toolset.NewGitHubToolset("test-token", toolset.WithGitHubBaseURL(server.URL)), server
+}
+
+// countRequests counts the requests whose method and path start with prefix
+func countRequests(server *fakegithub.Server, prefix string) int {
+	count := 0
+	for _, request := range server.Requests() {
+		if strings.HasPrefix(request, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestGetUserRepositoriesPaginatesAndFiltersByDays(t *testing.T) {
+	tools, server := newFakeToolset(t)
+
+	result := tools.GetUserRepositories(context.Background(), ptr("octo"), ptr(30), ptr(200))
+	if result.Status != "success" {
+		t.Fatalf("status %s: %s", result.Status, result.Message)
+	}
+	if len(result.Data) != 105 || *result.Count != 105 {
+		t.Fatalf("%d repositories, want the 105 updated in the last 30 days", len(result.Data))
+	}
+	if result.Data[0].FullName != "octo/repo-000" {
+		t.Errorf("first repository %s, want the most recently updated", result.Data[0].FullName)
+	}
+	if pages := countRequests(server, "GET /users/octo/repos"); pages != 2 {
+		t.Errorf("%d page requests, want 2: %v", pages, server.Requests())
+	}
+}
+
+func TestGetUserRepositoriesLimit(t *testing.T) {
+	tools, _ := newFakeToolset(t)
+
+	result := tools.GetUserRepositories(context.Background(), nil, nil, ptr(5))
+	if result.Status != "success" || len(result.Data) != 5 {
+		t.Fatalf("status %s with %d repositories: %s", result.Status, len(result.Data), result.Message)
+	}
+	if result.Data[0].Stars != 0 || result.Data[0].URL != "https://github.com/octo/repo-000" {
+		t.Errorf("first repository = %+v", result.Data[0])
+	}
+}
+
+func TestGetUserRepositoriesUnknownUser(t *testing.T) {
+	tools, _ := newFakeToolset(t)
+
+	result := tools.GetUserRepositories(context.Background(), ptr("nobody"), nil, nil)
+	if result.Status != "error" || !strings.Contains(result.Message, "Failed to get user") || !strings.Contains(result.Message, "404") {
+		t.Errorf("status %s: %s", result.Status, result.Message)
+	}
+}
+
+func TestGetRecentCommits(t *testing.T) {
+	tools, server := newFakeToolset(t)
+
+	result := tools.GetRecentCommits(context.Background(), "octo/demo", ptr(7), ptr(10))
+	if result.Status != "success" {
+		t.Fatalf("status %s: %s", result.Status, result.Message)
+	}
+	if len(result.Data) != 2 {
+		t.Fatalf("%d commits, want the 2 of the last 7 days", len(result.Data))
+	}
+	first := result.Data[0]
+	if first.SHA != "11111111" || first.Message != "Add feature" || first.Author != "Mona" {
+		t.Errorf("first commit = %+v, want a short SHA and the first line of the message", first)
+	}
+	if requests := server.Requests(); !strings.Contains(requests[0], "since=") || !strings.Contains(requests[0], "per_page=10") {
+		t.Errorf("request %s does not pass the cutoff and limit", requests[0])
+	}
+}
+
+func TestGetRecentCommitsErrors(t *testing.T) {
+	tools, server := newFakeToolset(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		repo     string
+		fail     int
+		contains string
+	}{
+		{name: "invalid name", repo: "demo", contains: "format 'owner/repo'"},
+		{name: "unknown repository", repo: "octo/missing", contains: "404"},
+		{name: "server error", repo: "octo/demo", fail: http.StatusInternalServerError, contains: "500"},
+		{name: "rate limited", repo: "octo/demo", fail: http.StatusForbidden, contains: "API rate limit exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.Reset()
+			if tt.fail != 0 {
+				server.Fail("GET /repos/{owner}/{repo}/commits", tt.fail, "API rate limit exceeded")
+			}
+			result := tools.GetRecentCommits(ctx, tt.repo, nil, nil)
+			if result.Status != "error" || result.ErrorMessage == nil || !strings.Contains(result.Message, tt.contains) {
+				t.Errorf("status %s: %s, want an error containing %q", result.Status, result.Message, tt.contains)
+			}
+		})
+	}
+}
+
+func TestSearchRepositories(t *testing.T) {
+	tools, server := newFakeToolset(t)
+
+	result := tools.SearchRepositories(context.Background(), "demo", ptr("stars"), ptr(5))
+	if result.Status != "success" {
+		t.Fatalf("status %s: %s", result.Status, result.Message)
+	}
+	if len(result.Data) != 1 || result.Data[0].FullName != "hubot/demo" || result.Data[0].Stars != 5000 {
+		t.Errorf("results = %+v", result.Data)
+	}
+	if request := server.Requests()[0]; !strings.Contains(request, "pushed%3A%3E%3D") {
+		t.Errorf("request %s does not restrict the search to recently pushed repositories", request)
+	}
+
+	stale := tools.SearchRepositories(context.Background(), "repo-110", nil, nil)
+	if stale.Status != "success" || len(stale.Data) != 0 {
+		t.Errorf("found %d repositories not pushed in the last 30 days", len(stale.Data))
+	}
+}
+
+func TestListIssuesPaginatesAndSkipsPullRequests(t *testing.T) {
+	tools, server := newFakeToolset(t)
+
+	result := tools.ListIssues(context.Background(), "octo/demo", nil, ptr(120))
+	if result.Status != "success" {
+		t.Fatalf("status %s: %s", result.Status, result.Message)
+	}
+	if len(result.Data) != 120 {
+		t.Fatalf("%d issues, want 120", len(result.Data))
+	}
+	for _, issue := range result.Data {
+		if issue.Number%10 == 0 {
+			t.Fatalf("pull request #%d listed as an issue", issue.Number)
+		}
+	}
+	if pages := countRequests(server, "GET /repos/octo/demo/issues"); pages != 2 {
+		t.Errorf("%d page requests, want 2", pages)
+	}
+	if labels := result.Data[0].Labels; len(labels) != 1 || labels[0] != "bug" {
+		t.Errorf("labels = %v", labels)
+	}
+}
+
+func TestListPullRequests(t *testing.T) {
+	tools, _ := newFakeToolset(t)
+
+	open := tools.ListPullRequests(context.Background(), "octo/demo", nil, nil)
+	if open.Status != "success" || len(open.Data) != 1 {
+		t.Fatalf("status %s with %d pull requests: %s", open.Status, len(open.Data), open.Message)
+	}
+	if pr := open.Data[0]; pr.Number != 7 || !pr.Draft || pr.Head != "docs" || pr.Base != "main" || pr.Author != "mona" {
+		t.Errorf("pull request = %+v", pr)
+	}
+
+	all := tools.ListPullRequests(context.Background(), "octo/demo", ptr("all"), nil)
+	if len(all.Data) != 2 {
+		t.Errorf("%d pull requests in state all, want 2", len(all.Data))
+	}
+}
+
+func TestListWorkflowRuns(t *testing.T) {
+	tools, _ := newFakeToolset(t)
+
+	result := tools.ListWorkflowRuns(context.Background(), "octo/demo", ptr("main"), nil, nil)
+	if result.Status != "success" || len(result.Data) != 1 {
+		t.Fatalf("status %s with %d runs: %s", result.Status, len(result.Data), result.Message)
+	}
+	run := result.Data[0]
+	if run.ID != 2 || run.Conclusion == nil || *run.Conclusion != "failure" || run.HeadSHA != "abcdef01" {
+		t.Errorf("run = %+v", run)
+	}
+}
+
+func TestListSecurityAlerts(t *testing.T) {
+	tools, _ := newFakeToolset(t)
+
+	result := tools.ListSecurityAlerts(context.Background(), "octo/demo", nil, ptr("high"), nil)
+	if result.Status != "success" || len(result.Data) != 1 {
+		t.Fatalf("status %s with %d alerts: %s", result.Status, len(result.Data), result.Message)
+	}
+	alert := result.Data[0]
+	if alert.Package != "lodash" || alert.Ecosystem != "npm" || alert.PatchedIn == nil || *alert.PatchedIn != "4.17.21" {
+		t.Errorf("alert = %+v", alert)
+	}
+}
+
+func TestGraphQLToolsWithoutToken(t *testing.T) {
+	server := fakegithub.New(fixtures())
+	defer server.Close()
+	tools := toolset.NewGitHubToolset("", toolset.WithGitHubBaseURL(server.URL))
+
+	result := tools.GetRepositoryOverviews(context.Background(), ptr("octo"), nil)
+	if result.Status != "error" || len(server.Requests()) != 0 {
+		t.Errorf("status %s after %d requests, want an error without calling the API", result.Status, len(server.Requests()))
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	tools, server := newFakeToolset(t)
+	server.HandleGraphQL(func(request fakegithub.GraphQLRequest) (any, []map[string]any) {
+		return nil, []map[string]any{{"type": "NOT_FOUND", "message": "Could not resolve to a Repository"}}
+	})
+
+	result := tools.GetDiscussions(context.Background(), "octo/missing", nil)
+	if result.Status != "error" || !strings.Contains(result.Message, "not found") {
+		t.Errorf("status %s: %s", result.Status, result.Message)
+	}
+
+	server.Fail("POST /graphql", http.StatusUnauthorized, "Bad credentials")
+	result = tools.GetDiscussions(context.Background(), "octo/demo", nil)
+	if result.Status != "error" || !strings.Contains(result.Message, "credentials") {
+		t.Errorf("status %s: %s", result.Status, result.Message)
+	}
+}
+
+func TestGetDiscussionsFollowsCursors(t *testing.T) {
+	tools, server := newFakeToolset(t)
+	// Every page holds one discussion, the cursor is the number of the next one
+	server.HandleGraphQL(func(request fakegithub.GraphQLRequest) (any, []map[string]any) {
+		number := 1
+		if after, ok := request.Variables["after"].(string); ok {
+			fmt.Sscan(after, &number)
+		}
+		node := map[string]any{
+			"number": number, "title": fmt.Sprintf("Discussion %d", number),
+			"url":       fmt.Sprintf("https://github.com/octo/demo/discussions/%d", number),
+			"createdAt": daysAgo(number), "updatedAt": daysAgo(number),
+			"author": map[string]any{"login": "mona"}, "category": map[string]any{"name": "Q&A"},
+			"comments": map[string]any{"totalCount": number}, "upvoteCount": 1,
+		}
+		if number == 2 {
+			node["answerChosenAt"] = daysAgo(1)
+		}
+		return map[string]any{
+			"rateLimit": map[string]any{"cost": 1, "limit": 5000, "remaining": 4999, "resetAt": time.Now().Add(time.Hour)},
+			"repository": map[string]any{"discussions": map[string]any{
+				"pageInfo": map[string]any{"hasNextPage": number < 5, "endCursor": fmt.Sprint(number + 1)},
+				"nodes":    []any{node},
+			}},
+		}, nil
+	})
+
+	result := tools.GetDiscussions(context.Background(), "octo/demo", ptr(3))
+	if result.Status != "success" {
+		t.Fatalf("status %s: %s", result.Status, result.Message)
+	}
+	if len(result.Data) != 3 || result.Data[2].Number != 3 {
+		t.Fatalf("discussions = %+v, want the first 3", result.Data)
+	}
+	if !result.Data[1].Answered || result.Data[0].Answered || result.Data[0].Category != "Q&A" {
+		t.Errorf("discussions = %+v", result.Data)
+	}
+	if queries := countRequests(server, "POST /graphql"); queries != 3 {
+		t.Errorf("%d queries, want 3", queries)
+	}
+}
+
+func TestRepositoryFingerprint(t *testing.T) {
+	tools, server := newFakeToolset(t)
+	ctx := context.Background()
+
+	first, err := tools.RepositoryFingerprint(ctx, "octo/demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := tools.RepositoryFingerprint(ctx, "octo/demo")
+	if err != nil || second != first {
+		t.Errorf("fingerprint %q then %q (%v), want it stable while nothing changes", first, second, err)
+	}
+	for _, request := range server.Requests() {
+		if !strings.Contains(request, "per_page=1") {
+			t.Errorf("request %s does not fetch a single item", request)
+		}
+	}
+
+	if _, err := tools.RepositoryFingerprint(ctx, "octo/unknown"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("unknown repository: %v, want a 404 error", err)
+	}
+	if _, err := tools.RepositoryFingerprint(ctx, "demo"); err == nil {
+		t.Error("invalid repository name accepted")
+	}
+}
@@ -0,0 +1,211 @@
+package toolset
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yeeaiclub/github-a2a/types"
+)
+
+const (
+	defaultGraphQLEndpoint = "https://api.github.com/graphql"
+	// defaultMinRemaining is the rate limit budget kept in reserve when paginating
+	defaultMinRemaining = 100
+	maxPageSize         = 100
+)
+
+// GraphQLClient issues queries against the GitHub GraphQL v4 API
+type GraphQLClient struct {
+	httpClient   *http.Client
+	endpoint     string
+	minRemaining int
+}
+
+// NewGraphQLClient creates a GraphQL client on top of an authenticated HTTP client
+func NewGraphQLClient(httpClient *http.Client) *GraphQLClient {
+	return &GraphQLClient{
+		httpClient:   httpClient,
+		endpoint:     defaultGraphQLEndpoint,
+		minRemaining: defaultMinRemaining,
+	}
+}
+
+// rateLimit mirrors the rateLimit object every query selects
+type rateLimit struct {
+	Cost      int       `json:"cost"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+// pageInfo mirrors the cursor information of a GraphQL connection
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage       `json:"data"`
+	Errors []GraphQLErrorDetails `json:"errors"`
+}
+
+// GraphQLErrorDetails is a single entry of the GraphQL errors array
+type GraphQLErrorDetails struct {
+	Type    string        `json:"type"`
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// GraphQLError is returned when the API answers with a non-empty errors array
+type GraphQLError struct {
+	Errors []GraphQLErrorDetails
+}
+
+func (e *GraphQLError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, detail := range e.Errors {
+		messages = append(messages, detail.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// GraphQLHTTPError is returned when the endpoint answers with a non-200 status
+type GraphQLHTTPError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *GraphQLHTTPError) Error() string {
+	return fmt.Sprintf("graphql request failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+// Query executes a GraphQL query and decodes the data field into out
+func (c *GraphQLClient) Query(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	payload, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		message := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			message = apiErr.Message
+		}
+		return &GraphQLHTTPError{StatusCode: resp.StatusCode, Message: message}
+	}
+
+	var result graphQLResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode graphql response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return &GraphQLError{Errors: result.Errors}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(result.Data, out)
+}
+
+// pageFetcher fetches a single page of at most first items starting after the cursor
+type pageFetcher func(first int, after *string) (page pageInfo, limit rateLimit, fetched int, err error)
+
+// paginate walks a connection until want items are collected, the connection is exhausted,
+// or the next page would push the rate limit below the reserved budget.
+// It reports whether pagination stopped early because of the budget.
+func (c *GraphQLClient) paginate(want int, fetch pageFetcher) (bool, error) {
+	var after *string
+	collected := 0
+	for collected < want {
+		first := want - collected
+		if first > maxPageSize {
+			first = maxPageSize
+		}
+
+		page, limit, fetched, err := fetch(first, after)
+		if err != nil {
+			return false, err
+		}
+		collected += fetched
+
+		if !page.HasNextPage || fetched == 0 || collected >= want {
+			return false, nil
+		}
+		if limit.Remaining-limit.Cost < c.minRemaining {
+			return true, nil
+		}
+		cursor := page.EndCursor
+		after = &cursor
+	}
+	return false, nil
+}
+
+// graphQLErrorResponse maps a GraphQL client error into the common response model
+func graphQLErrorResponse(action string, err error) types.GitHubResponse {
+	var reason string
+	switch e := err.(type) {
+	case *GraphQLHTTPError:
+		switch e.StatusCode {
+		case http.StatusUnauthorized:
+			reason = "bad or missing GitHub credentials"
+		case http.StatusForbidden:
+			reason = fmt.Sprintf("access forbidden or rate limited: %s", e.Message)
+		default:
+			reason = e.Error()
+		}
+	case *GraphQLError:
+		reason = e.Error()
+		if len(e.Errors) > 0 {
+			detail := e.Errors[0]
+			switch detail.Type {
+			case "NOT_FOUND":
+				reason = fmt.Sprintf("not found: %s", detail.Message)
+			case "RATE_LIMITED":
+				reason = "GraphQL rate limit exceeded, try again later"
+			case "FORBIDDEN":
+				reason = fmt.Sprintf("access forbidden: %s", detail.Message)
+			}
+		}
+	default:
+		reason = err.Error()
+	}
+
+	errorMsg := fmt.Sprintf("Failed to %s: %s", action, reason)
+	return types.GitHubResponse{
+		Status:       "error",
+		Message:      errorMsg,
+		ErrorMessage: &errorMsg,
+	}
+}
@@ -0,0 +1,408 @@
+package toolset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yeeaiclub/github-a2a/types"
+)
+
+const repositoryFields = `
+	pageInfo { hasNextPage endCursor }
+	nodes {
+		nameWithOwner
+		description
+		url
+		stargazerCount
+		forkCount
+		isArchived
+		pushedAt
+		primaryLanguage { name }
+		pullRequests(states: OPEN) { totalCount }
+		issues(states: OPEN) { totalCount }
+		defaultBranchRef {
+			name
+			target {
+				... on Commit {
+					oid
+					messageHeadline
+					committedDate
+					url
+					author { name }
+				}
+			}
+		}
+	}`
+
+const ownerRepositoriesQuery = `
+query($login: String!, $first: Int!, $after: String) {
+	rateLimit { cost limit remaining resetAt }
+	repositoryOwner(login: $login) {
+		repositories(first: $first, after: $after, ownerAffiliations: OWNER, orderBy: {field: PUSHED_AT, direction: DESC}) {` + repositoryFields + `
+		}
+	}
+}`
+
+const viewerRepositoriesQuery = `
+query($first: Int!, $after: String) {
+	rateLimit { cost limit remaining resetAt }
+	viewer {
+		repositories(first: $first, after: $after, ownerAffiliations: OWNER, orderBy: {field: PUSHED_AT, direction: DESC}) {` + repositoryFields + `
+		}
+	}
+}`
+
+const pullRequestReviewsQuery = `
+query($owner: String!, $name: String!, $number: Int!, $first: Int!, $after: String) {
+	rateLimit { cost limit remaining resetAt }
+	repository(owner: $owner, name: $name) {
+		pullRequest(number: $number) {
+			number
+			title
+			url
+			state
+			isDraft
+			mergeable
+			reviewDecision
+			author { login }
+			reviewRequests(first: 20) {
+				nodes {
+					requestedReviewer {
+						... on User { login }
+						... on Team { name }
+					}
+				}
+			}
+			reviews(first: $first, after: $after) {
+				pageInfo { hasNextPage endCursor }
+				nodes {
+					author { login }
+					state
+					submittedAt
+					url
+				}
+			}
+		}
+	}
+}`
+
+const discussionsQuery = `
+query($owner: String!, $name: String!, $first: Int!, $after: String) {
+	rateLimit { cost limit remaining resetAt }
+	repository(owner: $owner, name: $name) {
+		discussions(first: $first, after: $after, orderBy: {field: UPDATED_AT, direction: DESC}) {
+			pageInfo { hasNextPage endCursor }
+			nodes {
+				number
+				title
+				url
+				createdAt
+				updatedAt
+				answerChosenAt
+				upvoteCount
+				author { login }
+				category { name }
+				comments { totalCount }
+			}
+		}
+	}
+}`
+
+// maxReviews bounds how many reviews are collected for a single pull request
+const maxReviews = 100
+
+type actor struct {
+	Login string `json:"login"`
+}
+
+type repositoryNode struct {
+	NameWithOwner   string     `json:"nameWithOwner"`
+	Description     *string    `json:"description"`
+	URL             string     `json:"url"`
+	StargazerCount  int        `json:"stargazerCount"`
+	ForkCount       int        `json:"forkCount"`
+	IsArchived      bool       `json:"isArchived"`
+	PushedAt        *time.Time `json:"pushedAt"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	PullRequests struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"pullRequests"`
+	Issues struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"issues"`
+	DefaultBranchRef *struct {
+		Name   string `json:"name"`
+		Target struct {
+			OID             string    `json:"oid"`
+			MessageHeadline string    `json:"messageHeadline"`
+			CommittedDate   time.Time `json:"committedDate"`
+			URL             string    `json:"url"`
+			Author          *struct {
+				Name string `json:"name"`
+			} `json:"author"`
+		} `json:"target"`
+	} `json:"defaultBranchRef"`
+}
+
+type repositoryConnection struct {
+	PageInfo pageInfo         `json:"pageInfo"`
+	Nodes    []repositoryNode `json:"nodes"`
+}
+
+func (n repositoryNode) toOverview() types.GitHubRepositoryOverview {
+	overview := types.GitHubRepositoryOverview{
+		FullName:         n.NameWithOwner,
+		Description:      n.Description,
+		URL:              n.URL,
+		Stars:            n.StargazerCount,
+		Forks:            n.ForkCount,
+		Archived:         n.IsArchived,
+		PushedAt:         n.PushedAt,
+		OpenPullRequests: n.PullRequests.TotalCount,
+		OpenIssues:       n.Issues.TotalCount,
+	}
+	if n.PrimaryLanguage != nil {
+		overview.Language = &n.PrimaryLanguage.Name
+	}
+	if ref := n.DefaultBranchRef; ref != nil {
+		overview.DefaultBranch = ref.Name
+		// Tags and other non-commit targets have no oid
+		if ref.Target.OID != "" {
+			commit := &types.GitHubCommit{
+				SHA:     shortSHA(ref.Target.OID),
+				Message: ref.Target.MessageHeadline,
+				Date:    ref.Target.CommittedDate,
+				URL:     ref.Target.URL,
+			}
+			if ref.Target.Author != nil {
+				commit.Author = ref.Target.Author.Name
+			}
+			overview.LatestCommit = commit
+		}
+	}
+	return overview
+}
+
+// RepositoryOverviews lists repositories owned by login (or the authenticated user when empty)
+// with their latest default-branch commit and open pull request and issue counts in one round trip per page
+func (c *GraphQLClient) RepositoryOverviews(ctx context.Context, login string, limit int) ([]types.GitHubRepositoryOverview, bool, error) {
+	var overviews []types.GitHubRepositoryOverview
+	truncated, err := c.paginate(limit, func(first int, after *string) (pageInfo, rateLimit, int, error) {
+		variables := map[string]interface{}{"first": first, "after": after}
+
+		var connection *repositoryConnection
+		var limits rateLimit
+		if login == "" {
+			var data struct {
+				RateLimit rateLimit `json:"rateLimit"`
+				Viewer    struct {
+					Repositories repositoryConnection `json:"repositories"`
+				} `json:"viewer"`
+			}
+			if err := c.Query(ctx, viewerRepositoriesQuery, variables, &data); err != nil {
+				return pageInfo{}, rateLimit{}, 0, err
+			}
+			connection, limits = &data.Viewer.Repositories, data.RateLimit
+		} else {
+			variables["login"] = login
+			var data struct {
+				RateLimit       rateLimit `json:"rateLimit"`
+				RepositoryOwner *struct {
+					Repositories repositoryConnection `json:"repositories"`
+				} `json:"repositoryOwner"`
+			}
+			if err := c.Query(ctx, ownerRepositoriesQuery, variables, &data); err != nil {
+				return pageInfo{}, rateLimit{}, 0, err
+			}
+			if data.RepositoryOwner == nil {
+				return pageInfo{}, rateLimit{}, 0, &GraphQLError{Errors: []GraphQLErrorDetails{
+					{Type: "NOT_FOUND", Message: fmt.Sprintf("could not resolve to a user or organization with the login of '%s'", login)},
+				}}
+			}
+			connection, limits = &data.RepositoryOwner.Repositories, data.RateLimit
+		}
+
+		for _, node := range connection.Nodes {
+			overviews = append(overviews, node.toOverview())
+		}
+		return connection.PageInfo, limits, len(connection.Nodes), nil
+	})
+	return overviews, truncated, err
+}
+
+// PullRequestReviewState gets the review decision, requested reviewers and submitted reviews of a pull request
+func (c *GraphQLClient) PullRequestReviewState(ctx context.Context, owner, name string, number int) (*types.GitHubPullRequestReviewState, error) {
+	var state *types.GitHubPullRequestReviewState
+	_, err := c.paginate(maxReviews, func(first int, after *string) (pageInfo, rateLimit, int, error) {
+		var data struct {
+			RateLimit  rateLimit `json:"rateLimit"`
+			Repository *struct {
+				PullRequest *struct {
+					Number         int     `json:"number"`
+					Title          string  `json:"title"`
+					URL            string  `json:"url"`
+					State          string  `json:"state"`
+					IsDraft        bool    `json:"isDraft"`
+					Mergeable      string  `json:"mergeable"`
+					ReviewDecision *string `json:"reviewDecision"`
+					Author         *actor  `json:"author"`
+					ReviewRequests struct {
+						Nodes []struct {
+							RequestedReviewer *struct {
+								Login string `json:"login"`
+								Name  string `json:"name"`
+							} `json:"requestedReviewer"`
+						} `json:"nodes"`
+					} `json:"reviewRequests"`
+					Reviews struct {
+						PageInfo pageInfo `json:"pageInfo"`
+						Nodes    []struct {
+							Author      *actor     `json:"author"`
+							State       string     `json:"state"`
+							SubmittedAt *time.Time `json:"submittedAt"`
+							URL         string     `json:"url"`
+						} `json:"nodes"`
+					} `json:"reviews"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+
+		variables := map[string]interface{}{
+			"owner":  owner,
+			"name":   name,
+			"number": number,
+			"first":  first,
+			"after":  after,
+		}
+		if err := c.Query(ctx, pullRequestReviewsQuery, variables, &data); err != nil {
+			return pageInfo{}, rateLimit{}, 0, err
+		}
+		if data.Repository == nil || data.Repository.PullRequest == nil {
+			return pageInfo{}, rateLimit{}, 0, &GraphQLError{Errors: []GraphQLErrorDetails{
+				{Type: "NOT_FOUND", Message: fmt.Sprintf("pull request %s/%s#%d does not exist", owner, name, number)},
+			}}
+		}
+
+		pr := data.Repository.PullRequest
+		if state == nil {
+			state = &types.GitHubPullRequestReviewState{
+				Number:         pr.Number,
+				Title:          pr.Title,
+				URL:            pr.URL,
+				State:          pr.State,
+				Draft:          pr.IsDraft,
+				Mergeable:      pr.Mergeable,
+				ReviewDecision: pr.ReviewDecision,
+			}
+			if pr.Author != nil {
+				state.Author = pr.Author.Login
+			}
+			for _, request := range pr.ReviewRequests.Nodes {
+				if reviewer := request.RequestedReviewer; reviewer != nil {
+					if reviewer.Login != "" {
+						state.RequestedReviewers = append(state.RequestedReviewers, reviewer.Login)
+					} else if reviewer.Name != "" {
+						state.RequestedReviewers = append(state.RequestedReviewers, reviewer.Name)
+					}
+				}
+			}
+		}
+
+		for _, node := range pr.Reviews.Nodes {
+			review := types.GitHubPullRequestReview{
+				State:       node.State,
+				SubmittedAt: node.SubmittedAt,
+				URL:         node.URL,
+			}
+			if node.Author != nil {
+				review.Reviewer = node.Author.Login
+			}
+			state.Reviews = append(state.Reviews, review)
+		}
+		return pr.Reviews.PageInfo, data.RateLimit, len(pr.Reviews.Nodes), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Discussions lists the most recently updated discussions of a repository
+func (c *GraphQLClient) Discussions(ctx context.Context, owner, name string, limit int) ([]types.GitHubDiscussion, bool, error) {
+	var discussions []types.GitHubDiscussion
+	truncated, err := c.paginate(limit, func(first int, after *string) (pageInfo, rateLimit, int, error) {
+		var data struct {
+			RateLimit  rateLimit `json:"rateLimit"`
+			Repository *struct {
+				Discussions struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						Number         int        `json:"number"`
+						Title          string     `json:"title"`
+						URL            string     `json:"url"`
+						CreatedAt      time.Time  `json:"createdAt"`
+						UpdatedAt      time.Time  `json:"updatedAt"`
+						AnswerChosenAt *time.Time `json:"answerChosenAt"`
+						UpvoteCount    int        `json:"upvoteCount"`
+						Author         *actor     `json:"author"`
+						Category       *struct {
+							Name string `json:"name"`
+						} `json:"category"`
+						Comments struct {
+							TotalCount int `json:"totalCount"`
+						} `json:"comments"`
+					} `json:"nodes"`
+				} `json:"discussions"`
+			} `json:"repository"`
+		}
+
+		variables := map[string]interface{}{
+			"owner": owner,
+			"name":  name,
+			"first": first,
+			"after": after,
+		}
+		if err := c.Query(ctx, discussionsQuery, variables, &data); err != nil {
+			return pageInfo{}, rateLimit{}, 0, err
+		}
+		if data.Repository == nil {
+			return pageInfo{}, rateLimit{}, 0, &GraphQLError{Errors: []GraphQLErrorDetails{
+				{Type: "NOT_FOUND", Message: fmt.Sprintf("repository %s/%s does not exist", owner, name)},
+			}}
+		}
+
+		connection := data.Repository.Discussions
+		for _, node := range connection.Nodes {
+			discussion := types.GitHubDiscussion{
+				Number:    node.Number,
+				Title:     node.Title,
+				URL:       node.URL,
+				Answered:  node.AnswerChosenAt != nil,
+				Comments:  node.Comments.TotalCount,
+				Upvotes:   node.UpvoteCount,
+				CreatedAt: node.CreatedAt,
+				UpdatedAt: node.UpdatedAt,
+			}
+			if node.Author != nil {
+				discussion.Author = node.Author.Login
+			}
+			if node.Category != nil {
+				discussion.Category = node.Category.Name
+			}
+			discussions = append(discussions, discussion)
+		}
+		return connection.PageInfo, data.RateLimit, len(connection.Nodes), nil
+	})
+	return discussions, truncated, err
+}
+
+// shortSHA returns the first 8 characters of a commit SHA
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,149 @@
+package toolset_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/server/event"
+	"github.com/yeeaiclub/a2a-go/sdk/server/execution"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+	itypes "github.com/yeeaiclub/github-a2a/types"
+)
+
+// run is the outcome of one Execute call
+type run struct {
+	err       error
+	statuses  []*types.TaskStatusUpdateEvent
+	artifacts []*types.Artifact
+}
+
+// execute drives the executor with a message made of parts and collects the events it emits
+func execute(t *testing.T, executor *toolset.DeepSeekExecutor, parts ...types.Part) run {
+	t.Helper()
+	queue := event.NewQueue(256)
+	requestContext := &execution.RequestContext{
+		TaskId:    "task-1",
+		ContextId: "context-1",
+		Params: types.MessageSendParam{
+			Message: &types.Message{Role: types.User, MessageID: "message-1", Parts: parts},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result := run{err: executor.Execute(ctx, requestContext, queue)}
+	queue.Close()
+
+	// A subscription ends at the first final event, subscribe again to see anything emitted after it
+	for closed := false; !closed; {
+		for streamEvent := range queue.Subscribe(ctx) {
+			if streamEvent.Type == types.EventClosed || streamEvent.Type == types.EventCanceled {
+				closed = true
+			}
+			switch typed := streamEvent.Event.(type) {
+			case *types.TaskStatusUpdateEvent:
+				if typed.TaskId != "task-1" || typed.ContextId != "context-1" {
+					t.Errorf("status event for task %q context %q", typed.TaskId, typed.ContextId)
+				}
+				result.statuses = append(result.statuses, typed)
+			case *types.TaskArtifactUpdateEvent:
+				result.artifacts = append(result.artifacts, typed.Artifact)
+			}
+		}
+	}
+	return result
+}
+
+// text is a message with a single text part
+func text(s string) types.Part {
+	return &types.TextPart{Kind: "text", Text: s}
+}
+
+// states lists the task states in the order they were emitted
+func (r run) states() []types.TaskState {
+	var states []types.TaskState
+	for _, status := range r.statuses {
+		states = append(states, status.Status.State)
+	}
+	return states
+}
+
+// final returns the last status, which must be the only final one
+func (r run) final(t *testing.T) *types.TaskStatusUpdateEvent {
+	t.Helper()
+	if len(r.statuses) == 0 {
+		t.Fatal("no status events")
+	}
+	for _, status := range r.statuses[:len(r.statuses)-1] {
+		if status.Final {
+			t.Fatalf("status %s is final but not the last one", status.Status.State)
+		}
+	}
+	last := r.statuses[len(r.statuses)-1]
+	if !last.Final {
+		t.Fatalf("last status %s is not final", last.Status.State)
+	}
+	return last
+}
+
+// answer returns the text of the artifacts that carry text parts
+func (r run) answer() string {
+	var texts []string
+	for _, artifact := range r.artifacts {
+		for _, part := range artifact.Parts {
+			if textPart, ok := part.(*types.TextPart); ok {
+				texts = append(texts, textPart.Text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// dataArtifacts returns the artifacts that carry data parts
+func (r run) dataArtifacts() []*types.Artifact {
+	var artifacts []*types.Artifact
+	for _, artifact := range r.artifacts {
+		for _, part := range artifact.Parts {
+			if _, ok := part.(*types.DataPart); ok {
+				artifacts = append(artifacts, artifact)
+				break
+			}
+		}
+	}
+	return artifacts
+}
+
+// messageText joins the text parts of a status message
+func messageText(message *types.Message) string {
+	if message == nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range message.Parts {
+		if textPart, ok := part.(*types.TextPart); ok {
+			texts = append(texts, textPart.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func assertStates(t *testing.T, got []types.TaskState, want ...types.TaskState) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("states = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("states = %v, want %v", got, want)
+		}
+	}
+}
+
+// newExecutor creates an executor answering with the scripted model and offering the given tools
+func newExecutor(client toolset.ChatClient, tools map[string]itypes.Function, opts ...toolset.ExecutorOption) *toolset.DeepSeekExecutor {
+	opts = append([]toolset.ExecutorOption{toolset.WithChatClient(client)}, opts...)
+	return toolset.NewExecutor(nil, nil, tools, "", "You are a test agent.", opts...)
+}
@@ -0,0 +1,298 @@
+package toolset
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	itypes "github.com/yeeaiclub/github-a2a/types"
+)
+
+const (
+	defaultMaxFileBytes  = 100 * 1024
+	defaultMaxInputBytes = 150 * 1024
+	// fileFetchTimeout bounds downloading a file part given by URL
+	fileFetchTimeout = 30 * time.Second
+)
+
+// InputModes are the media types advertised in the agent card, any other text file is accepted as well
+var InputModes = []string{
+	"text",
+	"text/plain",
+	"text/markdown",
+	"text/csv",
+	"text/x-diff",
+	DataMimeType,
+}
+
+// textMimeTypes are application types that hold text the model can read
+var textMimeTypes = map[string]bool{
+	"application/json":     true,
+	"application/x-ndjson": true,
+	"application/xml":      true,
+	"application/yaml":     true,
+	"application/x-yaml":   true,
+	"application/toml":     true,
+	"application/x-patch":  true,
+	"application/x-diff":   true,
+	"application/x-sh":     true,
+	"application/sql":      true,
+	"application/csv":      true,
+}
+
+// InputLimits bounds the files and structured data a message may carry
+type InputLimits struct {
+	// MaxFileBytes bounds a single decoded file
+	MaxFileBytes int
+	// MaxInputBytes bounds the files and data of a message together
+	MaxInputBytes int
+	// AllowedFileHosts are the hosts file parts may be downloaded from, files given by URL
+	// are refused when it is empty
+	AllowedFileHosts []string
+}
+
+// withDefaults fills unset limits with the defaults
+func (l InputLimits) withDefaults() InputLimits {
+	if l.MaxFileBytes <= 0 {
+		l.MaxFileBytes = defaultMaxFileBytes
+	}
+	if l.MaxInputBytes <= 0 {
+		l.MaxInputBytes = defaultMaxInputBytes
+	}
+	return l
+}
+
+// Input is an incoming message prepared for the model
+type Input struct {
+	// Text is the user request followed by the attached files and data
+	Text string
+	// Parameters are the fields of the data parts, they fill tool arguments the model leaves out
+	Parameters map[string]any
+}
+
+// inputReader decodes the parts of a message within the limits
+type inputReader struct {
+	limits InputLimits
+	client *http.Client
+	used   int
+}
+
+// readInput turns the text, file and data parts of a message into the request for the model.
+// Files given by URL are downloaded with client, the default client when nil. Errors describe
+// the rejected part and are meant for the user.
+func readInput(ctx context.Context, parts []types.Part, limits InputLimits, client *http.Client) (Input, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	reader := &inputReader{limits: limits, client: client}
+	var text []string
+	var attachments []string
+	input := Input{}
+
+	for i, part := range parts {
+		switch typed := part.(type) {
+		case *types.TextPart:
+			text = append(text, typed.Text)
+		case *types.FilePart:
+			name, content, err := reader.file(ctx, typed)
+			if err != nil {
+				return Input{}, fmt.Errorf("part %d: %w", i+1, err)
+			}
+			attachments = append(attachments, fmt.Sprintf("Attached file %s (%d bytes):\n````\n%s\n````", name, len(content), strings.TrimRight(content, "\n")))
+		case *types.DataPart:
+			encoded, err := json.MarshalIndent(typed.Data, "", "  ")
+			if err != nil {
+				return Input{}, fmt.Errorf("part %d: data is not valid JSON: %w", i+1, err)
+			}
+			if err := reader.reserve(len(encoded)); err != nil {
+				return Input{}, fmt.Errorf("part %d: %w", i+1, err)
+			}
+			// Decode the encoding again so the values have the types of tool arguments sent by the model
+			var parameters map[string]any
+			if err := json.Unmarshal(encoded, &parameters); err != nil {
+				return Input{}, fmt.Errorf("part %d: data is not valid JSON: %w", i+1, err)
+			}
+			if input.Parameters == nil {
+				input.Parameters = map[string]any{}
+			}
+			for key, value := range parameters {
+				input.Parameters[key] = value
+			}
+			attachments = append(attachments, fmt.Sprintf("Structured parameters:\n```json\n%s\n```", encoded))
+		default:
+			return Input{}, fmt.Errorf("part %d: unsupported part kind %q", i+1, part.GetKind())
+		}
+	}
+
+	input.Text = strings.Join(append(text, attachments...), "\n\n")
+	if strings.TrimSpace(input.Text) == "" {
+		return Input{}, fmt.Errorf("the message has no content")
+	}
+	return input, nil
+}
+
+// reserve counts size bytes against the message limit
+func (r *inputReader) reserve(size int) error {
+	if r.used+size > r.limits.MaxInputBytes {
+		return fmt.Errorf("the attachments exceed the limit of %d bytes per message", r.limits.MaxInputBytes)
+	}
+	r.used += size
+	return nil
+}
+
+// file decodes a file part and checks that it is text the model can read
+func (r *inputReader) file(ctx context.Context, part *types.FilePart) (string, string, error) {
+	if part.File == nil {
+		return "", "", fmt.Errorf("file part has no content")
+	}
+	name := part.File.GetName()
+
+	var content []byte
+	var err error
+	switch file := part.File.(type) {
+	case *types.FileWithBytes:
+		// Reject before decoding, base64 encodes 3 bytes in 4 characters
+		if len(file.Bytes)/4*3 > r.limits.MaxFileBytes+2 {
+			return "", "", fmt.Errorf("file %s exceeds the limit of %d bytes", displayName(name), r.limits.MaxFileBytes)
+		}
+		content, err = base64.StdEncoding.DecodeString(file.Bytes)
+		if err != nil {
+			return "", "", fmt.Errorf("file %s is not valid base64: %w", displayName(name), err)
+		}
+	case *types.FileWithUrl:
+		content, err = r.fetch(ctx, file.Url)
+		if err != nil {
+			return "", "", fmt.Errorf("file %s: %w", displayName(name), err)
+		}
+		if name == "" {
+			if u, err := url.Parse(file.Url); err == nil {
+				name = path.Base(u.Path)
+			}
+		}
+	default:
+		return "", "", fmt.Errorf("unsupported file content %q", part.File.GetKind())
+	}
+
+	if len(content) > r.limits.MaxFileBytes {
+		return "", "", fmt.Errorf("file %s exceeds the limit of %d bytes", displayName(name), r.limits.MaxFileBytes)
+	}
+	if !isText(part.File.GetMimeType(), name, content) {
+		return "", "", fmt.Errorf("file %s is not a text file, only text files such as logs, patches, CSV or JSON are supported", displayName(name))
+	}
+	if err := r.reserve(len(content)); err != nil {
+		return "", "", err
+	}
+	return displayName(name), string(content), nil
+}
+
+// fetch downloads a file from an allowed host, reading at most one byte more than the limit
+func (r *inputReader) fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file URL")
+	}
+	if err := r.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fileFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Every redirect must lead to an allowed host as well
+	client := *r.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("too many redirects")
+		}
+		return r.checkURL(req.URL)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, int64(r.limits.MaxFileBytes)+1))
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	return content, nil
+}
+
+// checkURL refuses URLs that are not http(s) or whose host is not allowed
+func (r *inputReader) checkURL(u *url.URL) error {
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid file URL")
+	}
+	for _, host := range r.limits.AllowedFileHosts {
+		if strings.EqualFold(u.Hostname(), host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("files cannot be downloaded from %s, send the file content instead", u.Hostname())
+}
+
+// isText decides from the declared type, the file name and the content whether a file is readable text
+func isText(mimeType string, name string, content []byte) bool {
+	if mimeType == "" && name != "" {
+		mimeType = mime.TypeByExtension(path.Ext(name))
+	}
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		if strings.HasPrefix(mediaType, "text/") || textMimeTypes[mediaType] || strings.HasSuffix(mediaType, "+json") {
+			return utf8.Valid(content)
+		}
+		if mediaType != "application/octet-stream" {
+			return false
+		}
+	}
+	// Untyped files such as .patch or .log are accepted when they look like text
+	return utf8.Valid(content) && !strings.ContainsRune(string(content), 0)
+}
+
+func displayName(name string) string {
+	if name == "" {
+		return "(unnamed)"
+	}
+	return name
+}
+
+// applyParameters fills the arguments the model left out with the message parameters the tool declares
+func applyParameters(function itypes.Function, args map[string]interface{}, parameters map[string]any) []string {
+	definition := function.FunctionDefinition()
+	if definition.Parameters == nil || len(parameters) == 0 {
+		return nil
+	}
+	var applied []string
+	for property := range definition.Parameters.Properties {
+		if _, ok := args[property]; ok {
+			continue
+		}
+		if value, ok := parameters[property]; ok {
+			args[property] = value
+			applied = append(applied, property)
+		}
+	}
+	sort.Strings(applied)
+	return applied
+}
@@ -0,0 +1,214 @@
+package toolset
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+func filePart(url string) []types.Part {
+	return []types.Part{&types.FilePart{Kind: "file", File: &types.FileWithUrl{Url: url, FileBase: types.FileBase{Name: "build.log"}}}}
+}
+
+func TestFetchChecksEveryRedirect(t *testing.T) {
+	var internalHits int
+	// Reached as localhost, a host that is not allowed
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHits++
+		w.Write([]byte("secret"))
+	}))
+	defer internal.Close()
+	internalURL := strings.Replace(internal.URL, "127.0.0.1", "localhost", 1)
+
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/build.log":
+			w.Write([]byte("ok 42 tests"))
+		case "/moved":
+			http.Redirect(w, r, "/build.log", http.StatusFound)
+		case "/internal":
+			http.Redirect(w, r, internalURL+"/metadata", http.StatusFound)
+		case "/scheme":
+			http.Redirect(w, r, "ftp://127.0.0.1/build.log", http.StatusFound)
+		}
+	}))
+	defer files.Close()
+	limits := InputLimits{AllowedFileHosts: []string{"127.0.0.1"}}.withDefaults()
+	ctx := context.Background()
+
+	// A redirect within the allowed host is followed
+	input, err := readInput(ctx, filePart(files.URL+"/moved"), limits, files.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(input.Text, "ok 42 tests") {
+		t.Errorf("input %q does not contain the file", input.Text)
+	}
+
+	for path, want := range map[string]string{
+		"/internal": "files cannot be downloaded from localhost",
+		"/scheme":   "invalid file URL",
+	} {
+		_, err := readInput(ctx, filePart(files.URL+path), limits, files.Client())
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("redirect from %s: error %v, want %q", path, err, want)
+		}
+	}
+	if internalHits != 0 {
+		t.Errorf("redirect to a host that is not allowed followed %d times", internalHits)
+	}
+	if _, err := readInput(ctx, filePart(internalURL+"/metadata"), limits, files.Client()); err == nil {
+		t.Error("file from a host that is not allowed downloaded")
+	}
+}
+
+func bytesPart(name, mimeType string, content string) *types.FilePart {
+	return &types.FilePart{Kind: "file", File: &types.FileWithBytes{
+		Bytes:    base64.StdEncoding.EncodeToString([]byte(content)),
+		FileBase: types.FileBase{Name: name, MimeType: mimeType},
+	}}
+}
+
+func dataPart(data map[string]any) *types.DataPart {
+	return &types.DataPart{Kind: "data", Data: data}
+}
+
+func TestReadInputLimits(t *testing.T) {
+	limits := InputLimits{MaxFileBytes: 12, MaxInputBytes: 40}
+	text := &types.TextPart{Kind: "text", Text: strings.Repeat("t", 100)}
+	tests := map[string]struct {
+		parts []types.Part
+		want  string
+	}{
+		"file at the limit": {[]types.Part{bytesPart("a.txt", "", strings.Repeat("a", 12))}, ""},
+		"file over the limit": {
+			[]types.Part{bytesPart("a.txt", "", strings.Repeat("a", 13))},
+			"part 1: file a.txt exceeds the limit of 12 bytes",
+		},
+		// Oversized content is refused before it is decoded
+		"oversized invalid base64": {
+			[]types.Part{&types.FilePart{Kind: "file", File: &types.FileWithBytes{Bytes: strings.Repeat("!", 100)}}},
+			"part 1: file (unnamed) exceeds the limit of 12 bytes",
+		},
+		"invalid base64": {
+			[]types.Part{&types.FilePart{Kind: "file", File: &types.FileWithBytes{Bytes: "!!!!"}}},
+			"part 1: file (unnamed) is not valid base64",
+		},
+		// Text does not count against the limit of the attachments, files and data do
+		"text and files": {[]types.Part{text, bytesPart("a.txt", "", strings.Repeat("a", 12)), bytesPart("b.txt", "", strings.Repeat("b", 12))}, ""},
+		"files over the message limit": {
+			[]types.Part{bytesPart("a.txt", "", strings.Repeat("a", 12)), bytesPart("b.txt", "", strings.Repeat("b", 12)), bytesPart("c.txt", "", strings.Repeat("c", 12)), bytesPart("d.txt", "", "ddddd")},
+			"part 4: the attachments exceed the limit of 40 bytes per message",
+		},
+		"data over the message limit": {
+			[]types.Part{bytesPart("a.txt", "", strings.Repeat("a", 12)), bytesPart("b.txt", "", strings.Repeat("b", 12)), dataPart(map[string]any{"owner": "octo"})},
+			"part 3: the attachments exceed the limit of 40 bytes per message",
+		},
+		"binary file": {
+			[]types.Part{bytesPart("logo.png", "", "\x89PNG")},
+			"part 1: file logo.png is not a text file",
+		},
+		"empty": {[]types.Part{&types.TextPart{Kind: "text", Text: " "}}, "the message has no content"},
+	}
+	for name, test := range tests {
+		_, err := readInput(context.Background(), test.parts, limits, nil)
+		switch {
+		case test.want == "" && err != nil:
+			t.Errorf("%s: %v", name, err)
+		case test.want != "" && (err == nil || !strings.HasPrefix(err.Error(), test.want)):
+			t.Errorf("%s: error %v, want %q", name, err, test.want)
+		}
+	}
+}
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		name     string
+		content  string
+		want     bool
+	}{
+		{"text/plain", "", "hello", true},
+		{"text/csv; charset=utf-8", "", "a,b", true},
+		{"application/json", "", `{"a":1}`, true},
+		{"application/vnd.github+json", "", `{"a":1}`, true},
+		{"text/plain", "", "\xff\xfe", false},
+		{"image/png", "notes.txt", "hello", false},
+		// Without a declared type the extension decides
+		{"", "build.csv", "a,b", true},
+		{"", "logo.png", "hello", false},
+		// Unknown extensions and untyped content are sniffed
+		{"", "fix.patch", "--- a/main.go\n+++ b/main.go", true},
+		{"", "", "plain text", true},
+		{"application/octet-stream", "build.log", "ok 42 tests", true},
+		{"", "dump.bin", "a\x00b", false},
+		{"", "", "\xff\xfe", false},
+	}
+	for _, test := range tests {
+		if got := isText(test.mimeType, test.name, []byte(test.content)); got != test.want {
+			t.Errorf("isText(%q, %q, %q) = %v, want %v", test.mimeType, test.name, test.content, got, test.want)
+		}
+	}
+}
+
+func TestReadInputParameters(t *testing.T) {
+	parts := []types.Part{
+		&types.TextPart{Kind: "text", Text: "list the repos"},
+		dataPart(map[string]any{"owner": "octo", "limit": 3}),
+		// Later data parts win
+		dataPart(map[string]any{"limit": 5, "archived": true}),
+	}
+	input, err := readInput(context.Background(), parts, InputLimits{}.withDefaults(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The values have the types of arguments decoded from the model
+	want := map[string]any{"owner": "octo", "limit": 5.0, "archived": true}
+	if !reflect.DeepEqual(input.Parameters, want) {
+		t.Errorf("parameters %v, want %v", input.Parameters, want)
+	}
+	if !strings.HasPrefix(input.Text, "list the repos\n\nStructured parameters:") || strings.Count(input.Text, "Structured parameters:") != 2 {
+		t.Errorf("text %q", input.Text)
+	}
+}
+
+func TestApplyParameters(t *testing.T) {
+	parameters := map[string]any{"query": "from data", "limit": 5.0, "owner": "octo"}
+
+	// Only the declared arguments the model left out are filled
+	args := map[string]interface{}{"query": "from the model"}
+	applied := applyParameters(searchTool(), args, parameters)
+	if !reflect.DeepEqual(applied, []string{"limit"}) {
+		t.Errorf("applied %v, want [limit]", applied)
+	}
+	if want := map[string]interface{}{"query": "from the model", "limit": 5.0}; !reflect.DeepEqual(args, want) {
+		t.Errorf("arguments %v, want %v", args, want)
+	}
+
+	// Tools without arguments get none
+	args = map[string]interface{}{}
+	if applied := applyParameters(namedTool("list_runs"), args, parameters); len(applied) != 0 || len(args) != 0 {
+		t.Errorf("applied %v to a tool without arguments: %v", applied, args)
+	}
+}
+
+func TestFetchWithoutClient(t *testing.T) {
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok 42 tests"))
+	}))
+	defer files.Close()
+	limits := InputLimits{AllowedFileHosts: []string{"127.0.0.1"}}.withDefaults()
+	input, err := readInput(context.Background(), filePart(files.URL+"/build.log"), limits, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(input.Text, "Attached file build.log (11 bytes)") {
+		t.Errorf("input %q", input.Text)
+	}
+}
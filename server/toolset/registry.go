@@ -0,0 +1,153 @@
+package toolset
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	itypes "github.com/yeeaiclub/github-a2a/types"
+)
+
+// Skill identifiers the GitHub tools are grouped under
+const (
+	SkillRepos        = "repos"
+	SkillIssues       = "issues"
+	SkillPullRequests = "pull_requests"
+	SkillCI           = "ci"
+	SkillSecurity     = "security"
+)
+
+// Skill describes a group of related tools advertised to A2A clients
+type Skill struct {
+	ID          string
+	Name        string
+	Description string
+	Tags        []string
+	Examples    []string
+}
+
+// RegistryConfig selects the skills and tools a deployment offers.
+// Empty allowlists enable everything, deny lists are applied after the allowlists.
+type RegistryConfig struct {
+	EnabledSkills  []string `json:"enabled_skills,omitempty"`
+	DisabledSkills []string `json:"disabled_skills,omitempty"`
+	EnabledTools   []string `json:"enabled_tools,omitempty"`
+	DisabledTools  []string `json:"disabled_tools,omitempty"`
+}
+
+type registeredSkill struct {
+	skill Skill
+	tools []itypes.Function
+}
+
+// Registry collects tools under named skills and filters them by deployment configuration
+type Registry struct {
+	config RegistryConfig
+	skills []*registeredSkill
+}
+
+// NewRegistry creates an empty registry using the given configuration
+func NewRegistry(config RegistryConfig) *Registry {
+	return &Registry{config: config}
+}
+
+// Register adds tools under a skill, registering the same skill ID again appends to it
+func (r *Registry) Register(skill Skill, tools ...itypes.Function) {
+	for _, registered := range r.skills {
+		if registered.skill.ID == skill.ID {
+			registered.tools = append(registered.tools, tools...)
+			return
+		}
+	}
+	r.skills = append(r.skills, &registeredSkill{skill: skill, tools: tools})
+}
+
+// Validate reports configuration entries that do not match any registered skill or tool
+func (r *Registry) Validate() error {
+	skills := make(map[string]bool)
+	tools := make(map[string]bool)
+	for _, registered := range r.skills {
+		skills[registered.skill.ID] = true
+		for _, tool := range registered.tools {
+			tools[tool.FunctionDefinition().Name] = true
+		}
+	}
+
+	var unknown []string
+	for _, id := range append(append([]string{}, r.config.EnabledSkills...), r.config.DisabledSkills...) {
+		if !skills[id] {
+			unknown = append(unknown, "skill "+id)
+		}
+	}
+	for _, name := range append(append([]string{}, r.config.EnabledTools...), r.config.DisabledTools...) {
+		if !tools[name] {
+			unknown = append(unknown, "tool "+name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown entries in tool configuration: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Tools returns the enabled tools keyed by function name
+func (r *Registry) Tools() map[string]itypes.Function {
+	tools := make(map[string]itypes.Function)
+	for _, registered := range r.skills {
+		for _, tool := range r.enabledTools(registered) {
+			tools[tool.FunctionDefinition().Name] = tool
+		}
+	}
+	return tools
+}
+
+// Skills returns the agent card skills that have at least one enabled tool
+func (r *Registry) Skills() []types.AgentSkill {
+	var skills []types.AgentSkill
+	for _, registered := range r.skills {
+		enabled := r.enabledTools(registered)
+		if len(enabled) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(enabled))
+		for _, tool := range enabled {
+			names = append(names, tool.FunctionDefinition().Name)
+		}
+		sort.Strings(names)
+
+		skill := registered.skill
+		skills = append(skills, types.AgentSkill{
+			Id:          skill.ID,
+			Name:        skill.Name,
+			Description: fmt.Sprintf("%s (tools: %s)", skill.Description, strings.Join(names, ", ")),
+			Tags:        skill.Tags,
+			Examples:    skill.Examples,
+		})
+	}
+	return skills
+}
+
+func (r *Registry) enabledTools(registered *registeredSkill) []itypes.Function {
+	id := registered.skill.ID
+	if len(r.config.EnabledSkills) > 0 && !contains(r.config.EnabledSkills, id) {
+		return nil
+	}
+	if contains(r.config.DisabledSkills, id) {
+		return nil
+	}
+
+	var tools []itypes.Function
+	for _, tool := range registered.tools {
+		name := tool.FunctionDefinition().Name
+		if len(r.config.EnabledTools) > 0 && !contains(r.config.EnabledTools, name) {
+			continue
+		}
+		if contains(r.config.DisabledTools, name) {
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	return tools
+}
@@ -0,0 +1,111 @@
+package toolset
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func namedTool(name string) *TypedTool[struct{}] {
+	return NewTypedTool(name, "", func(context.Context, struct{}) interface{} { return nil })
+}
+
+func testRegistry(config RegistryConfig) *Registry {
+	r := NewRegistry(config)
+	r.Register(Skill{ID: SkillRepos, Name: "Repositories", Description: "Repositories"}, namedTool("list_repos"), namedTool("get_repo"))
+	r.Register(Skill{ID: SkillIssues, Name: "Issues", Description: "Issues"}, namedTool("list_issues"))
+	// Registering a skill again adds to its tools
+	r.Register(Skill{ID: SkillIssues}, namedTool("get_issue"))
+	r.Register(Skill{ID: SkillCI, Name: "CI", Description: "Workflow runs"}, namedTool("list_runs"))
+	return r
+}
+
+func toolNames(r *Registry) string {
+	var names []string
+	for name := range r.Tools() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestRegistryFilters(t *testing.T) {
+	tests := map[string]struct {
+		config RegistryConfig
+		tools  string
+		skills string
+	}{
+		"everything": {RegistryConfig{}, "get_issue,get_repo,list_issues,list_repos,list_runs", "repos,issues,ci"},
+		"enabled skills": {
+			RegistryConfig{EnabledSkills: []string{SkillIssues, SkillCI}},
+			"get_issue,list_issues,list_runs", "issues,ci",
+		},
+		"disabled skills": {
+			RegistryConfig{DisabledSkills: []string{SkillRepos}},
+			"get_issue,list_issues,list_runs", "issues,ci",
+		},
+		// A tool allowlist empties the skills without an allowed tool
+		"enabled tools": {
+			RegistryConfig{EnabledTools: []string{"get_repo", "list_runs"}},
+			"get_repo,list_runs", "repos,ci",
+		},
+		"disabled tools": {
+			RegistryConfig{DisabledTools: []string{"get_repo", "list_runs"}},
+			"get_issue,list_issues,list_repos", "repos,issues",
+		},
+		// Deny lists win over allowlists
+		"skill enabled and disabled": {
+			RegistryConfig{EnabledSkills: []string{SkillRepos, SkillIssues}, DisabledSkills: []string{SkillIssues}},
+			"get_repo,list_repos", "repos",
+		},
+		"tool enabled and disabled": {
+			RegistryConfig{EnabledTools: []string{"get_repo", "list_repos"}, DisabledTools: []string{"get_repo"}},
+			"list_repos", "repos",
+		},
+		// An allowed tool of a disabled skill stays off
+		"tool of a disabled skill": {
+			RegistryConfig{EnabledTools: []string{"get_repo", "list_issues"}, DisabledSkills: []string{SkillRepos}},
+			"list_issues", "issues",
+		},
+	}
+	for name, test := range tests {
+		r := testRegistry(test.config)
+		if err := r.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if got := toolNames(r); got != test.tools {
+			t.Errorf("%s: tools %s, want %s", name, got, test.tools)
+		}
+		var skills []string
+		for _, skill := range r.Skills() {
+			skills = append(skills, skill.Id)
+		}
+		if got := strings.Join(skills, ","); got != test.skills {
+			t.Errorf("%s: skills %s, want %s", name, got, test.skills)
+		}
+	}
+
+	// The card lists the enabled tools of a skill
+	skills := testRegistry(RegistryConfig{DisabledTools: []string{"get_repo"}}).Skills()
+	if skills[0].Description != "Repositories (tools: list_repos)" || skills[1].Description != "Issues (tools: get_issue, list_issues)" {
+		t.Errorf("skill descriptions %q, %q", skills[0].Description, skills[1].Description)
+	}
+}
+
+func TestRegistryValidate(t *testing.T) {
+	r := testRegistry(RegistryConfig{
+		EnabledSkills:  []string{SkillRepos, "wiki"},
+		DisabledSkills: []string{SkillSecurity},
+		EnabledTools:   []string{"list_repos", "list_repo"},
+		DisabledTools:  []string{"delete_repo"},
+	})
+	err := r.Validate()
+	if err == nil {
+		t.Fatal("unknown skills and tools accepted")
+	}
+	want := "unknown entries in tool configuration: skill wiki, skill security, tool list_repo, tool delete_repo"
+	if err.Error() != want {
+		t.Errorf("error %q, want %q", err, want)
+	}
+}
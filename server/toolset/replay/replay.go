@@ -0,0 +1,251 @@
+// Package replay records GitHub API responses into golden files once and replays them in tests.
+// Requests are matched on method, path, query and body. Dates in the query and body are
+// normalized first, so requests computing a cutoff from the current time keep matching.
+// Credentials are never written: request headers are not recorded and only a few response
+// headers are kept.
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Transport replays or records
+type Mode int
+
+const (
+	// Replay answers from the golden files and fails requests that were not recorded
+	Replay Mode = iota
+	// Record sends requests to the real API and writes the responses into golden files
+	Record
+)
+
+// RecordEnv is the environment variable that switches tests into record mode when set to 1
+const RecordEnv = "GITHUB_RECORD"
+
+// ModeFromEnv returns Record when GITHUB_RECORD=1 and Replay otherwise
+func ModeFromEnv() Mode {
+	if os.Getenv(RecordEnv) == "1" {
+		return Record
+	}
+	return Replay
+}
+
+// keptHeaders are the response headers written to golden files
+var keptHeaders = []string{
+	"Content-Type",
+	"Link",
+	"X-Ratelimit-Limit",
+	"X-Ratelimit-Remaining",
+	"X-Ratelimit-Resource",
+}
+
+// datePattern matches dates and timestamps, URL encoded or not
+var datePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}([T ]|%20|%3A|:|\d|\.|Z|[+-]|%2B)*`)
+
+// Transport records or replays API responses, it is safe for concurrent use
+type Transport struct {
+	// Dir holds the golden files
+	Dir string
+	// Mode selects recording or replaying
+	Mode Mode
+	// Base sends requests while recording, http.DefaultTransport when nil
+	Base http.RoundTripper
+
+	mu sync.Mutex
+}
+
+// Recording is the content of a golden file
+type Recording struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest identifies the request a response belongs to
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// RecordedResponse is replayed for a matching request
+type RecordedResponse struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header,omitempty"`
+	Body   json.RawMessage     `json:"body"`
+}
+
+// RoundTrip answers from the golden file of the request, or records it
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	key := requestKey(req, body)
+	path := filepath.Join(t.Dir, fileName(req, key))
+
+	if t.Mode == Record {
+		return t.record(req, body, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("replay: no recording of %s in %s, record it with %s=1", key, t.Dir, RecordEnv)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var recording Recording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("replay: decode %s: %w", path, err)
+	}
+	return recording.response(req), nil
+}
+
+// record sends the request and writes the response into path
+func (t *Transport) record(req *http.Request, body []byte, path string) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	recording := Recording{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.RequestURI(),
+			Body:   rawJSON(body),
+		},
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: map[string][]string{},
+			Body:   rawJSON(responseBody),
+		},
+	}
+	for _, name := range keptHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			recording.Response.Header[name] = values
+		}
+	}
+
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(recording); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, encoded.Bytes(), 0o644); err != nil {
+		return nil, err
+	}
+	return recording.response(req), nil
+}
+
+// response rebuilds the recorded response for req
+func (r Recording) response(req *http.Request) *http.Response {
+	header := http.Header{}
+	for name, values := range r.Response.Header {
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+	body := []byte(r.Response.Body)
+	// Bodies that are not JSON are stored as a JSON string
+	var text string
+	if json.Unmarshal(body, &text) == nil && !strings.Contains(header.Get("Content-Type"), "json") {
+		body = []byte(text)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Response.Status, http.StatusText(r.Response.Status)),
+		StatusCode:    r.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// rawJSON keeps JSON bodies readable in golden files and stores anything else as a string
+func rawJSON(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		var compact bytes.Buffer
+		if json.Compact(&compact, body) == nil {
+			return compact.Bytes()
+		}
+	}
+	encoded, _ := json.Marshal(string(body))
+	return encoded
+}
+
+// requestKey identifies a request by method, path, sorted query and body with dates normalized
+func requestKey(req *http.Request, body []byte) string {
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			pairs = append(pairs, name+"="+datePattern.ReplaceAllString(value, "{date}"))
+		}
+	}
+	key := req.Method + " " + req.URL.Path
+	if len(pairs) > 0 {
+		key += "?" + strings.Join(pairs, "&")
+	}
+	if len(body) > 0 {
+		sum := sha256.Sum256([]byte(datePattern.ReplaceAllString(string(body), "{date}")))
+		key += " body:" + hex.EncodeToString(sum[:6])
+	}
+	return key
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// fileName is a readable name for the request with a hash of its key, so every request gets its own file
+func fileName(req *http.Request, key string) string {
+	name := strings.Trim(unsafeChars.ReplaceAllString(req.URL.Path, "_"), "_")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%s_%s_%s.json", strings.ToLower(req.Method), name, hex.EncodeToString(sum[:4]))
+}
@@ -0,0 +1,132 @@
+package toolset_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yeeaiclub/github-a2a/server/toolset"
+	"github.com/yeeaiclub/github-a2a/server/toolset/replay"
+)
+
+// replayDir holds the golden files, replayRepoFile in it names the repository they were recorded from
+const (
+	replayDir      = "testdata/github"
+	replayRepoFile = "repository"
+	// replayRepoEnv selects the public repository to record from
+	replayRepoEnv = "GITHUB_REPLAY_REPO"
+)
+
+// newReplayToolset answers the API requests of the toolset from testdata/github and returns the
+// repository they were recorded from. With GITHUB_RECORD=1 it records them from the repository in
+// GITHUB_REPLAY_REPO instead, a public one can be read without GITHUB_TOKEN.
+func newReplayToolset(t *testing.T) (*toolset.GitHubToolset, string) {
+	t.Helper()
+	transport := &replay.Transport{Dir: replayDir, Mode: replay.ModeFromEnv()}
+	if transport.Mode == replay.Record {
+		repo := os.Getenv(replayRepoEnv)
+		if repo == "" {
+			t.Skipf("recording needs %s", replayRepoEnv)
+		}
+		t.Cleanup(func() {
+			if t.Failed() {
+				return
+			}
+			if err := os.WriteFile(filepath.Join(replayDir, replayRepoFile), []byte(repo+"\n"), 0o644); err != nil {
+				t.Error(err)
+			}
+		})
+		return toolset.NewGitHubToolset(os.Getenv("GITHUB_TOKEN"), toolset.WithGitHubTransport(transport)), repo
+	}
+	repo, err := os.ReadFile(filepath.Join(replayDir, replayRepoFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return toolset.NewGitHubToolset("replay-token", toolset.WithGitHubTransport(transport)), strings.TrimSpace(string(repo))
+}
+
+// recordedPullRequests returns the numbers of the pull requests in the recorded issue pages
+func recordedPullRequests(t *testing.T) map[int]bool {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(replayDir, "get_*_issues_*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbers := map[int]bool{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var recording struct {
+			Response struct {
+				Body []struct {
+					Number      int             `json:"number"`
+					PullRequest json.RawMessage `json:"pull_request"`
+				} `json:"body"`
+			} `json:"response"`
+		}
+		if err := json.Unmarshal(data, &recording); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		for _, item := range recording.Response.Body {
+			if item.PullRequest != nil {
+				numbers[item.Number] = true
+			}
+		}
+	}
+	return numbers
+}
+
+func TestReplayListIssues(t *testing.T) {
+	tools, repo := newReplayToolset(t)
+
+	result := tools.ListIssues(context.Background(), repo, nil, ptr(5))
+	if result.Status != "success" {
+		t.Fatalf("status %s: %s", result.Status, result.Message)
+	}
+	if len(result.Data) == 0 || len(result.Data) > 5 {
+		t.Fatalf("%d issues, want 1 to 5", len(result.Data))
+	}
+	// The issues API lists pull requests as well, the tool leaves them out
+	pullRequests := recordedPullRequests(t)
+	for _, issue := range result.Data {
+		if issue.Number == 0 || issue.Title == "" || issue.URL == "" {
+			t.Errorf("incomplete issue %+v", issue)
+		}
+		if pullRequests[issue.Number] {
+			t.Errorf("pull request #%d listed as issue", issue.Number)
+		}
+	}
+}
+
+func TestReplayListPullRequests(t *testing.T) {
+	tools, repo := newReplayToolset(t)
+
+	result := tools.ListPullRequests(context.Background(), repo, ptr("all"), ptr(10))
+	if result.Status != "success" {
+		t.Fatalf("status %s: %s", result.Status, result.Message)
+	}
+	for _, pr := range result.Data {
+		if pr.Number == 0 || pr.State == "" || pr.Author == "" {
+			t.Errorf("incomplete pull request %+v", pr)
+		}
+	}
+}
+
+func TestReplayListWorkflowRuns(t *testing.T) {
+	tools, repo := newReplayToolset(t)
+
+	result := tools.ListWorkflowRuns(context.Background(), repo, nil, nil, ptr(5))
+	if result.Status != "success" {
+		t.Fatalf("status %s: %s", result.Status, result.Message)
+	}
+	for _, run := range result.Data {
+		if run.ID == 0 || run.Status == "" || len(run.HeadSHA) != 8 {
+			t.Errorf("incomplete workflow run %+v", run)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package toolset
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cohesion-org/deepseek-go"
+)
+
+// argField describes a single tool argument derived from a struct field.
+//
+// Supported struct tags:
+//
+//	json:"name"            argument name, fields without a json name are skipped
+//	description:"..."      description shown to the model
+//	enum:"a,b,c"           allowed values
+//	default:"10"           value used when the argument is omitted
+//	minimum:"1"            inclusive lower bound for numbers
+//	maximum:"100"          inclusive upper bound for numbers
+//	required:"true"        the argument must be provided
+type argField struct {
+	name        string
+	kind        string
+	description string
+	enum        []string
+	def         interface{}
+	minimum     *float64
+	maximum     *float64
+	required    bool
+}
+
+// parseArgFields reflects the tool argument struct into argument descriptions
+func parseArgFields(t reflect.Type) ([]argField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("tool arguments must be a struct, got %s", t.Kind())
+	}
+
+	var fields []argField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		kind, err := schemaKind(sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", name, err)
+		}
+
+		field := argField{
+			name:        name,
+			kind:        kind,
+			description: sf.Tag.Get("description"),
+			required:    sf.Tag.Get("required") == "true",
+		}
+		if enum := sf.Tag.Get("enum"); enum != "" {
+			field.enum = strings.Split(enum, ",")
+		}
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			if field.def, err = parseTagValue(kind, def); err != nil {
+				return nil, fmt.Errorf("argument %s default: %w", name, err)
+			}
+		}
+		if field.minimum, err = parseBound(sf.Tag, "minimum"); err != nil {
+			return nil, fmt.Errorf("argument %s: %w", name, err)
+		}
+		if field.maximum, err = parseBound(sf.Tag, "maximum"); err != nil {
+			return nil, fmt.Errorf("argument %s: %w", name, err)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// schemaKind maps a Go type to its JSON Schema type
+func schemaKind(t reflect.Type) (string, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", nil
+	case reflect.Float32, reflect.Float64:
+		return "number", nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func parseBound(tag reflect.StructTag, key string) (*float64, error) {
+	raw, ok := tag.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", key, raw)
+	}
+	return &value, nil
+}
+
+// parseTagValue converts a tag string into the JSON value of the given schema kind
+func parseTagValue(kind string, raw string) (interface{}, error) {
+	switch kind {
+	case "string":
+		return raw, nil
+	case "boolean":
+		return strconv.ParseBool(raw)
+	case "integer":
+		value, err := strconv.ParseInt(raw, 10, 64)
+		return float64(value), err
+	default:
+		return strconv.ParseFloat(raw, 64)
+	}
+}
+
+// buildParameters generates the function parameters schema for the argument fields
+func buildParameters(fields []argField) *deepseek.FunctionParameters {
+	params := &deepseek.FunctionParameters{
+		Type:       "object",
+		Properties: map[string]interface{}{},
+	}
+	for _, field := range fields {
+		property := map[string]interface{}{"type": field.kind}
+		if field.description != "" {
+			property["description"] = field.description
+		}
+		if len(field.enum) > 0 {
+			property["enum"] = field.enum
+		}
+		if field.def != nil {
+			property["default"] = field.def
+		}
+		if field.minimum != nil {
+			property["minimum"] = *field.minimum
+		}
+		if field.maximum != nil {
+			property["maximum"] = *field.maximum
+		}
+		params.Properties[field.name] = property
+		if field.required {
+			params.Required = append(params.Required, field.name)
+		}
+	}
+	return params
+}
+
+// validate checks a decoded argument value against the field description
+func (f argField) validate(value interface{}) string {
+	switch f.kind {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("must be a string, got %s", jsonTypeName(value))
+		}
+		if len(f.enum) > 0 && !contains(f.enum, s) {
+			return fmt.Sprintf("must be one of [%s], got %q", strings.Join(f.enum, ", "), s)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Sprintf("must be a boolean, got %s", jsonTypeName(value))
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Sprintf("must be %s, got %s", article(f.kind), jsonTypeName(value))
+		}
+		if f.kind == "integer" && n != math.Trunc(n) {
+			return fmt.Sprintf("must be an integer, got %v", n)
+		}
+		if f.minimum != nil && n < *f.minimum {
+			return fmt.Sprintf("must be >= %v, got %v", *f.minimum, n)
+		}
+		if f.maximum != nil && n > *f.maximum {
+			return fmt.Sprintf("must be <= %v, got %v", *f.maximum, n)
+		}
+	}
+	return ""
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func article(kind string) string {
+	if kind == "integer" {
+		return "an integer"
+	}
+	return "a " + kind
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package toolset
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type searchArgs struct {
+	Query    string  `json:"query" description:"Search query" required:"true"`
+	Sort     string  `json:"sort" enum:"stars,updated" default:"stars"`
+	Limit    *int    `json:"limit" default:"10" minimum:"1" maximum:"100"`
+	Score    float64 `json:"score" minimum:"0.5"`
+	Archived bool    `json:"archived"`
+	// Fields without a json name are not arguments
+	Ignored string `json:"-"`
+	Unnamed string
+}
+
+func searchTool() *TypedTool[searchArgs] {
+	return NewTypedTool("search", "Search repositories", func(ctx context.Context, args searchArgs) interface{} { return args })
+}
+
+func TestTypedToolSchema(t *testing.T) {
+	got, err := json.Marshal(searchTool().FunctionDefinition().Parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"object","properties":{` +
+		`"archived":{"type":"boolean"},` +
+		`"limit":{"default":10,"maximum":100,"minimum":1,"type":"integer"},` +
+		`"query":{"description":"Search query","type":"string"},` +
+		`"score":{"minimum":0.5,"type":"number"},` +
+		`"sort":{"default":"stars","enum":["stars","updated"],"type":"string"}},` +
+		`"required":["query"]}`
+	if string(got) != want {
+		t.Errorf("schema\n%s\nwant\n%s", got, want)
+	}
+}
+
+type (
+	listArgs struct {
+		A []string `json:"a"`
+	}
+	badDefaultArgs struct {
+		A int `json:"a" default:"ten"`
+	}
+	badBoundArgs struct {
+		A int `json:"a" maximum:"many"`
+	}
+)
+
+func TestTypedToolInvalidTags(t *testing.T) {
+	for name, build := range map[string]func(){
+		"type":    func() { NewTypedTool("t", "", func(context.Context, listArgs) interface{} { return nil }) },
+		"default": func() { NewTypedTool("t", "", func(context.Context, badDefaultArgs) interface{} { return nil }) },
+		"bound":   func() { NewTypedTool("t", "", func(context.Context, badBoundArgs) interface{} { return nil }) },
+		"struct":  func() { NewTypedTool("t", "", func(context.Context, string) interface{} { return nil }) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: invalid arguments accepted", name)
+				}
+			}()
+			build()
+		}()
+	}
+}
+
+func TestTypedToolDecode(t *testing.T) {
+	tool := searchTool()
+
+	// Omitted and null arguments get their defaults
+	args, errs := tool.Decode(map[string]interface{}{"query": "a2a", "sort": nil})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if args.Query != "a2a" || args.Sort != "stars" || args.Limit == nil || *args.Limit != 10 || args.Score != 0 {
+		t.Errorf("decoded %+v", args)
+	}
+	args, errs = tool.Decode(map[string]interface{}{"query": "a2a", "sort": "updated", "limit": 100.0, "score": 0.5, "archived": true})
+	if len(errs) > 0 || args.Sort != "updated" || *args.Limit != 100 || args.Score != 0.5 || !args.Archived {
+		t.Errorf("decoded %+v, %v", args, errs)
+	}
+
+	tests := map[string]struct {
+		args map[string]interface{}
+		want []ValidationError
+	}{
+		"required": {map[string]interface{}{}, []ValidationError{{"query", "is required"}}},
+		"unknown": {map[string]interface{}{"query": "a2a", "q": "a2a", "author": "mona"}, []ValidationError{
+			{"author", "is not a known argument"}, {"q", "is not a known argument"},
+		}},
+		"string":  {map[string]interface{}{"query": 7.0}, []ValidationError{{"query", "must be a string, got number"}}},
+		"enum":    {map[string]interface{}{"query": "a2a", "sort": "forks"}, []ValidationError{{"sort", `must be one of [stars, updated], got "forks"`}}},
+		"integer": {map[string]interface{}{"query": "a2a", "limit": 2.5}, []ValidationError{{"limit", "must be an integer, got 2.5"}}},
+		"number":  {map[string]interface{}{"query": "a2a", "limit": "10"}, []ValidationError{{"limit", "must be an integer, got string"}}},
+		"minimum": {map[string]interface{}{"query": "a2a", "limit": 0.0, "score": 0.1}, []ValidationError{
+			{"limit", "must be >= 1, got 0"}, {"score", "must be >= 0.5, got 0.1"},
+		}},
+		"maximum": {map[string]interface{}{"query": "a2a", "limit": 101.0}, []ValidationError{{"limit", "must be <= 100, got 101"}}},
+		"boolean": {map[string]interface{}{"query": "a2a", "archived": "yes"}, []ValidationError{{"archived", "must be a boolean, got string"}}},
+	}
+	for name, test := range tests {
+		if _, errs := tool.Decode(test.args); !reflect.DeepEqual(errs, test.want) {
+			t.Errorf("%s: errors %v, want %v", name, errs, test.want)
+		}
+	}
+
+	// Rejected arguments are returned to the model instead of calling the handler
+	result := tool.Call(context.Background(), map[string]interface{}{"limit": 0.0})
+	if toolErr, ok := result.(*ToolError); !ok || toolErr.Status != "error" || len(toolErr.Errors) != 2 {
+		t.Errorf("result %+v", result)
+	}
+}
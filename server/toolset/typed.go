@@ -0,0 +1,125 @@
+package toolset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/cohesion-org/deepseek-go"
+)
+
+// ValidationError describes why a single tool argument was rejected
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ToolError is returned to the model instead of a tool result when the call could not be executed
+type ToolError struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Errors  []ValidationError `json:"errors,omitempty"`
+}
+
+// NewValidationError creates a ToolError for rejected arguments of the named tool
+func NewValidationError(tool string, errors []ValidationError) *ToolError {
+	return &ToolError{
+		Status:  "error",
+		Message: fmt.Sprintf("Invalid arguments for %s, fix the listed fields and call the tool again", tool),
+		Errors:  errors,
+	}
+}
+
+// TypedTool implements types.Function for a handler taking a typed argument struct.
+// The JSON Schema sent to the model is generated from the struct tags of A, see argField.
+type TypedTool[A any] struct {
+	name        string
+	description string
+	fields      []argField
+	parameters  *deepseek.FunctionParameters
+	handler     func(ctx context.Context, args A) interface{}
+}
+
+// NewTypedTool creates a tool from an argument struct and a handler.
+// It panics if A cannot be described as a JSON Schema object.
+func NewTypedTool[A any](name string, description string, handler func(ctx context.Context, args A) interface{}) *TypedTool[A] {
+	fields, err := parseArgFields(reflect.TypeOf((*A)(nil)).Elem())
+	if err != nil {
+		panic(fmt.Sprintf("toolset: invalid arguments for tool %s: %v", name, err))
+	}
+	return &TypedTool[A]{
+		name:        name,
+		description: description,
+		fields:      fields,
+		parameters:  buildParameters(fields),
+		handler:     handler,
+	}
+}
+
+func (t *TypedTool[A]) FunctionDefinition() deepseek.Function {
+	return deepseek.Function{
+		Name:        t.name,
+		Description: t.description,
+		Parameters:  t.parameters,
+	}
+}
+
+func (t *TypedTool[A]) Call(ctx context.Context, args map[string]interface{}) interface{} {
+	decoded, errs := t.Decode(args)
+	if len(errs) > 0 {
+		return NewValidationError(t.name, errs)
+	}
+	return t.handler(ctx, decoded)
+}
+
+// Decode validates raw model arguments, applies defaults and decodes them into A
+func (t *TypedTool[A]) Decode(args map[string]interface{}) (A, []ValidationError) {
+	var decoded A
+	var errs []ValidationError
+
+	values := make(map[string]interface{}, len(t.fields))
+	known := make(map[string]bool, len(t.fields))
+	for _, field := range t.fields {
+		known[field.name] = true
+
+		value, ok := args[field.name]
+		if !ok || value == nil {
+			if field.required {
+				errs = append(errs, ValidationError{Field: field.name, Message: "is required"})
+			} else if field.def != nil {
+				values[field.name] = field.def
+			}
+			continue
+		}
+		if msg := field.validate(value); msg != "" {
+			errs = append(errs, ValidationError{Field: field.name, Message: msg})
+			continue
+		}
+		values[field.name] = value
+	}
+
+	var unknown []string
+	for name := range args {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, ValidationError{Field: name, Message: "is not a known argument"})
+	}
+	if len(errs) > 0 {
+		return decoded, errs
+	}
+
+	raw, err := json.Marshal(values)
+	if err == nil {
+		err = json.Unmarshal(raw, &decoded)
+	}
+	if err != nil {
+		return decoded, []ValidationError{{Field: "", Message: err.Error()}}
+	}
+	return decoded, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/toolset/fakellm"
+	"github.com/yeeaiclub/github-a2a/server/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider recording the spans for the duration of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	if _, err := tracing.Setup(context.Background(), tracing.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// endedSpans waits until the spans with the names ended and returns all ended spans by name
+func endedSpans(t *testing.T, recorder *tracetest.SpanRecorder, names ...string) map[string][]sdktrace.ReadOnlySpan {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		spans := map[string][]sdktrace.ReadOnlySpan{}
+		for _, span := range recorder.Ended() {
+			spans[span.Name()] = append(spans[span.Name()], span)
+		}
+		missing := ""
+		for _, name := range names {
+			if len(spans[name]) == 0 {
+				missing = name
+			}
+		}
+		if missing == "" {
+			return spans
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("span %s did not end, got %v", missing, spans)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := recordSpans(t)
+	llm := fakellm.New(
+		fakellm.CallTools(fakellm.ToolCall("list_pull_requests", map[string]any{"repoName": "octo/demo"})),
+		fakellm.Reply("There is one open pull request."),
+	)
+	agent := startAgent(t, llm)
+
+	// The client continues its own trace
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	req, err := http.NewRequest(http.MethodPost, agent.URL+"/api", bytes.NewReader(request("1", types.MethodMessageSend, userMessage("Which pull requests are open?"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	spans := endedSpans(t, recorder, "a2a message/send", "a2a.task.execute", "llm.iteration", "llm.chat_completion", "tool.call", "GitHub GET")
+	for name, spans := range spans {
+		for _, span := range spans {
+			if got := span.SpanContext().TraceID().String(); got != traceID {
+				t.Errorf("span %s in trace %s, want the trace of the client", name, got)
+			}
+		}
+	}
+
+	request := spans["a2a message/send"][0]
+	if request.Parent().SpanID().String() != parentID || !request.Parent().IsRemote() {
+		t.Errorf("request span parent %v, want the span of the client", request.Parent())
+	}
+	if got := spanAttribute(request, "rpc.method").AsString(); got != types.MethodMessageSend {
+		t.Errorf("request span rpc.method %q", got)
+	}
+
+	execute := spans["a2a.task.execute"][0]
+	if execute.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Error("task execute span is not a child of the request span")
+	}
+	if spanAttribute(execute, "a2a.task_id").AsString() == "" {
+		t.Error("task execute span without task id")
+	}
+
+	iterations := spans["llm.iteration"]
+	if len(iterations) != 2 {
+		t.Fatalf("%d model iterations, want 2", len(iterations))
+	}
+	isIteration := map[string]bool{}
+	for _, iteration := range iterations {
+		isIteration[iteration.SpanContext().SpanID().String()] = true
+		if iteration.Parent().SpanID() != execute.SpanContext().SpanID() {
+			t.Error("model iteration is not a child of the task execute span")
+		}
+	}
+	for _, completion := range spans["llm.chat_completion"] {
+		if !isIteration[completion.Parent().SpanID().String()] {
+			t.Error("chat completion is not a child of a model iteration")
+		}
+	}
+
+	call := spans["tool.call"][0]
+	if !isIteration[call.Parent().SpanID().String()] {
+		t.Error("tool call is not a child of a model iteration")
+	}
+	if got := spanAttribute(call, "tool.name").AsString(); got != "list_pull_requests" {
+		t.Errorf("tool call span tool.name %q", got)
+	}
+
+	// The GitHub request carries the span that traced it
+	githubSpan := spans["GitHub GET"][0]
+	if githubSpan.Parent().SpanID() != call.SpanContext().SpanID() {
+		t.Error("GitHub request is not a child of the tool call")
+	}
+	headers := agent.github.Headers()
+	if len(headers) != 1 {
+		t.Fatalf("%d GitHub requests, want 1", len(headers))
+	}
+	want := "00-" + traceID + "-" + githubSpan.SpanContext().SpanID().String() + "-01"
+	if got := headers[0].Get("traceparent"); !strings.EqualFold(got, want) {
+		t.Errorf("GitHub request traceparent %q, want %q", got, want)
+	}
+}
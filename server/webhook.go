@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/yeeaiclub/a2a-go/sdk/server/tasks"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/config"
+	"github.com/yeeaiclub/github-a2a/server/push"
+	"github.com/yeeaiclub/github-a2a/server/webhook"
+)
+
+// newWebhookReceiver starts a task of the agent for every configured rule a GitHub delivery matches, see runTask
+func newWebhookReceiver(cfg config.GitHubWebhookConfig, taskHandler *TaskHandler, taskStore tasks.TaskStore, notifier *push.Notifier) (*webhook.Receiver, error) {
+	var rules []webhook.Rule
+	for _, spec := range cfg.Rules {
+		rule := webhook.Rule{
+			Name:        spec.Name,
+			Event:       spec.Event,
+			Actions:     spec.Actions,
+			Repos:       spec.Repos,
+			Branches:    spec.Branches,
+			Conclusions: spec.Conclusions,
+			Prompt:      spec.Prompt,
+		}
+		if spec.ResultWebhook != "" {
+			rule.Notify = &types.PushNotificationConfig{URL: spec.ResultWebhook}
+			if spec.ResultWebhookToken != "" {
+				rule.Notify.Authentication = &types.PushNotificationAuthenticationInfo{
+					Schemes:     []string{"Bearer"},
+					Credentials: spec.ResultWebhookToken,
+				}
+			}
+		}
+		rules = append(rules, rule)
+	}
+
+	var opts []webhook.Option
+	// A nil *push.Notifier must not end up in the interface, the receiver checks for nil
+	if notifier != nil {
+		opts = append(opts, webhook.WithNotifier(notifier))
+	}
+	return webhook.New(webhook.Config{Secret: cfg.Secret, Rules: rules, MaxBodyBytes: cfg.MaxBodyBytes}, runTask(taskHandler, taskStore), opts...)
+}
@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Supported event names, the value of the X-GitHub-Event header
+const (
+	EventPush        = "push"
+	EventPullRequest = "pull_request"
+	EventIssues      = "issues"
+	EventWorkflowRun = "workflow_run"
+	eventPing        = "ping"
+)
+
+// SupportedEvents are the events rules can react to
+var SupportedEvents = []string{EventPush, EventPullRequest, EventIssues, EventWorkflowRun}
+
+// Event is a webhook delivery reduced to what rules match on and prompts need. Prompts are
+// text/template templates executed with it, Payload holds the complete delivery for anything else.
+type Event struct {
+	Delivery string `json:"delivery"`
+	Name     string `json:"event"`
+	Action   string `json:"action,omitempty"`
+	Repo     string `json:"repo"`
+	Sender   string `json:"sender,omitempty"`
+	// Number, Title and URL describe the pull request or issue, URL the workflow run or the compare view of a push
+	Number int    `json:"number,omitempty"`
+	Title  string `json:"title,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// Branch is the pushed branch, the base branch of a pull request or the head branch of a workflow run
+	Branch string `json:"branch,omitempty"`
+	// Tag is the pushed tag
+	Tag string `json:"tag,omitempty"`
+	// Commits and HeadCommit describe a push, HeadCommit is the first line of the message of its last commit
+	Commits    int    `json:"commits,omitempty"`
+	HeadCommit string `json:"head_commit,omitempty"`
+	// Deleted is set for a push deleting a branch or tag
+	Deleted bool `json:"deleted,omitempty"`
+	// Merged is set when a closed pull request was merged
+	Merged bool `json:"merged,omitempty"`
+	// Workflow and Conclusion describe a workflow run
+	Workflow   string `json:"workflow,omitempty"`
+	Conclusion string `json:"conclusion,omitempty"`
+
+	Payload map[string]any `json:"-"`
+}
+
+// payload is the part of a delivery the Event is built from
+type payload struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+
+	// push
+	Ref        string `json:"ref"`
+	Deleted    bool   `json:"deleted"`
+	Compare    string `json:"compare"`
+	Commits    []any  `json:"commits"`
+	HeadCommit *struct {
+		Message string `json:"message"`
+	} `json:"head_commit"`
+
+	PullRequest *struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+		Merged  bool   `json:"merged"`
+		Base    struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	} `json:"pull_request"`
+
+	Issue *struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+	} `json:"issue"`
+
+	WorkflowRun *struct {
+		Name       string `json:"name"`
+		HeadBranch string `json:"head_branch"`
+		Conclusion string `json:"conclusion"`
+		HTMLURL    string `json:"html_url"`
+	} `json:"workflow_run"`
+}
+
+// parseEvent decodes the body of a delivery of one of the supported events
+func parseEvent(name string, delivery string, body []byte) (*Event, error) {
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("decode %s payload: %w", name, err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("decode %s payload: %w", name, err)
+	}
+	if p.Repository.FullName == "" {
+		return nil, fmt.Errorf("%s payload without repository", name)
+	}
+
+	event := &Event{
+		Delivery: delivery,
+		Name:     name,
+		Action:   p.Action,
+		Repo:     p.Repository.FullName,
+		Sender:   p.Sender.Login,
+		Payload:  raw,
+	}
+	switch name {
+	case EventPush:
+		if branch, ok := strings.CutPrefix(p.Ref, "refs/heads/"); ok {
+			event.Branch = branch
+		} else {
+			event.Tag = strings.TrimPrefix(p.Ref, "refs/tags/")
+		}
+		event.URL = p.Compare
+		event.Deleted = p.Deleted
+		event.Commits = len(p.Commits)
+		if p.HeadCommit != nil {
+			event.HeadCommit, _, _ = strings.Cut(p.HeadCommit.Message, "\n")
+		}
+	case EventPullRequest:
+		if p.PullRequest == nil {
+			return nil, fmt.Errorf("%s payload without pull_request", name)
+		}
+		event.Number = p.PullRequest.Number
+		event.Title = p.PullRequest.Title
+		event.URL = p.PullRequest.HTMLURL
+		event.Branch = p.PullRequest.Base.Ref
+		event.Merged = p.PullRequest.Merged
+	case EventIssues:
+		if p.Issue == nil {
+			return nil, fmt.Errorf("%s payload without issue", name)
+		}
+		event.Number = p.Issue.Number
+		event.Title = p.Issue.Title
+		event.URL = p.Issue.HTMLURL
+	case EventWorkflowRun:
+		if p.WorkflowRun == nil {
+			return nil, fmt.Errorf("%s payload without workflow_run", name)
+		}
+		event.Workflow = p.WorkflowRun.Name
+		event.Branch = p.WorkflowRun.HeadBranch
+		event.Conclusion = p.WorkflowRun.Conclusion
+		event.URL = p.WorkflowRun.HTMLURL
+	}
+	return event, nil
+}
+
+// subject names what the event is about, deliveries about the same pull request or issue share a context
+func (e *Event) subject() string {
+	switch e.Name {
+	case EventPullRequest:
+		return fmt.Sprintf("github/%s/pull/%d", e.Repo, e.Number)
+	case EventIssues:
+		return fmt.Sprintf("github/%s/issues/%d", e.Repo, e.Number)
+	}
+	return ""
+}
+
+// defaultPrompts are used by rules without a prompt
+var defaultPrompts = map[string]string{
+	EventPush: `{{.Sender}} pushed {{.Commits}} commits to {{if .Branch}}branch {{.Branch}}{{else}}tag {{.Tag}}{{end}} of {{.Repo}}, the last one is "{{.HeadCommit}}". ` +
+		`Summarize what the recent commits change and point out anything risky.`,
+	EventPullRequest: `Pull request #{{.Number}} "{{.Title}}" in {{.Repo}} was {{.Action}} by {{.Sender}} ({{.URL}}). ` +
+		`Summarize what it changes, the state of its reviews and anything that needs attention.`,
+	EventIssues: `Issue #{{.Number}} "{{.Title}}" in {{.Repo}} was {{.Action}} by {{.Sender}} ({{.URL}}). ` +
+		`Summarize it, find related open issues and pull requests and suggest the next step.`,
+	EventWorkflowRun: `The workflow {{.Workflow}} on branch {{.Branch}} of {{.Repo}} finished with conclusion {{.Conclusion}} ({{.URL}}). ` +
+		`Look at the recent workflow runs and commits of the branch and explain the likely cause.`,
+}
@@ -0,0 +1,486 @@
+// Package webhook receives GitHub webhook deliveries and turns them into agent tasks. Deliveries are
+// verified with the shared secret, matched against rules, and every matching rule starts a task with
+// its prompt. Deliveries and the outcome of their tasks are recorded.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+)
+
+// Headers of a GitHub webhook delivery
+const (
+	HeaderEvent     = "X-GitHub-Event"
+	HeaderDelivery  = "X-GitHub-Delivery"
+	HeaderSignature = "X-Hub-Signature-256"
+)
+
+// Rule starts a task for the deliveries it matches. Empty filters match everything, the repository
+// and branch filters are path.Match patterns such as "octo/*" or "release/*".
+type Rule struct {
+	Name  string
+	Event string
+	// Actions match the action of the delivery, such as opened for a pull request
+	Actions  []string
+	Repos    []string
+	Branches []string
+	// Conclusions match the conclusion of a workflow run, such as failure
+	Conclusions []string
+	// Prompt is a text/template executed with the Event, a prompt for the event when empty
+	Prompt string
+	// Notify receives the finished task as a push notification when set
+	Notify *types.PushNotificationConfig
+}
+
+// Config configures the receiver
+type Config struct {
+	// Secret verifies the signature of every delivery, it is required
+	Secret string
+	Rules  []Rule
+	// MaxBodyBytes bounds a delivery, 5 MiB when zero
+	MaxBodyBytes int64
+	// LogSize is how many deliveries are recorded, 200 when zero
+	LogSize int
+}
+
+// RunFunc runs a message as an agent task and returns the task once it stopped running
+type RunFunc func(ctx context.Context, message *types.Message) (*types.Task, error)
+
+// Notifier delivers a task to the push notification config registered for it
+type Notifier interface {
+	SetInfo(ctx context.Context, taskId string, config *types.PushNotificationConfig) error
+	SendNotification(task *types.Task) error
+}
+
+// Option configures optional Receiver dependencies
+type Option func(r *Receiver)
+
+// WithNotifier delivers the tasks of rules with Notify set, it is required when a rule sets it
+func WithNotifier(notifier Notifier) Option {
+	return func(r *Receiver) {
+		r.notifier = notifier
+	}
+}
+
+// Delivery statuses
+const (
+	StatusAccepted  = "accepted"
+	StatusUnmatched = "unmatched"
+	StatusIgnored   = "ignored"
+	StatusDuplicate = "duplicate"
+	StatusRejected  = "rejected"
+)
+
+// Delivery records a webhook delivery and the tasks it started
+type Delivery struct {
+	ID         string    `json:"id"`
+	Event      string    `json:"event"`
+	Action     string    `json:"action,omitempty"`
+	Repo       string    `json:"repo,omitempty"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+	Tasks      []*Task   `json:"tasks,omitempty"`
+}
+
+// Task records a task started for a delivery
+type Task struct {
+	Rule       string          `json:"rule"`
+	TaskID     string          `json:"task_id"`
+	State      types.TaskState `json:"state,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// rule is a Rule with its parsed prompt
+type rule struct {
+	Rule
+	prompt *template.Template
+}
+
+// Receiver serves the webhook endpoint
+type Receiver struct {
+	secret   []byte
+	maxBody  int64
+	logSize  int
+	rules    []*rule
+	run      RunFunc
+	notifier Notifier
+
+	mu  sync.Mutex
+	log []*Delivery
+	// handling holds the IDs of the deliveries between claim and record
+	handling map[string]bool
+
+	runs sync.WaitGroup
+}
+
+// New validates the rules, the receiver starts a task with run for every rule a delivery matches
+func New(config Config, run RunFunc, opts ...Option) (*Receiver, error) {
+	r := &Receiver{
+		secret:   []byte(config.Secret),
+		maxBody:  config.MaxBodyBytes,
+		logSize:  config.LogSize,
+		run:      run,
+		handling: map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.maxBody <= 0 {
+		r.maxBody = 5 << 20
+	}
+	if r.logSize <= 0 {
+		r.logSize = 200
+	}
+	if len(r.secret) == 0 {
+		return nil, errors.New("a webhook secret is required")
+	}
+
+	var errs []error
+	for i, spec := range config.Rules {
+		parsed, err := r.newRule(spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d (%s): %w", i, spec.Name, err))
+			continue
+		}
+		r.rules = append(r.rules, parsed)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Receiver) newRule(spec Rule) (*rule, error) {
+	if !slices.Contains(SupportedEvents, spec.Event) {
+		return nil, fmt.Errorf("event %q is not one of %s", spec.Event, strings.Join(SupportedEvents, ", "))
+	}
+	if spec.Name == "" {
+		spec.Name = spec.Event
+	}
+	for _, pattern := range append(append([]string(nil), spec.Repos...), spec.Branches...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if spec.Notify != nil && r.notifier == nil {
+		return nil, errors.New("notifying requires push notifications")
+	}
+	if spec.Prompt == "" {
+		spec.Prompt = defaultPrompts[spec.Event]
+	}
+	prompt, err := template.New(spec.Name).Parse(spec.Prompt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt: %w", err)
+	}
+	return &rule{Rule: spec, prompt: prompt}, nil
+}
+
+// matches reports whether the rule applies to an event
+func (r *rule) matches(event *Event) bool {
+	if r.Event != event.Name || event.Deleted {
+		return false
+	}
+	if len(r.Actions) > 0 && !slices.Contains(r.Actions, event.Action) {
+		return false
+	}
+	if len(r.Conclusions) > 0 && !slices.Contains(r.Conclusions, event.Conclusion) {
+		return false
+	}
+	return matchAny(r.Repos, event.Repo) && matchAny(r.Branches, event.Branch)
+}
+
+// matchAny reports whether a value matches one of the patterns, any value matches no patterns
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok && value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP verifies a delivery, answers at once and runs the tasks of the matching rules in the background
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, r.maxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Delivery too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read delivery", http.StatusBadRequest)
+		return
+	}
+	// Unsigned deliveries are answered without being recorded, anyone can send them
+	if !r.verify(req.Header.Get(HeaderSignature), body) {
+		slog.Warn("Rejected GitHub webhook delivery with an invalid signature", "delivery", req.Header.Get(HeaderDelivery), "remote", req.RemoteAddr)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	delivery := &Delivery{
+		ID:         req.Header.Get(HeaderDelivery),
+		Event:      req.Header.Get(HeaderEvent),
+		ReceivedAt: time.Now(),
+	}
+	if delivery.ID == "" {
+		delivery.ID = uuid.NewString()
+	}
+	status, err := r.handle(delivery, body)
+	if err != nil {
+		delivery.Status = StatusRejected
+		delivery.Error = err.Error()
+		r.record(delivery)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	r.respond(w, status, delivery)
+}
+
+// handle matches a verified delivery against the rules and starts their tasks, returning the HTTP status
+func (r *Receiver) handle(delivery *Delivery, body []byte) (int, error) {
+	if delivery.Event == "" {
+		return http.StatusBadRequest, fmt.Errorf("missing %s header", HeaderEvent)
+	}
+	// GitHub retries failed deliveries and redelivers on request, the tasks of a delivery run once
+	if !r.claim(delivery.ID) {
+		delivery.Status = StatusDuplicate
+		return http.StatusOK, nil
+	}
+	if delivery.Event == eventPing || !slices.Contains(SupportedEvents, delivery.Event) {
+		delivery.Status = StatusIgnored
+		r.record(delivery)
+		return http.StatusOK, nil
+	}
+
+	event, err := parseEvent(delivery.Event, delivery.ID, body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	delivery.Action = event.Action
+	delivery.Repo = event.Repo
+
+	var starts []func()
+	for _, rule := range r.rules {
+		if !rule.matches(event) {
+			continue
+		}
+		message, err := rule.message(event)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		task := &Task{Rule: rule.Name, TaskID: message.TaskID}
+		delivery.Tasks = append(delivery.Tasks, task)
+		starts = append(starts, func() { r.start(rule, message, task) })
+	}
+	delivery.Status = StatusAccepted
+	if len(starts) == 0 {
+		delivery.Status = StatusUnmatched
+	}
+	r.record(delivery)
+	for _, start := range starts {
+		start()
+	}
+	if len(starts) == 0 {
+		return http.StatusOK, nil
+	}
+	return http.StatusAccepted, nil
+}
+
+// message renders the prompt of the rule for an event, the task ID is assigned up front so it can
+// be returned in the response before the task starts
+func (r *rule) message(event *Event) (*types.Message, error) {
+	var prompt bytes.Buffer
+	if err := r.prompt.Execute(&prompt, event); err != nil {
+		return nil, fmt.Errorf("render prompt of rule %s: %w", r.Name, err)
+	}
+	contextID := event.subject()
+	if contextID == "" {
+		contextID = uuid.NewString()
+	}
+	return &types.Message{
+		Kind:      "message",
+		MessageID: uuid.NewString(),
+		TaskID:    uuid.NewString(),
+		ContextID: contextID,
+		Role:      types.User,
+		Parts:     []types.Part{&types.TextPart{Kind: "text", Text: prompt.String()}},
+		Metadata: map[string]any{
+			"github_event":    event.Name,
+			"github_delivery": event.Delivery,
+			"webhook_rule":    r.Name,
+		},
+	}, nil
+}
+
+// start runs the task of a rule in the background and records its outcome
+func (r *Receiver) start(rule *rule, message *types.Message, record *Task) {
+	r.runs.Add(1)
+	go func() {
+		defer r.runs.Done()
+		ctx := context.Background()
+		logger := slog.With("rule", rule.Name, "task_id", record.TaskID)
+
+		task, err := r.run(ctx, message)
+		if err == nil && rule.Notify != nil {
+			if err = r.notifier.SetInfo(ctx, task.Id, rule.Notify); err == nil {
+				err = r.notifier.SendNotification(task)
+			}
+		}
+
+		now := time.Now()
+		r.mu.Lock()
+		record.FinishedAt = &now
+		if task != nil {
+			record.State = task.Status.State
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		r.mu.Unlock()
+
+		if err != nil {
+			logger.Error("Webhook task failed", "error", err)
+			return
+		}
+		logger.Info("Webhook task finished", "state", task.Status.State)
+	}()
+}
+
+// Wait blocks until the running tasks finished or the context ends
+func (r *Receiver) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// verify checks the HMAC-SHA256 signature GitHub computes over the body with the webhook secret
+func (r *Receiver) verify(signature string, body []byte) bool {
+	hexSum, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	sum, err := hex.DecodeString(hexSum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, r.secret)
+	mac.Write(body)
+	return hmac.Equal(sum, mac.Sum(nil))
+}
+
+// Sign returns the X-Hub-Signature-256 header value GitHub sends for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// claim reserves a delivery ID until the delivery is recorded, it fails for an ID being handled or
+// recorded already unless that delivery was rejected. Checking and reserving at once keeps concurrent
+// redeliveries from starting the tasks twice.
+func (r *Receiver) claim(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.handling[id] {
+		return false
+	}
+	for _, delivery := range r.log {
+		if delivery.ID == id && delivery.Status != StatusRejected {
+			return false
+		}
+	}
+	r.handling[id] = true
+	return true
+}
+
+// record appends a delivery to the log, dropping the oldest entries beyond the log size, and releases
+// its claim
+func (r *Receiver) record(delivery *Delivery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.handling, delivery.ID)
+	r.log = append(r.log, delivery)
+	if len(r.log) > r.logSize {
+		r.log = r.log[len(r.log)-r.logSize:]
+	}
+}
+
+// Deliveries returns copies of the recorded deliveries, newest first
+func (r *Receiver) Deliveries() []Delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deliveries := make([]Delivery, 0, len(r.log))
+	for i := len(r.log) - 1; i >= 0; i-- {
+		delivery := *r.log[i]
+		delivery.Tasks = nil
+		for _, task := range r.log[i].Tasks {
+			copied := *task
+			delivery.Tasks = append(delivery.Tasks, &copied)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries
+}
+
+func (r *Receiver) respond(w http.ResponseWriter, status int, delivery *Delivery) {
+	r.mu.Lock()
+	body, err := json.Marshal(delivery)
+	r.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// DeliveriesHandler serves the delivery log as JSON
+func (r *Receiver) DeliveriesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(r.Deliveries()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
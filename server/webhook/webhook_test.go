@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/internal/fakeagent"
+)
+
+const secret = "It's a Secret to Everybody"
+
+const pullRequestOpened = `{
+	"action": "opened",
+	"repository": {"full_name": "octo/demo"},
+	"sender": {"login": "mona"},
+	"pull_request": {"number": 7, "title": "Add docs", "html_url": "https://github.com/octo/demo/pull/7", "base": {"ref": "main"}}
+}`
+
+// deliver posts a delivery signed with the secret
+func deliver(t *testing.T, handler http.Handler, event, id, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	return deliverSigned(t, handler, event, id, body, Sign(secret, []byte(body)))
+}
+
+func deliverSigned(t *testing.T, handler http.Handler, event, id, body, signature string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/github/webhook", strings.NewReader(body))
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, id)
+	req.Header.Set(HeaderSignature, signature)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func wait(t *testing.T, r *Receiver) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSignatureVerification(t *testing.T) {
+	agent := &fakeagent.Agent{}
+	r, err := New(Config{Secret: secret, Rules: []Rule{{Event: EventPullRequest}}}, agent.Run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, signature := range map[string]string{
+		"missing":    "",
+		"sha1":       "sha1=" + strings.TrimPrefix(Sign(secret, []byte(pullRequestOpened)), "sha256="),
+		"not hex":    "sha256=zz",
+		"other body": Sign(secret, []byte(pullRequestOpened+" ")),
+		"other key":  Sign("guessed", []byte(pullRequestOpened)),
+	} {
+		if got := deliverSigned(t, r, EventPullRequest, "d-1", pullRequestOpened, signature); got.Code != http.StatusUnauthorized {
+			t.Errorf("%s signature: status %d", name, got.Code)
+		}
+	}
+	if len(r.Deliveries()) != 0 {
+		t.Errorf("unsigned deliveries recorded: %+v", r.Deliveries())
+	}
+
+	// The example from the GitHub documentation on validating deliveries
+	if got := Sign(secret, []byte("Hello, World!")); got != "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17" {
+		t.Errorf("signature %s", got)
+	}
+
+	if got := deliver(t, r, eventPing, "d-ping", `{"zen": "Keep it logically awesome."}`); got.Code != http.StatusOK {
+		t.Errorf("ping: status %d", got.Code)
+	}
+	if got := deliver(t, r, EventPullRequest, "d-bad", `{"action": "opened"}`); got.Code != http.StatusBadRequest {
+		t.Errorf("payload without repository: status %d", got.Code)
+	}
+	wait(t, r)
+	if prompts := agent.Prompts(); len(prompts) != 0 {
+		t.Errorf("tasks started: %v", prompts)
+	}
+}
+
+func TestDeliveryStartsMatchingRules(t *testing.T) {
+	agent := &fakeagent.Agent{}
+	notifier := &fakeagent.Notifier{}
+	r, err := New(Config{Secret: secret, Rules: []Rule{
+		{Name: "summary", Event: EventPullRequest, Actions: []string{"opened", "reopened"}, Repos: []string{"octo/*"},
+			Notify: &types.PushNotificationConfig{URL: "https://hooks.example.com/pr"}},
+		{Name: "release", Event: EventPullRequest, Branches: []string{"release/*"}},
+		{Name: "custom", Event: EventPullRequest, Prompt: "Review {{.Repo}}#{{.Number}} on {{.Branch}}"},
+		{Name: "ci", Event: EventWorkflowRun, Conclusions: []string{"failure"}},
+	}}, agent.Run, WithNotifier(notifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := deliver(t, r, EventPullRequest, "d-1", pullRequestOpened)
+	if got.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", got.Code, got.Body)
+	}
+	var accepted Delivery
+	if err := json.NewDecoder(got.Body).Decode(&accepted); err != nil {
+		t.Fatal(err)
+	}
+	if len(accepted.Tasks) != 2 || accepted.Tasks[0].Rule != "summary" || accepted.Tasks[1].Rule != "custom" || accepted.Tasks[0].TaskID == "" {
+		t.Fatalf("accepted %+v, want the summary and custom rules", accepted)
+	}
+	wait(t, r)
+
+	prompts := agent.Prompts()
+	if !strings.Contains(prompts[0], `Pull request #7 "Add docs" in octo/demo was opened by mona`) || prompts[1] != "Review octo/demo#7 on main" {
+		t.Errorf("prompts %q", prompts)
+	}
+	message := agent.Messages()[0]
+	if message.TaskID != accepted.Tasks[0].TaskID || message.ContextID != "github/octo/demo/pull/7" || message.Metadata["github_delivery"] != "d-1" {
+		t.Errorf("message %+v", message)
+	}
+	if config := notifier.Config(accepted.Tasks[0].TaskID); len(notifier.Sent()) != 1 || config == nil || config.URL != "https://hooks.example.com/pr" {
+		t.Errorf("notified %v with %v", notifier.Sent(), config)
+	}
+
+	// A redelivery does not start the tasks again
+	if got := deliver(t, r, EventPullRequest, "d-1", pullRequestOpened); got.Code != http.StatusOK || !strings.Contains(got.Body.String(), StatusDuplicate) {
+		t.Errorf("redelivery: status %d: %s", got.Code, got.Body)
+	}
+
+	succeeded := `{"action": "completed", "repository": {"full_name": "octo/demo"}, "workflow_run": {"name": "CI", "head_branch": "main", "conclusion": "success"}}`
+	if got := deliver(t, r, EventWorkflowRun, "d-2", succeeded); got.Code != http.StatusOK {
+		t.Errorf("unmatched delivery: status %d", got.Code)
+	}
+	if got := deliver(t, r, "star", "d-3", `{}`); got.Code != http.StatusOK {
+		t.Errorf("unsupported event: status %d", got.Code)
+	}
+	wait(t, r)
+	if messages := agent.Messages(); len(messages) != 2 {
+		t.Errorf("%d tasks started, want 2", len(messages))
+	}
+
+	deliveries := r.Deliveries()
+	var statuses []string
+	for _, delivery := range deliveries {
+		statuses = append(statuses, delivery.Status)
+	}
+	if strings.Join(statuses, ",") != "ignored,unmatched,accepted" {
+		t.Errorf("statuses %v", statuses)
+	}
+	if task := deliveries[2].Tasks[0]; task.State != types.COMPLETED || task.FinishedAt == nil {
+		t.Errorf("recorded task %+v", task)
+	}
+}
+
+func TestConcurrentRedeliveriesStartTasksOnce(t *testing.T) {
+	agent := &fakeagent.Agent{}
+	r, err := New(Config{Secret: secret, Rules: []Rule{{Event: EventPullRequest}}}, agent.Run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// GitHub may redeliver while the first attempt is still being handled, a large payload keeps it
+	// being parsed for a while
+	payload := strings.Replace(pullRequestOpened, `"action"`, `"padding": "`+strings.Repeat("x", 1<<20)+`", "action"`, 1)
+	const attempts = 20
+	codes := make(chan int, attempts)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			codes <- deliver(t, r, EventPullRequest, "d-1", payload).Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+	wait(t, r)
+
+	accepted := 0
+	for code := range codes {
+		if code == http.StatusAccepted {
+			accepted++
+		}
+	}
+	if messages := agent.Messages(); accepted != 1 || len(messages) != 1 {
+		t.Errorf("%d deliveries accepted and %d tasks started, want 1", accepted, len(messages))
+	}
+	if deliveries := r.Deliveries(); len(deliveries) != 1 {
+		t.Errorf("%d deliveries recorded, want 1", len(deliveries))
+	}
+}
+
+func TestFailedTaskIsRecorded(t *testing.T) {
+	agent := &fakeagent.Agent{Err: errors.New("server is shutting down")}
+	r, err := New(Config{Secret: secret, Rules: []Rule{{Event: EventPush, Branches: []string{"main"}}}}, agent.Run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	push := `{"ref": "refs/heads/main", "repository": {"full_name": "octo/demo"}, "commits": [{}, {}], "head_commit": {"message": "Fix build\n\nDetails"}}`
+	if got := deliver(t, r, EventPush, "d-1", push); got.Code != http.StatusAccepted {
+		t.Fatalf("status %d", got.Code)
+	}
+	deleted := `{"ref": "refs/heads/main", "deleted": true, "repository": {"full_name": "octo/demo"}}`
+	if got := deliver(t, r, EventPush, "d-2", deleted); got.Code != http.StatusOK {
+		t.Errorf("deleted branch: status %d", got.Code)
+	}
+	wait(t, r)
+
+	if prompts := agent.Prompts(); len(prompts) != 1 || !strings.Contains(prompts[0], `2 commits to branch main of octo/demo, the last one is "Fix build"`) {
+		t.Errorf("prompts %q", prompts)
+	}
+	if task := r.Deliveries()[1].Tasks[0]; task.Error != "server is shutting down" || task.FinishedAt == nil {
+		t.Errorf("recorded task %+v", task)
+	}
+}
+
+func TestNewValidatesRules(t *testing.T) {
+	run := (&fakeagent.Agent{}).Run
+	tests := map[string]Config{
+		"secret":  {Rules: []Rule{{Event: EventPush}}},
+		"event":   {Secret: secret, Rules: []Rule{{Event: "star"}}},
+		"pattern": {Secret: secret, Rules: []Rule{{Event: EventPush, Branches: []string{"release/["}}}},
+		"prompt":  {Secret: secret, Rules: []Rule{{Event: EventPush, Prompt: "{{.Repo"}}},
+		"notify":  {Secret: secret, Rules: []Rule{{Event: EventPush, Notify: &types.PushNotificationConfig{URL: "https://example.com"}}}},
+	}
+	for name, config := range tests {
+		if _, err := New(config, run); err == nil {
+			t.Errorf("%s: invalid config accepted", name)
+		}
+	}
+}
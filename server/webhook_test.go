@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeeaiclub/a2a-go/sdk/types"
+	"github.com/yeeaiclub/github-a2a/server/config"
+	"github.com/yeeaiclub/github-a2a/server/toolset/fakellm"
+	"github.com/yeeaiclub/github-a2a/server/webhook"
+)
+
+func TestWebhookStartsTask(t *testing.T) {
+	agent := startAgent(t, fakellm.New(fakellm.Reply("Pull request #7 adds the docs.")), func(cfg *config.Config) {
+		cfg.GitHub.Webhook.Secret = "webhook-secret"
+		cfg.GitHub.Webhook.Rules = []config.WebhookRule{{Name: "pr-summary", Event: "pull_request", Actions: []string{"opened"}}}
+	})
+
+	body := `{"action": "opened", "repository": {"full_name": "octo/demo"}, "sender": {"login": "mona"},
+		"pull_request": {"number": 7, "title": "Add docs", "base": {"ref": "main"}}}`
+	post := func(signature string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, agent.URL+"/github/webhook", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(webhook.HeaderEvent, "pull_request")
+		req.Header.Set(webhook.HeaderDelivery, "delivery-1")
+		req.Header.Set(webhook.HeaderSignature, signature)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := post(webhook.Sign("guessed", []byte(body))); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("forged delivery: status %d", resp.StatusCode)
+	}
+	resp := post(webhook.Sign("webhook-secret", []byte(body)))
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var delivery webhook.Delivery
+	if err := json.NewDecoder(resp.Body).Decode(&delivery); err != nil {
+		t.Fatal(err)
+	}
+	if len(delivery.Tasks) != 1 {
+		t.Fatalf("delivery %+v, want one task", delivery)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := agent.app.Webhooks.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The task is a task of the agent like any other, in the context of the pull request
+	response := agent.call(t, request(1, types.MethodTasksGet, map[string]any{"id": delivery.Tasks[0].TaskID}))
+	task := checkTask(t, response.Result)
+	if task.Status.State != types.COMPLETED || task.ContextId != "github/octo/demo/pull/7" {
+		t.Errorf("webhook task %s in context %s", task.Status.State, task.ContextId)
+	}
+
+	debug, err := http.Get(agent.debug.URL + "/debug/webhooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer debug.Body.Close()
+	var deliveries []webhook.Delivery
+	if err := json.NewDecoder(debug.Body).Decode(&deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Tasks[0].State != types.COMPLETED {
+		t.Errorf("deliveries %+v", deliveries)
+	}
+	// The deliveries and their payload details are only served on the debug listener
+	public, err := http.Get(agent.URL + "/debug/webhooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	public.Body.Close()
+	if public.StatusCode != http.StatusNotFound {
+		t.Errorf("public /debug/webhooks: status %d", public.StatusCode)
+	}
+}
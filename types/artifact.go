@@ -0,0 +1,51 @@
+package types
+
+// Schemas of the tool results emitted as data artifacts. A schema name never changes its shape,
+// an incompatible change gets a new version.
+const (
+	SchemaRepositories        = "github.repositories.v1"
+	SchemaCommits             = "github.commits.v1"
+	SchemaRepositoryOverviews = "github.repository_overviews.v1"
+	SchemaPullRequestReview   = "github.pull_request_review.v1"
+	SchemaDiscussions         = "github.discussions.v1"
+	SchemaIssues              = "github.issues.v1"
+	SchemaPullRequests        = "github.pull_requests.v1"
+	SchemaWorkflowRuns        = "github.workflow_runs.v1"
+	SchemaSecurityAlerts      = "github.security_alerts.v1"
+)
+
+// DataArtifact is implemented by tool results that are also emitted to A2A clients as structured data
+type DataArtifact interface {
+	// ArtifactSchema names the JSON schema of the result
+	ArtifactSchema() string
+	// Succeeded reports whether the result carries data rather than an error
+	Succeeded() bool
+}
+
+// Succeeded reports whether the operation succeeded
+func (r GitHubResponse) Succeeded() bool { return r.Status == "success" }
+
+func (RepositoryResponse) ArtifactSchema() string         { return SchemaRepositories }
+func (CommitResponse) ArtifactSchema() string             { return SchemaCommits }
+func (RepositoryOverviewResponse) ArtifactSchema() string { return SchemaRepositoryOverviews }
+func (PullRequestReviewResponse) ArtifactSchema() string  { return SchemaPullRequestReview }
+func (DiscussionResponse) ArtifactSchema() string         { return SchemaDiscussions }
+func (IssueResponse) ArtifactSchema() string              { return SchemaIssues }
+func (PullRequestResponse) ArtifactSchema() string        { return SchemaPullRequests }
+func (WorkflowRunResponse) ArtifactSchema() string        { return SchemaWorkflowRuns }
+func (SecurityAlertResponse) ArtifactSchema() string      { return SchemaSecurityAlerts }
+
+// DataArtifacts lists a zero value of every data artifact type, for publishing their schemas
+func DataArtifacts() []DataArtifact {
+	return []DataArtifact{
+		RepositoryResponse{},
+		CommitResponse{},
+		RepositoryOverviewResponse{},
+		PullRequestReviewResponse{},
+		DiscussionResponse{},
+		IssueResponse{},
+		PullRequestResponse{},
+		WorkflowRunResponse{},
+		SecurityAlertResponse{},
+	}
+}
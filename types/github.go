@@ -30,6 +30,62 @@ type GitHubCommit struct {
 	URL     string    `json:"url"`
 }
 
+// GitHubIssue represents GitHub issue information
+type GitHubIssue struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	State     string    `json:"state"`
+	Author    string    `json:"author,omitempty"`
+	Labels    []string  `json:"labels,omitempty"`
+	Comments  int       `json:"comments"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GitHubPullRequest represents GitHub pull request information
+type GitHubPullRequest struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	State     string    `json:"state"`
+	Draft     bool      `json:"draft"`
+	Author    string    `json:"author,omitempty"`
+	Head      string    `json:"head"`
+	Base      string    `json:"base"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GitHubWorkflowRun represents a GitHub Actions workflow run
+type GitHubWorkflowRun struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Title      string    `json:"title,omitempty"`
+	Branch     string    `json:"branch"`
+	Event      string    `json:"event"`
+	Status     string    `json:"status"`
+	Conclusion *string   `json:"conclusion,omitempty"`
+	HeadSHA    string    `json:"head_sha"`
+	URL        string    `json:"url"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// GitHubSecurityAlert represents a Dependabot security alert
+type GitHubSecurityAlert struct {
+	Number    int       `json:"number"`
+	State     string    `json:"state"`
+	Severity  string    `json:"severity"`
+	Package   string    `json:"package"`
+	Ecosystem string    `json:"ecosystem"`
+	Summary   string    `json:"summary"`
+	GHSAID    string    `json:"ghsa_id,omitempty"`
+	CVEID     *string   `json:"cve_id,omitempty"`
+	PatchedIn *string   `json:"patched_in,omitempty"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // GitHubResponse represents base response model for GitHub API operations
 type GitHubResponse struct {
 	Status       string  `json:"status"`
@@ -50,6 +106,100 @@ type CommitResponse struct {
 	Data []GitHubCommit `json:"data,omitempty"`
 }
 
+// GitHubRepositoryOverview represents a repository together with its latest activity
+type GitHubRepositoryOverview struct {
+	FullName         string        `json:"full_name"`
+	Description      *string       `json:"description,omitempty"`
+	URL              string        `json:"url"`
+	Language         *string       `json:"language,omitempty"`
+	Stars            int           `json:"stars"`
+	Forks            int           `json:"forks"`
+	Archived         bool          `json:"archived"`
+	PushedAt         *time.Time    `json:"pushed_at,omitempty"`
+	DefaultBranch    string        `json:"default_branch,omitempty"`
+	LatestCommit     *GitHubCommit `json:"latest_commit,omitempty"`
+	OpenPullRequests int           `json:"open_pull_requests"`
+	OpenIssues       int           `json:"open_issues"`
+}
+
+// GitHubPullRequestReview represents a single review submitted on a pull request
+type GitHubPullRequestReview struct {
+	Reviewer    string     `json:"reviewer"`
+	State       string     `json:"state"`
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+	URL         string     `json:"url"`
+}
+
+// GitHubPullRequestReviewState represents the review status of a pull request
+type GitHubPullRequestReviewState struct {
+	Number             int                       `json:"number"`
+	Title              string                    `json:"title"`
+	URL                string                    `json:"url"`
+	State              string                    `json:"state"`
+	Draft              bool                      `json:"draft"`
+	Author             string                    `json:"author,omitempty"`
+	Mergeable          string                    `json:"mergeable,omitempty"`
+	ReviewDecision     *string                   `json:"review_decision,omitempty"`
+	RequestedReviewers []string                  `json:"requested_reviewers,omitempty"`
+	Reviews            []GitHubPullRequestReview `json:"reviews,omitempty"`
+}
+
+// GitHubDiscussion represents GitHub discussion information
+type GitHubDiscussion struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	Author    string    `json:"author,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Answered  bool      `json:"answered"`
+	Comments  int       `json:"comments"`
+	Upvotes   int       `json:"upvotes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RepositoryOverviewResponse represents response model for repository overview operations
+type RepositoryOverviewResponse struct {
+	GitHubResponse
+	Data []GitHubRepositoryOverview `json:"data,omitempty"`
+}
+
+// PullRequestReviewResponse represents response model for pull request review operations
+type PullRequestReviewResponse struct {
+	GitHubResponse
+	Data *GitHubPullRequestReviewState `json:"data,omitempty"`
+}
+
+// DiscussionResponse represents response model for discussion operations
+type DiscussionResponse struct {
+	GitHubResponse
+	Data []GitHubDiscussion `json:"data,omitempty"`
+}
+
+// IssueResponse represents response model for issue operations
+type IssueResponse struct {
+	GitHubResponse
+	Data []GitHubIssue `json:"data,omitempty"`
+}
+
+// PullRequestResponse represents response model for pull request operations
+type PullRequestResponse struct {
+	GitHubResponse
+	Data []GitHubPullRequest `json:"data,omitempty"`
+}
+
+// WorkflowRunResponse represents response model for workflow run operations
+type WorkflowRunResponse struct {
+	GitHubResponse
+	Data []GitHubWorkflowRun `json:"data,omitempty"`
+}
+
+// SecurityAlertResponse represents response model for security alert operations
+type SecurityAlertResponse struct {
+	GitHubResponse
+	Data []GitHubSecurityAlert `json:"data,omitempty"`
+}
+
 // ToolFunction represents a tool function for OpenAI function calling
 type ToolFunction struct {
 	Name        string                 `json:"name"`
@@ -1,6 +1,10 @@
 package types
 
-import "github.com/cohesion-org/deepseek-go"
+import (
+	"context"
+
+	"github.com/cohesion-org/deepseek-go"
+)
 
 type LLMRequest struct {
 	Role    string
@@ -13,5 +17,5 @@ type LLMResponse struct {
 
 type Function interface {
 	FunctionDefinition() deepseek.Function
-	Call(args map[string]interface{}) interface{}
+	Call(ctx context.Context, args map[string]interface{}) interface{}
 }